@@ -0,0 +1,103 @@
+package brng
+
+import (
+	"fmt"
+
+	"github.com/renproject/mpc/msm"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// IsValidBatched is the same as IsValid, but instead of checking each share
+// against its commitment individually, all of the shares are checked at once.
+// Each share and its commitment are weighted by a random scalar, and the
+// weighted sum of the checks is computed using a single multi-scalar
+// multiplication, which is much faster than a polynomial evaluation in the
+// exponent for each share when the batch is large. If any of the shares are
+// not valid, the combined check will fail except with negligible probability.
+// In the case that the combined check (or any of the other checks) fails, the
+// individual checks are performed so that the same error as IsValid is
+// returned.
+//
+// Panics: This function will panic if the given required contributions is less
+// than 1.
+func IsValidBatched(
+	batchSize uint32,
+	ownIndex secp256k1.Fn,
+	h secp256k1.Point,
+	sharesBatch []shamir.VerifiableShares,
+	commitmentsBatch [][]shamir.Commitment,
+	requiredContributions int,
+) error {
+	if requiredContributions < 1 {
+		panic(fmt.Sprintf("required contributions must be at least 1: got %v", requiredContributions))
+	}
+	if uint32(len(commitmentsBatch)) != batchSize || uint32(len(sharesBatch)) != batchSize {
+		return IsValid(batchSize, ownIndex, h, sharesBatch, commitmentsBatch, requiredContributions)
+	}
+	contributions := checkContributions(ownIndex, h, sharesBatch, commitmentsBatch, false)
+	if validityErr(sharesBatch, commitmentsBatch, requiredContributions, contributions) != nil ||
+		!batchCheck(ownIndex, h, sharesBatch, commitmentsBatch) {
+		return IsValid(batchSize, ownIndex, h, sharesBatch, commitmentsBatch, requiredContributions)
+	}
+	return nil
+}
+
+// batchCheck returns true if the random linear combination of the checks for
+// the given shares holds. The shares and commitments are assumed to have
+// consistent dimensions, and all of the shares are assumed to have the given
+// index. For random weights r_ij, the check is
+//
+//	g^(sum r_ij s_ij) h^(sum r_ij d_ij) = prod_ij prod_l C_ijl^(r_ij x^l),
+//
+// where s_ij and d_ij are the value and decommitment of the share, C_ijl are
+// the points of the commitment and x is the index. This is computed as a
+// single multi-scalar multiplication that should equal the point at infinity.
+func batchCheck(
+	index secp256k1.Fn,
+	h secp256k1.Point,
+	sharesBatch []shamir.VerifiableShares,
+	commitmentsBatch [][]shamir.Commitment,
+) bool {
+	k := commitmentsBatch[0][0].Len()
+	powers := make([]secp256k1.Fn, k)
+	powers[0].SetU16(1)
+	for l := 1; l < k; l++ {
+		powers[l].Mul(&powers[l-1], &index)
+	}
+
+	numPoints := 2
+	for i := range commitmentsBatch {
+		numPoints += len(commitmentsBatch[i]) * k
+	}
+	points := make([]secp256k1.Point, 0, numPoints)
+	scalars := make([]secp256k1.Fn, 0, numPoints)
+
+	var valueSum, decommitmentSum, tmp secp256k1.Fn
+	for i, shares := range sharesBatch {
+		for j := range shares {
+			weight := secp256k1.RandomFn()
+			tmp.Mul(&weight, &shares[j].Share.Value)
+			valueSum.Add(&valueSum, &tmp)
+			tmp.Mul(&weight, &shares[j].Decommitment)
+			decommitmentSum.Add(&decommitmentSum, &tmp)
+			for l := range commitmentsBatch[i][j] {
+				tmp.Mul(&weight, &powers[l])
+				points = append(points, commitmentsBatch[i][j][l])
+				scalars = append(scalars, tmp)
+			}
+		}
+	}
+
+	var one secp256k1.Fn
+	var g secp256k1.Point
+	one.SetU16(1)
+	g.BaseExp(&one)
+	valueSum.Negate(&valueSum)
+	decommitmentSum.Negate(&decommitmentSum)
+	points = append(points, g, h)
+	scalars = append(scalars, valueSum, decommitmentSum)
+
+	sum := msm.MultiScalarMul(points, scalars)
+	return sum.IsInfinity()
+}
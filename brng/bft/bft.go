@@ -0,0 +1,805 @@
+// Package bft implements a simple leader based Byzantine fault tolerant
+// consensus protocol that can be used to agree on the table of sharings in the
+// BRNG algorithm (see brng.Consensus). It allows BRNG to be run without a
+// trusted party.
+//
+// The protocol follows the Tendermint consensus algorithm [1]. Each player
+// first deals its row of sharings by sending each of the other players only
+// the shares for that player, along with the commitments. A player that
+// receives valid shares from a dealer acknowledges this to all of the other
+// players. A player that has not received valid shares with acknowledged
+// commitments from a dealer by the end of the dealing phase complains about
+// the dealer, and the dealer answers a complaint by revealing the shares of
+// the complaining player to everyone, as in the complaint phase of Pedersen's
+// DKG [2]. A dealer is certified for a set of commitments once 2t+1 players
+// have acknowledged them and it has revealed valid shares for them to every
+// player that complained about it; unless all n players have acknowledged
+// the commitments, this is only checked after the complaint phase. Since each
+// honest player only acknowledges one set of commitments for each dealer, a
+// dealer that sends inconsistent rows to different players can have at most
+// one set of commitments certified, and a dealer that sends malformed rows,
+// or no rows at all, is never certified.
+//
+// In each round there is a designated proposer, which proposes a table made
+// up of the commitments of k distinct certified dealers. The other players
+// then prevote and precommit for the table if every contributor in it is
+// certified with the given commitments, and a table is decided on once 2t+1
+// players have precommitted to it. The table does not contain any shares, and
+// so a player only learns its own shares, either from the row that the
+// contributor dealt to it or from the shares that the contributor revealed.
+// The protocol tolerates t faulty players as long as n >= 3t + 1.
+//
+// Timeouts are driven by the caller using Tick. The rounds of consensus only
+// use timeouts for liveness, and so the agreement on the table does not
+// depend on how often Tick is called. The dealing and complaint phases each
+// last for the number of ticks given by the timeout, and every honest player
+// is only guaranteed to hold valid shares for the decided table if the rows,
+// complaints and revealed shares that honest players send to each other are
+// received within one of these phases.
+//
+// [1] Ethan Buchman, Jae Kwon, and Zarko Milosevic. 2018.
+// The latest gossip on BFT consensus.
+// https://arxiv.org/abs/1807.04938
+//
+// [2] Rosario Gennaro, Stanislaw Jarecki, Hugo Krawczyk, and Tal Rabin. 1999.
+// Secure distributed key generation for discrete-log based cryptosystems.
+// https://doi.org/10.1007/3-540-48910-X_21
+package bft
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/params"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+const (
+	phasePropose = uint8(iota)
+	phasePrevote
+	phasePrecommit
+)
+
+const (
+	dealPhaseShare = uint8(iota)
+	dealPhaseComplain
+	dealPhaseDone
+)
+
+// A Replica is a state machine that implements the consensus protocol for one
+// of the players. It implements the brng.Consensus interface.
+type Replica struct {
+	ownPos                   uint32
+	ownIndex                 secp256k1.Fn
+	indices                  []secp256k1.Fn
+	batchSize, k, t, timeout uint32
+	h                        secp256k1.Point
+
+	row     []brng.Sharing
+	rowSent bool
+	deals   [][]brng.Sharing
+	acks    []ack
+
+	dealPhase     uint8
+	dealTicks     uint32
+	complaints    []complaint
+	reveals       []reveal
+	revealedDeals [][]brng.Sharing
+
+	round    uint32
+	phase    uint8
+	ticks    uint32
+	proposed bool
+
+	lockedRound, validRound int32
+	lockedHash              [32]byte
+	validProposal           proposal
+	decision                proposal
+
+	proposals            []proposal
+	prevotes, precommits []vote
+
+	out []Message
+}
+
+type proposal struct {
+	round        uint32
+	validRound   int32
+	contributors []uint32
+	commitments  [][]shamir.Commitment
+	hash         [32]byte
+	valid        bool
+}
+
+type vote struct {
+	round, from uint32
+	hash        [32]byte
+}
+
+type ack struct {
+	dealer, from uint32
+	hash         [32]byte
+}
+
+type complaint struct {
+	dealer, from uint32
+}
+
+type reveal struct {
+	dealer, to uint32
+	hash       [32]byte
+}
+
+// New returns a new Replica for the player with the given index. The indices
+// are the indices of all of the players, and the position of an index in this
+// slice is used to identify the corresponding player in HandleMessage. The
+// batch size and reconstruction threshold (k) are the same as for the BRNG
+// algorithm, and a decided table will contain the rows of k players. The
+// replica will tolerate t faulty players. The timeout is the number of calls
+// to Tick that are made without progress before the replica gives up on the
+// current step of the current round, and is also the length of the dealing
+// and complaint phases.
+//
+// Panics: This function will panic if the given index is not in the list of
+// indices, if n < 3t + 1, if the batch size is less than 1, if k is less than
+// t + 1 or larger than n - t, if the timeout is less than 1, or if the
+// Pedersen parameter is known to be insecure.
+func New(
+	ownIndex secp256k1.Fn, indices []secp256k1.Fn,
+	batchSize, k, t, timeout uint32,
+	h secp256k1.Point,
+) Replica {
+	n := uint32(len(indices))
+	if n < 3*t+1 {
+		panic(fmt.Sprintf("n must be at least 3t+1: got n = %v, t = %v", n, t))
+	}
+	if batchSize < 1 {
+		panic(fmt.Sprintf("batch size must be at least 1: got %v", batchSize))
+	}
+	if k < t+1 || k > n-t {
+		panic(fmt.Sprintf("k must be between t+1 = %v and n-t = %v: got %v", t+1, n-t, k))
+	}
+	if timeout < 1 {
+		panic(fmt.Sprintf("timeout must be at least 1: got %v", timeout))
+	}
+	if !params.ValidPedersenParameter(h) {
+		panic("insecure choice of pedersen parameter")
+	}
+	ownPos := -1
+	for i := range indices {
+		if indices[i].Eq(&ownIndex) {
+			ownPos = i
+			break
+		}
+	}
+	if ownPos == -1 {
+		panic("own index is not in the list of indices")
+	}
+	indicesCopy := make([]secp256k1.Fn, n)
+	copy(indicesCopy, indices)
+
+	return Replica{
+		ownPos:    uint32(ownPos),
+		ownIndex:  ownIndex,
+		indices:   indicesCopy,
+		batchSize: batchSize,
+		k:         k,
+		t:         t,
+		timeout:   timeout,
+		h:         h,
+
+		deals:         make([][]brng.Sharing, n),
+		revealedDeals: make([][]brng.Sharing, n),
+
+		lockedRound: -1,
+		validRound:  -1,
+	}
+}
+
+// Propose implements the brng.Consensus interface. The shares in the row will
+// be sent to the other players on the next call to Tick.
+func (replica *Replica) Propose(row []brng.Sharing) {
+	if len(replica.row) != 0 || len(row) == 0 {
+		return
+	}
+	replica.row = row
+}
+
+// Done implements the brng.Consensus interface.
+func (replica Replica) Done() bool {
+	return len(replica.decision.contributors) != 0
+}
+
+// Table implements the brng.Consensus interface. Each row in the table only
+// contains the share for this player, and only if the contributor dealt or
+// revealed a valid share with the decided commitments to this player;
+// otherwise the shares in the row are empty.
+func (replica Replica) Table() [][]brng.Sharing {
+	if !replica.Done() {
+		return nil
+	}
+	table := make([][]brng.Sharing, len(replica.decision.contributors))
+	for i, pos := range replica.decision.contributors {
+		commitments := replica.decision.commitments[i]
+		hash := commitmentsHash(commitments)
+		deal := replica.deals[pos]
+		if !replica.isValidDeal(deal, replica.ownIndex) || dealHash(deal) != hash {
+			deal = replica.revealedDeals[pos]
+			if !replica.isValidDeal(deal, replica.ownIndex) || dealHash(deal) != hash {
+				deal = nil
+			}
+		}
+		table[i] = make([]brng.Sharing, len(commitments))
+		for j := range commitments {
+			table[i][j].Commitment = commitments[j]
+			if deal != nil {
+				table[i][j].Shares = deal[j].Shares
+			}
+		}
+	}
+	return table
+}
+
+// Tick advances the clock of the replica by one unit of time, and returns the
+// messages that need to be sent to the other players. Messages of type
+// TypeRow need to be sent only to the player given by their To field, and all
+// other messages need to be sent to all of the other players. If the replica
+// has not made progress in the current step of the current round for the
+// number of ticks given by its timeout, it will move on to the next step.
+// Independently of the rounds, the replica sends its complaints once the
+// dealing phase has lasted for the timeout, and ends the complaint phase once
+// it has lasted for the timeout as well. After the replica has decided, Tick
+// does nothing.
+func (replica *Replica) Tick() []Message {
+	if replica.Done() {
+		return nil
+	}
+	replica.out = nil
+	if !replica.rowSent && len(replica.row) != 0 {
+		for pos := range replica.indices {
+			deal := dealFor(replica.row, pos)
+			if uint32(pos) == replica.ownPos {
+				replica.storeDeal(replica.ownPos, deal)
+				continue
+			}
+			replica.out = append(replica.out, Message{Type: TypeRow, To: uint32(pos), Row: deal})
+		}
+		replica.rowSent = true
+
+		// Players might have complained before the row was sent.
+		for _, c := range replica.complaints {
+			if c.dealer == replica.ownPos {
+				replica.reveal(c.from)
+			}
+		}
+	}
+	if replica.dealPhase != dealPhaseDone {
+		replica.dealTicks++
+		if replica.dealTicks >= replica.timeout {
+			replica.dealPhase++
+			replica.dealTicks = 0
+			if replica.dealPhase == dealPhaseComplain {
+				replica.complain()
+			} else {
+				// Dealers that were not acknowledged by all of the players
+				// can now be certified.
+				replica.revalidate()
+			}
+		}
+	}
+	replica.ticks++
+	if replica.ticks >= replica.timeout {
+		replica.onTimeout()
+	}
+	replica.process()
+	return replica.takeMessages()
+}
+
+// HandleMessage applies a state transition upon receiving the given message
+// from the player at the given position in the list of indices, and returns
+// the messages that need to be sent to all of the other players. Invalid
+// messages, messages of type TypeRow that are addressed to another player and
+// messages from unknown players are ignored. Messages of type TypeReveal are
+// handled regardless of the player that they are addressed to.
+func (replica *Replica) HandleMessage(from uint32, msg Message) []Message {
+	if replica.Done() || from >= uint32(len(replica.indices)) || from == replica.ownPos {
+		return nil
+	}
+	replica.out = nil
+	replica.record(from, msg)
+	replica.process()
+	return replica.takeMessages()
+}
+
+func (replica *Replica) takeMessages() []Message {
+	msgs := replica.out
+	replica.out = nil
+	return msgs
+}
+
+// broadcast queues the given message to be sent to the other players, and
+// records it as though it was received from this player.
+func (replica *Replica) broadcast(msg Message) {
+	replica.out = append(replica.out, msg)
+	replica.record(replica.ownPos, msg)
+}
+
+// record stores the given message from the given player. Only the first
+// message of each kind from each player is stored.
+func (replica *Replica) record(from uint32, msg Message) {
+	switch msg.Type {
+	case TypeRow:
+		if msg.To == replica.ownPos && len(replica.deals[from]) == 0 && len(msg.Row) != 0 {
+			replica.storeDeal(from, msg.Row)
+		}
+
+	case TypeAck:
+		if msg.Dealer >= uint32(len(replica.indices)) || replica.hasAcked(msg.Dealer, from) {
+			return
+		}
+		replica.acks = append(replica.acks, ack{msg.Dealer, from, msg.Hash})
+		replica.revalidate()
+
+	case TypeComplaint:
+		if msg.Dealer >= uint32(len(replica.indices)) || msg.Dealer == from || replica.hasComplained(msg.Dealer, from) {
+			return
+		}
+		replica.complaints = append(replica.complaints, complaint{msg.Dealer, from})
+		if msg.Dealer == replica.ownPos && replica.rowSent {
+			replica.reveal(from)
+		}
+		replica.revalidate()
+
+	case TypeReveal:
+		if msg.To >= uint32(len(replica.indices)) || msg.To == from || replica.hasRevealed(from, msg.To) ||
+			!replica.isValidDeal(msg.Row, replica.indices[msg.To]) {
+			return
+		}
+		replica.reveals = append(replica.reveals, reveal{from, msg.To, dealHash(msg.Row)})
+		if msg.To == replica.ownPos {
+			replica.revealedDeals[from] = msg.Row
+		}
+		replica.revalidate()
+
+	case TypeProposal:
+		if from != replica.proposer(msg.Round) || replica.proposal(msg.Round) != nil {
+			return
+		}
+		p := proposal{
+			round:        msg.Round,
+			validRound:   msg.ValidRound,
+			contributors: msg.Contributors,
+			commitments:  msg.Commitments,
+			hash:         tableHash(msg.Contributors, msg.Commitments),
+		}
+		p.valid = replica.isValid(&p)
+		replica.proposals = append(replica.proposals, p)
+
+	case TypePrevote:
+		replica.prevotes = addVote(replica.prevotes, vote{msg.Round, from, msg.Hash})
+
+	case TypePrecommit:
+		replica.precommits = addVote(replica.precommits, vote{msg.Round, from, msg.Hash})
+	}
+}
+
+// storeDeal stores the shares that were dealt by the given player, and
+// acknowledges them if they are valid.
+func (replica *Replica) storeDeal(from uint32, deal []brng.Sharing) {
+	replica.deals[from] = deal
+	if replica.isValidDeal(deal, replica.ownIndex) {
+		replica.broadcast(Message{Type: TypeAck, Dealer: from, Hash: dealHash(deal)})
+	}
+}
+
+// complain complains about every other dealer that has not dealt valid
+// shares to this player with commitments that have been acknowledged by 2t+1
+// players.
+func (replica *Replica) complain() {
+	for pos := range replica.indices {
+		if uint32(pos) == replica.ownPos {
+			continue
+		}
+		deal := replica.deals[pos]
+		if replica.isValidDeal(deal, replica.ownIndex) && replica.countAcks(uint32(pos), dealHash(deal)) >= replica.quorum() {
+			continue
+		}
+		replica.broadcast(Message{Type: TypeComplaint, Dealer: uint32(pos)})
+	}
+}
+
+// reveal sends the shares in the row of this player for the player at the
+// given position to all of the other players.
+func (replica *Replica) reveal(to uint32) {
+	replica.broadcast(Message{Type: TypeReveal, To: to, Row: dealFor(replica.row, int(to))})
+}
+
+// revalidate checks the validity of all of the proposals again, since
+// whether a dealer is certified can change as acknowledgements, complaints
+// and revealed shares are received, and when the complaint phase ends.
+func (replica *Replica) revalidate() {
+	for i := range replica.proposals {
+		replica.proposals[i].valid = replica.isValid(&replica.proposals[i])
+	}
+}
+
+// isValidDeal returns true if the given shares are valid shares for the
+// player with the given index with respect to the commitments, and the
+// commitments have the correct batch size and threshold.
+func (replica *Replica) isValidDeal(deal []brng.Sharing, index secp256k1.Fn) bool {
+	if uint32(len(deal)) != replica.batchSize {
+		return false
+	}
+	for i := range deal {
+		if uint32(deal[i].Commitment.Len()) != replica.k {
+			return false
+		}
+	}
+	sharesBatch, commitmentsBatch := brng.TableSharesAndCommitments([][]brng.Sharing{deal}, index)
+	return brng.IsValid(replica.batchSize, index, replica.h, sharesBatch, commitmentsBatch, 1) == nil
+}
+
+func (replica *Replica) hasAcked(dealer, from uint32) bool {
+	for _, a := range replica.acks {
+		if a.dealer == dealer && a.from == from {
+			return true
+		}
+	}
+	return false
+}
+
+func (replica *Replica) hasComplained(dealer, from uint32) bool {
+	for _, c := range replica.complaints {
+		if c.dealer == dealer && c.from == from {
+			return true
+		}
+	}
+	return false
+}
+
+func (replica *Replica) hasRevealed(dealer, to uint32) bool {
+	for _, r := range replica.reveals {
+		if r.dealer == dealer && r.to == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (replica *Replica) countAcks(dealer uint32, hash [32]byte) int {
+	count := 0
+	for _, a := range replica.acks {
+		if a.dealer == dealer && a.hash == hash {
+			count++
+		}
+	}
+	return count
+}
+
+// isCertified returns true if 2t+1 players have acknowledged the given
+// commitments hash for the given dealer, and the dealer has revealed valid
+// shares with these commitments for every player that complained about it.
+// Unless all of the players have acknowledged the commitments, a dealer can
+// only be certified after the complaint phase, since an honest player that
+// did not receive valid shares might not have complained yet.
+func (replica *Replica) isCertified(dealer uint32, hash [32]byte) bool {
+	count := replica.countAcks(dealer, hash)
+	if count < replica.quorum() {
+		return false
+	}
+	if count < len(replica.indices) && replica.dealPhase != dealPhaseDone {
+		return false
+	}
+	for _, c := range replica.complaints {
+		if c.dealer != dealer {
+			continue
+		}
+		revealed := false
+		for _, r := range replica.reveals {
+			if r.dealer == dealer && r.to == c.from && r.hash == hash {
+				revealed = true
+				break
+			}
+		}
+		if !revealed {
+			return false
+		}
+	}
+	return true
+}
+
+// process applies all of the state transitions that are possible given the
+// messages that have been received so far.
+func (replica *Replica) process() {
+	for !replica.Done() {
+		if !(replica.tryDecide() ||
+			replica.trySkipRound() ||
+			replica.tryPropose() ||
+			replica.tryPrevote() ||
+			replica.tryLock() ||
+			replica.tryPrecommitNil()) {
+			return
+		}
+	}
+}
+
+// tryDecide decides on a proposal once it has 2t+1 precommits. Unlike the
+// other steps, this does not require the table to be valid for this player:
+// at least t+1 honest players have found it to be valid, and a player that
+// has not yet seen enough acknowledgements or revealed shares for the
+// contributors still needs to learn the table to compute the output
+// commitments.
+func (replica *Replica) tryDecide() bool {
+	for i := range replica.proposals {
+		p := &replica.proposals[i]
+		if countVotes(replica.precommits, p.round, p.hash) >= replica.quorum() {
+			replica.decision = *p
+			return true
+		}
+	}
+	return false
+}
+
+func (replica *Replica) trySkipRound() bool {
+	senders := make(map[uint32]map[uint32]struct{})
+	add := func(round, from uint32) {
+		if round <= replica.round {
+			return
+		}
+		if senders[round] == nil {
+			senders[round] = make(map[uint32]struct{})
+		}
+		senders[round][from] = struct{}{}
+	}
+	for _, p := range replica.proposals {
+		add(p.round, replica.proposer(p.round))
+	}
+	for _, v := range replica.prevotes {
+		add(v.round, v.from)
+	}
+	for _, v := range replica.precommits {
+		add(v.round, v.from)
+	}
+	skipTo := replica.round
+	for round, from := range senders {
+		if uint32(len(from)) >= replica.t+1 && round > skipTo {
+			skipTo = round
+		}
+	}
+	if skipTo == replica.round {
+		return false
+	}
+	replica.startRound(skipTo)
+	return true
+}
+
+func (replica *Replica) tryPropose() bool {
+	if replica.phase != phasePropose || replica.proposed || replica.proposer(replica.round) != replica.ownPos {
+		return false
+	}
+	msg := Message{
+		Type:       TypeProposal,
+		Round:      replica.round,
+		ValidRound: replica.validRound,
+	}
+	if replica.validRound != -1 {
+		msg.Contributors = replica.validProposal.contributors
+		msg.Commitments = replica.validProposal.commitments
+	} else {
+		msg.Contributors, msg.Commitments = replica.newTable()
+		if msg.Commitments == nil {
+			return false
+		}
+	}
+	replica.proposed = true
+	replica.broadcast(msg)
+	return true
+}
+
+func (replica *Replica) tryPrevote() bool {
+	if replica.phase != phasePropose {
+		return false
+	}
+	p := replica.proposal(replica.round)
+	if p == nil {
+		return false
+	}
+	var accept bool
+	if p.validRound == -1 {
+		accept = p.valid && (replica.lockedRound == -1 || replica.lockedHash == p.hash)
+	} else if p.validRound >= 0 && uint32(p.validRound) < replica.round &&
+		countVotes(replica.prevotes, uint32(p.validRound), p.hash) >= replica.quorum() {
+		accept = p.valid && (replica.lockedRound <= p.validRound || replica.lockedHash == p.hash)
+	} else {
+		return false
+	}
+	var hash [32]byte
+	if accept {
+		hash = p.hash
+	}
+	replica.phase = phasePrevote
+	replica.ticks = 0
+	replica.broadcast(Message{Type: TypePrevote, Round: replica.round, Hash: hash})
+	return true
+}
+
+func (replica *Replica) tryLock() bool {
+	if replica.phase == phasePropose || replica.validRound == int32(replica.round) {
+		return false
+	}
+	p := replica.proposal(replica.round)
+	if p == nil || !p.valid || countVotes(replica.prevotes, replica.round, p.hash) < replica.quorum() {
+		return false
+	}
+	if replica.phase == phasePrevote {
+		replica.lockedHash = p.hash
+		replica.lockedRound = int32(replica.round)
+		replica.phase = phasePrecommit
+		replica.ticks = 0
+		replica.broadcast(Message{Type: TypePrecommit, Round: replica.round, Hash: p.hash})
+	}
+	replica.validProposal = *p
+	replica.validRound = int32(replica.round)
+	return true
+}
+
+func (replica *Replica) tryPrecommitNil() bool {
+	if replica.phase != phasePrevote || countVotes(replica.prevotes, replica.round, [32]byte{}) < replica.quorum() {
+		return false
+	}
+	replica.phase = phasePrecommit
+	replica.ticks = 0
+	replica.broadcast(Message{Type: TypePrecommit, Round: replica.round})
+	return true
+}
+
+func (replica *Replica) onTimeout() {
+	switch replica.phase {
+	case phasePropose:
+		replica.phase = phasePrevote
+		replica.ticks = 0
+		replica.broadcast(Message{Type: TypePrevote, Round: replica.round})
+	case phasePrevote:
+		replica.phase = phasePrecommit
+		replica.ticks = 0
+		replica.broadcast(Message{Type: TypePrecommit, Round: replica.round})
+	default:
+		replica.startRound(replica.round + 1)
+	}
+}
+
+func (replica *Replica) startRound(round uint32) {
+	replica.round = round
+	replica.phase = phasePropose
+	replica.ticks = 0
+	replica.proposed = false
+}
+
+// newTable returns a new table made up of the commitments of the first k
+// certified dealers for which this player knows the certified commitments,
+// starting with this player. The return values will be nil if not enough
+// dealers have been certified.
+func (replica *Replica) newTable() ([]uint32, [][]shamir.Commitment) {
+	n := uint32(len(replica.indices))
+	contributors := make([]uint32, 0, replica.k)
+	commitments := make([][]shamir.Commitment, 0, replica.k)
+	for i := uint32(0); i < n && uint32(len(contributors)) < replica.k; i++ {
+		pos := (replica.ownPos + i) % n
+		deal := replica.deals[pos]
+		if len(deal) != 0 && replica.isCertified(pos, dealHash(deal)) {
+			contributors = append(contributors, pos)
+			commitments = append(commitments, dealCommitments(deal))
+		}
+	}
+	if uint32(len(contributors)) < replica.k {
+		return nil, nil
+	}
+	return contributors, commitments
+}
+
+// isValid returns true if the table in the given proposal is made up of the
+// certified commitments of k distinct dealers.
+func (replica *Replica) isValid(p *proposal) bool {
+	n := uint32(len(replica.indices))
+	if uint32(len(p.contributors)) != replica.k || len(p.commitments) != len(p.contributors) {
+		return false
+	}
+	seen := make(map[uint32]struct{}, len(p.contributors))
+	for i, pos := range p.contributors {
+		if pos >= n {
+			return false
+		}
+		if _, ok := seen[pos]; ok {
+			return false
+		}
+		seen[pos] = struct{}{}
+		if !replica.isCertified(pos, commitmentsHash(p.commitments[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func (replica *Replica) proposal(round uint32) *proposal {
+	for i := range replica.proposals {
+		if replica.proposals[i].round == round {
+			return &replica.proposals[i]
+		}
+	}
+	return nil
+}
+
+func (replica *Replica) proposer(round uint32) uint32 {
+	return round % uint32(len(replica.indices))
+}
+
+func (replica *Replica) quorum() int {
+	return int(2*replica.t + 1)
+}
+
+func addVote(votes []vote, v vote) []vote {
+	for _, other := range votes {
+		if other.round == v.round && other.from == v.from {
+			return votes
+		}
+	}
+	return append(votes, v)
+}
+
+func countVotes(votes []vote, round uint32, hash [32]byte) int {
+	count := 0
+	for _, v := range votes {
+		if v.round == round && v.hash == hash {
+			count++
+		}
+	}
+	return count
+}
+
+// dealFor returns the sharings in the given row with only the share for the
+// player at the given position.
+func dealFor(row []brng.Sharing, pos int) []brng.Sharing {
+	deal := make([]brng.Sharing, len(row))
+	for i := range row {
+		deal[i].Commitment = row[i].Commitment
+		if pos < len(row[i].Shares) {
+			deal[i].Shares = shamir.VerifiableShares{row[i].Shares[pos]}
+		}
+	}
+	return deal
+}
+
+func dealCommitments(deal []brng.Sharing) []shamir.Commitment {
+	commitments := make([]shamir.Commitment, len(deal))
+	for i := range deal {
+		commitments[i] = deal[i].Commitment
+	}
+	return commitments
+}
+
+func dealHash(deal []brng.Sharing) [32]byte {
+	return commitmentsHash(dealCommitments(deal))
+}
+
+func commitmentsHash(commitments []shamir.Commitment) [32]byte {
+	buf, err := surge.ToBinary(commitments)
+	if err != nil {
+		panic(fmt.Sprintf("marshaling commitments: %v", err))
+	}
+	return sha256.Sum256(buf)
+}
+
+func tableHash(contributors []uint32, commitments [][]shamir.Commitment) [32]byte {
+	buf, err := surge.ToBinary(contributors)
+	if err != nil {
+		panic(fmt.Sprintf("marshaling contributors: %v", err))
+	}
+	commitmentsBuf, err := surge.ToBinary(commitments)
+	if err != nil {
+		panic(fmt.Sprintf("marshaling commitments: %v", err))
+	}
+	return sha256.Sum256(append(buf, commitmentsBuf...))
+}
@@ -0,0 +1,13 @@
+package bft_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBFT(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BFT Suite")
+}
@@ -0,0 +1,411 @@
+package bft_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/brng/bft"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/brng/brngutil"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+)
+
+var _ = Describe("BFT consensus", func() {
+	n := 7
+	t := 2
+	k := t + 1
+	b := 2
+	timeout := 10
+
+	var indices []secp256k1.Fn
+	var h secp256k1.Point
+
+	BeforeEach(func() {
+		indices = shamirutil.RandomIndices(n)
+		h = secp256k1.RandomPoint()
+	})
+
+	// Deliver repeatedly delivers all of the given messages from the given
+	// senders to the replicas that are not faulty, until no more messages are
+	// produced. Messages of type TypeRow are only delivered to the replica
+	// that they are addressed to.
+	Deliver := func(replicas []Replica, faulty map[uint32]bool, from []uint32, msgs [][]Message) {
+		for len(msgs) > 0 {
+			var nextFrom []uint32
+			var next [][]Message
+			for i, batch := range msgs {
+				for _, msg := range batch {
+					for j := range replicas {
+						if uint32(j) == from[i] || faulty[uint32(j)] {
+							continue
+						}
+						if msg.Type == TypeRow && msg.To != uint32(j) {
+							continue
+						}
+						out := replicas[j].HandleMessage(from[i], msg)
+						if out != nil {
+							nextFrom = append(nextFrom, uint32(j))
+							next = append(next, out)
+						}
+					}
+				}
+			}
+			from, msgs = nextFrom, next
+		}
+	}
+
+	// TickAll ticks all of the replicas that are not faulty and delivers the
+	// resulting messages.
+	TickAll := func(replicas []Replica, faulty map[uint32]bool) {
+		var from []uint32
+		var msgs [][]Message
+		for i := range replicas {
+			if faulty[uint32(i)] {
+				continue
+			}
+			from = append(from, uint32(i))
+			msgs = append(msgs, replicas[i].Tick())
+		}
+		Deliver(replicas, faulty, from, msgs)
+	}
+
+	// TickUntilDone repeatedly ticks all of the replicas that are not faulty
+	// and delivers the resulting messages, until all of these replicas have
+	// decided or a bound on the number of ticks is reached.
+	TickUntilDone := func(replicas []Replica, faulty map[uint32]bool) {
+		for tick := 0; tick < 20*timeout; tick++ {
+			done := true
+			for i := range replicas {
+				if !faulty[uint32(i)] && !replicas[i].Done() {
+					done = false
+				}
+			}
+			if done {
+				return
+			}
+			TickAll(replicas, faulty)
+		}
+	}
+
+	NewReplicas := func() ([]Replica, [][]brng.Sharing) {
+		replicas := make([]Replica, n)
+		rows := make([][]brng.Sharing, n)
+		for i := range replicas {
+			replicas[i] = New(indices[i], indices, uint32(b), uint32(k), uint32(t), uint32(timeout), h)
+			rows[i] = brng.New(uint32(b), uint32(k), indices, indices[i], h)
+			replicas[i].Propose(rows[i])
+		}
+		return replicas, rows
+	}
+
+	// Deal returns the message that contains the shares in the given row for
+	// the player at the given position.
+	Deal := func(row []brng.Sharing, pos int) Message {
+		deal := make([]brng.Sharing, len(row))
+		for i := range row {
+			deal[i] = brng.Sharing{
+				Shares:     shamir.VerifiableShares{row[i].Shares[pos]},
+				Commitment: row[i].Commitment,
+			}
+		}
+		return Message{Type: TypeRow, To: uint32(pos), Row: deal}
+	}
+
+	// ExpectConsistentTables checks that all of the replicas that are not
+	// faulty have decided on the same table, that the shares of each of
+	// these replicas are valid, and that none of the given rows are in the
+	// table.
+	ExpectConsistentTables := func(replicas []Replica, faulty map[uint32]bool, excluded ...[]brng.Sharing) {
+		var ref [][]shamir.Commitment
+		for i := range replicas {
+			if faulty[uint32(i)] {
+				continue
+			}
+			Expect(replicas[i].Done()).To(BeTrue())
+			table := replicas[i].Table()
+			Expect(len(table)).To(Equal(k))
+
+			sharesBatch, commitmentsBatch := brng.TableSharesAndCommitments(table, indices[i])
+			Expect(brng.IsValid(uint32(b), indices[i], h, sharesBatch, commitmentsBatch, k)).To(Succeed())
+			if ref == nil {
+				ref = commitmentsBatch
+			}
+			Expect(commitmentsBatch).To(Equal(ref))
+
+			for _, row := range table {
+				for _, other := range excluded {
+					Expect(row[0].Commitment.Eq(other[0].Commitment)).To(BeFalse())
+				}
+			}
+		}
+	}
+
+	Context("honest replicas", func() {
+		Specify("all replicas should decide on the same valid table", func() {
+			replicas, _ := NewReplicas()
+			TickAll(replicas, nil)
+			ExpectConsistentTables(replicas, nil)
+		})
+
+		Specify("players should only be sent their own shares", func() {
+			replicas, rows := NewReplicas()
+			msgs := replicas[0].Tick()
+			numRows := 0
+			for _, msg := range msgs {
+				if msg.Type != TypeRow {
+					continue
+				}
+				numRows++
+				Expect(msg.To).ToNot(Equal(uint32(0)))
+				Expect(msg).To(Equal(Deal(rows[0], int(msg.To))))
+			}
+			Expect(numRows).To(Equal(n - 1))
+		})
+	})
+
+	Context("faulty proposer", func() {
+		Specify("replicas should not prevote for a table with dealers that are not certified", func() {
+			replicas, _ := NewReplicas()
+
+			// The proposer of the first round is faulty, so the other
+			// replicas exchange their rows and acknowledgements but do not
+			// receive a proposal.
+			faulty := map[uint32]bool{0: true}
+			TickAll(replicas, faulty)
+
+			// The proposer replaces the commitments of the contributors with
+			// commitments for rows that they did not send.
+			contributors := make([]uint32, k)
+			commitments := make([][]shamir.Commitment, k)
+			for i := range commitments {
+				contributors[i] = uint32(i + 1)
+				row := brng.New(uint32(b), uint32(k), indices, indices[i+1], h)
+				commitments[i] = make([]shamir.Commitment, b)
+				for j := range row {
+					commitments[i][j] = row[j].Commitment
+				}
+			}
+			proposal := Message{Type: TypeProposal, Round: 0, ValidRound: -1, Contributors: contributors, Commitments: commitments}
+
+			for j := 1; j < n; j++ {
+				out := replicas[j].HandleMessage(0, proposal)
+				Expect(out).To(HaveLen(1))
+				Expect(out[0].Type).To(Equal(TypePrevote))
+				Expect(out[0].Hash).To(Equal([32]byte{}))
+			}
+		})
+	})
+
+	Context("faulty dealers", func() {
+		// The dealer is not the proposer of the first round, and would be
+		// the second contributor in its table.
+		dealer := 1
+
+		Specify("a dealer that sends inconsistent rows should be excluded from the table", func() {
+			replicas, _ := NewReplicas()
+			faulty := map[uint32]bool{uint32(dealer): true}
+
+			// The dealer sends shares from one row to half of the players,
+			// and shares from another row to the other half, so neither row
+			// can be certified.
+			rowA := brng.New(uint32(b), uint32(k), indices, indices[dealer], h)
+			rowB := brng.New(uint32(b), uint32(k), indices, indices[dealer], h)
+			var deals []Message
+			for pos := range indices {
+				if pos == dealer {
+					continue
+				}
+				if pos < n/2+1 {
+					deals = append(deals, Deal(rowA, pos))
+				} else {
+					deals = append(deals, Deal(rowB, pos))
+				}
+			}
+			Deliver(replicas, faulty, []uint32{uint32(dealer)}, [][]Message{deals})
+
+			TickUntilDone(replicas, faulty)
+			ExpectConsistentTables(replicas, faulty, rowA, rowB)
+		})
+
+		Specify("a dealer that sends invalid shares should be excluded from the table", func() {
+			replicas, _ := NewReplicas()
+			faulty := map[uint32]bool{uint32(dealer): true}
+
+			row := brng.New(uint32(b), uint32(k), indices, indices[dealer], h)
+			var deals []Message
+			for pos := range indices {
+				if pos == dealer {
+					continue
+				}
+				deal := Deal(row, pos)
+				deal.Row[0].Shares[0].Share.Value = secp256k1.RandomFn()
+				deals = append(deals, deal)
+			}
+			Deliver(replicas, faulty, []uint32{uint32(dealer)}, [][]Message{deals})
+
+			TickUntilDone(replicas, faulty)
+			ExpectConsistentTables(replicas, faulty, row)
+		})
+
+		Specify("a dealer that is silent should be excluded from the table", func() {
+			replicas, rows := NewReplicas()
+			faulty := map[uint32]bool{uint32(dealer): true}
+
+			TickUntilDone(replicas, faulty)
+			ExpectConsistentTables(replicas, faulty, rows[dealer])
+		})
+
+		Specify("a dealer that colludes with t-1 other players against an honest player should be excluded from the table", func() {
+			replicas, rows := NewReplicas()
+			colluder := 2
+			victim := 3
+			faulty := map[uint32]bool{uint32(dealer): true, uint32(colluder): true}
+
+			// The first honest player acknowledges the shares that it
+			// receives from the dealer, which also gives the hash of the
+			// commitments for the other players to acknowledge.
+			out := replicas[0].HandleMessage(uint32(dealer), Deal(rows[dealer], 0))
+			Expect(out).To(HaveLen(1))
+			Expect(out[0].Type).To(Equal(TypeAck))
+			ack := out[0]
+			Deliver(replicas, faulty, []uint32{0}, [][]Message{out})
+
+			// The dealer sends invalid shares to the victim and valid shares
+			// to the other players, and together with the colluder
+			// acknowledges the shares, so that the dealer has 2t+1
+			// acknowledgements without the victim. The dealer then ignores
+			// the complaint from the victim.
+			var deals []Message
+			for pos := 1; pos < n; pos++ {
+				if faulty[uint32(pos)] {
+					continue
+				}
+				deal := Deal(rows[dealer], pos)
+				if pos == victim {
+					deal.Row[0].Shares[0].Share.Value = secp256k1.RandomFn()
+				}
+				deals = append(deals, deal)
+			}
+			Deliver(replicas, faulty, []uint32{uint32(dealer)}, [][]Message{deals})
+			Deliver(replicas, faulty, []uint32{uint32(dealer), uint32(colluder)}, [][]Message{{ack}, {ack}})
+
+			TickUntilDone(replicas, faulty)
+			ExpectConsistentTables(replicas, faulty, rows[dealer])
+		})
+
+		Specify("a dealer that reveals the shares of a complaining player can be included in the table", func() {
+			replicas, rows := NewReplicas()
+			victim := 3
+
+			// One of the players is silent, so no dealer is acknowledged by
+			// all of the players, and no dealer is certified before the end
+			// of the complaint phase.
+			faulty := map[uint32]bool{uint32(n - 1): true}
+
+			// The dealer sends invalid shares to the victim on the first
+			// tick, but otherwise follows the protocol.
+			var from []uint32
+			var msgs [][]Message
+			for i := range replicas {
+				if faulty[uint32(i)] {
+					continue
+				}
+				out := replicas[i].Tick()
+				if i == dealer {
+					for j := range out {
+						if out[j].Type == TypeRow && out[j].To == uint32(victim) {
+							out[j] = Deal(rows[dealer], victim)
+							out[j].Row[0].Shares[0].Share.Value = secp256k1.RandomFn()
+						}
+					}
+				}
+				from = append(from, uint32(i))
+				msgs = append(msgs, out)
+			}
+			Deliver(replicas, faulty, from, msgs)
+
+			TickUntilDone(replicas, faulty)
+			ExpectConsistentTables(replicas, faulty, rows[n-1])
+
+			// The complaint phase ends in the round that the dealer
+			// proposes, and the dealer proposes a table that starts with its
+			// own row.
+			table := replicas[victim].Table()
+			Expect(table[0][0].Commitment.Eq(rows[dealer][0].Commitment)).To(BeTrue())
+		})
+	})
+
+	Context("network", func() {
+		Specify("all online players should get valid and consistent outputs", func() {
+			ids := make([]mpcutil.ID, n)
+			for i := range ids {
+				ids[i] = mpcutil.ID(i + 1)
+			}
+			shuffleMsgs, isOffline := mpcutil.MessageShufflerDropper(ids, t)
+
+			machines := make([]mpcutil.Machine, n)
+			for i := range machines {
+				machine := brngutil.NewBFTMachine(ids[i], ids, indices, indices[i], h, k, b, t, timeout)
+				machines[i] = &machine
+			}
+
+			network := mpcutil.NewNetwork(machines, shuffleMsgs)
+			network.SetCaptureHist(true)
+			Expect(network.Run()).To(Succeed())
+
+			var ref *brngutil.BrngMachine
+			for i := range machines {
+				if !isOffline[ids[i]] {
+					ref = machines[i].(*brngutil.BrngMachine)
+					break
+				}
+			}
+			for j := 0; j < b; j++ {
+				shares := make(shamir.VerifiableShares, 0, n-t)
+				for i := range machines {
+					if isOffline[ids[i]] {
+						continue
+					}
+					machine := machines[i].(*brngutil.BrngMachine)
+					Expect(machine.Commitments()[j].Eq(ref.Commitments()[j])).To(BeTrue())
+					Expect(shamir.IsValid(h, &machine.Commitments()[j], &machine.Shares()[j])).To(BeTrue())
+					shares = append(shares, machine.Shares()[j])
+				}
+				Expect(shamirutil.VsharesAreConsistent(shares, k)).To(BeTrue())
+			}
+		})
+	})
+
+	Context("panics", func() {
+		Specify("n less than 3t+1", func() {
+			Expect(func() { New(indices[0], indices, uint32(b), 1, uint32(n/3+1), uint32(timeout), h) }).To(Panic())
+		})
+
+		Specify("k out of range", func() {
+			Expect(func() { New(indices[0], indices, uint32(b), 0, uint32(t), uint32(timeout), h) }).To(Panic())
+			Expect(func() { New(indices[0], indices, uint32(b), uint32(t), uint32(t), uint32(timeout), h) }).To(Panic())
+			Expect(func() { New(indices[0], indices, uint32(b), uint32(n-t+1), uint32(t), uint32(timeout), h) }).To(Panic())
+		})
+
+		Specify("batch size or timeout less than 1", func() {
+			Expect(func() { New(indices[0], indices, 0, uint32(k), uint32(t), uint32(timeout), h) }).To(Panic())
+			Expect(func() { New(indices[0], indices, uint32(b), uint32(k), uint32(t), 0, h) }).To(Panic())
+		})
+
+		Specify("unknown index", func() {
+			index := secp256k1.RandomFn()
+			Expect(func() { New(index, indices, uint32(b), uint32(k), uint32(t), uint32(timeout), h) }).To(Panic())
+		})
+
+		Specify("insecure pedersen parameter", func() {
+			Expect(func() {
+				New(indices[rand.Intn(n)], indices, uint32(b), uint32(k), uint32(t), uint32(timeout), secp256k1.NewPointInfinity())
+			}).To(Panic())
+		})
+	})
+})
@@ -0,0 +1,685 @@
+package bft
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+	"github.com/renproject/surge"
+)
+
+// SizeHint implements the surge.SizeHinter interface.
+func (replica Replica) SizeHint() int {
+	return surge.SizeHint(replica.ownPos) +
+		replica.ownIndex.SizeHint() +
+		surge.SizeHint(replica.indices) +
+		surge.SizeHint(replica.batchSize) +
+		surge.SizeHint(replica.k) +
+		surge.SizeHint(replica.t) +
+		surge.SizeHint(replica.timeout) +
+		replica.h.SizeHint() +
+		surge.SizeHint(replica.row) +
+		surge.SizeHint(replica.rowSent) +
+		surge.SizeHint(replica.deals) +
+		surge.SizeHint(replica.acks) +
+		surge.SizeHint(replica.dealPhase) +
+		surge.SizeHint(replica.dealTicks) +
+		surge.SizeHint(replica.complaints) +
+		surge.SizeHint(replica.reveals) +
+		surge.SizeHint(replica.revealedDeals) +
+		surge.SizeHint(replica.round) +
+		surge.SizeHint(replica.phase) +
+		surge.SizeHint(replica.ticks) +
+		surge.SizeHint(replica.proposed) +
+		surge.SizeHint(replica.lockedRound) +
+		surge.SizeHint(replica.validRound) +
+		surge.SizeHint(replica.lockedHash) +
+		replica.validProposal.SizeHint() +
+		replica.decision.SizeHint() +
+		surge.SizeHint(replica.proposals) +
+		surge.SizeHint(replica.prevotes) +
+		surge.SizeHint(replica.precommits)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (replica Replica) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(replica.ownPos, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.ownIndex.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(replica.batchSize, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(replica.k, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(replica.t, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(replica.timeout, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.h.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(replica.rowSent, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.deals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.acks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU8(replica.dealPhase, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(replica.dealTicks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.complaints, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.reveals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.revealedDeals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(replica.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU8(replica.phase, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(replica.ticks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(replica.proposed, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalI32(replica.lockedRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalI32(replica.validRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.lockedHash, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.validProposal.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.decision.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.proposals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(replica.prevotes, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(replica.precommits, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (replica *Replica) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&replica.ownPos, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.ownIndex.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&replica.batchSize, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&replica.k, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&replica.t, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&replica.timeout, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.h.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&replica.rowSent, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.deals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.acks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU8(&replica.dealPhase, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&replica.dealTicks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.complaints, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.reveals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.revealedDeals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&replica.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU8(&replica.phase, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&replica.ticks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&replica.proposed, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalI32(&replica.lockedRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalI32(&replica.validRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.lockedHash, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.validProposal.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = replica.decision.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.proposals, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&replica.prevotes, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&replica.precommits, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (replica Replica) Generate(rand *rand.Rand, size int) reflect.Value {
+	size /= 8
+	n := rand.Intn(size/2+1) + 1
+	deals := make([][]brng.Sharing, n)
+	for i := range deals {
+		deals[i] = randomRow(rand, size)
+	}
+	acks := make([]ack, rand.Intn(size+1))
+	for i := range acks {
+		acks[i] = ack{dealer: rand.Uint32(), from: rand.Uint32()}
+		rand.Read(acks[i].hash[:])
+	}
+	complaints := make([]complaint, rand.Intn(size+1))
+	for i := range complaints {
+		complaints[i] = complaint{dealer: rand.Uint32(), from: rand.Uint32()}
+	}
+	reveals := make([]reveal, rand.Intn(size+1))
+	for i := range reveals {
+		reveals[i] = reveal{dealer: rand.Uint32(), to: rand.Uint32()}
+		rand.Read(reveals[i].hash[:])
+	}
+	revealedDeals := make([][]brng.Sharing, n)
+	for i := range revealedDeals {
+		revealedDeals[i] = randomRow(rand, size)
+	}
+	proposals := make([]proposal, rand.Intn(3))
+	for i := range proposals {
+		proposals[i] = randomProposal(rand, size)
+	}
+	randomVotes := func() []vote {
+		votes := make([]vote, rand.Intn(size+1))
+		for i := range votes {
+			votes[i] = vote{round: rand.Uint32(), from: rand.Uint32()}
+			rand.Read(votes[i].hash[:])
+		}
+		return votes
+	}
+	var lockedHash [32]byte
+	rand.Read(lockedHash[:])
+	r := Replica{
+		ownPos:        rand.Uint32(),
+		ownIndex:      secp256k1.RandomFn(),
+		indices:       shamirutil.RandomIndices(n),
+		batchSize:     rand.Uint32(),
+		k:             rand.Uint32(),
+		t:             rand.Uint32(),
+		timeout:       rand.Uint32(),
+		h:             secp256k1.RandomPoint(),
+		row:           randomRow(rand, size),
+		rowSent:       rand.Int()&1 == 1,
+		deals:         deals,
+		acks:          acks,
+		dealPhase:     uint8(rand.Intn(3)),
+		dealTicks:     rand.Uint32(),
+		complaints:    complaints,
+		reveals:       reveals,
+		revealedDeals: revealedDeals,
+		round:         rand.Uint32(),
+		phase:         uint8(rand.Intn(3)),
+		ticks:         rand.Uint32(),
+		proposed:      rand.Int()&1 == 1,
+		lockedRound:   rand.Int31(),
+		validRound:    rand.Int31(),
+		lockedHash:    lockedHash,
+		validProposal: randomProposal(rand, size),
+		decision:      randomProposal(rand, size),
+		proposals:     proposals,
+		prevotes:      randomVotes(),
+		precommits:    randomVotes(),
+	}
+	return reflect.ValueOf(r)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (msg Message) SizeHint() int {
+	return surge.SizeHint(uint8(msg.Type)) +
+		surge.SizeHint(msg.To) +
+		surge.SizeHint(msg.Dealer) +
+		surge.SizeHint(msg.Round) +
+		surge.SizeHint(msg.ValidRound) +
+		surge.SizeHint(msg.Hash) +
+		surge.SizeHint(msg.Row) +
+		surge.SizeHint(msg.Contributors) +
+		surge.SizeHint(msg.Commitments)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (msg Message) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU8(uint8(msg.Type), buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(msg.To, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(msg.Dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(msg.Round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalI32(msg.ValidRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(msg.Hash, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(msg.Row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(msg.Contributors, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(msg.Commitments, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (msg *Message) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU8((*uint8)(&msg.Type), buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&msg.To, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&msg.Dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&msg.Round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalI32(&msg.ValidRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&msg.Hash, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&msg.Row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&msg.Contributors, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&msg.Commitments, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (msg Message) Generate(rand *rand.Rand, size int) reflect.Value {
+	size /= 8
+	m := Message{
+		Type:         MessageType(rand.Intn(7)),
+		To:           rand.Uint32(),
+		Dealer:       rand.Uint32(),
+		Round:        rand.Uint32(),
+		ValidRound:   rand.Int31(),
+		Row:          randomRow(rand, size),
+		Contributors: randomContributors(rand, size),
+		Commitments:  randomCommitments(rand),
+	}
+	rand.Read(m.Hash[:])
+	return reflect.ValueOf(m)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (p proposal) SizeHint() int {
+	return surge.SizeHint(p.round) +
+		surge.SizeHint(p.validRound) +
+		surge.SizeHint(p.contributors) +
+		surge.SizeHint(p.commitments) +
+		surge.SizeHint(p.hash) +
+		surge.SizeHint(p.valid)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (p proposal) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(p.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalI32(p.validRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(p.contributors, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(p.commitments, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(p.hash, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.MarshalBool(p.valid, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (p *proposal) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&p.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalI32(&p.validRound, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&p.contributors, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&p.commitments, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&p.hash, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.UnmarshalBool(&p.valid, buf, rem)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (v vote) SizeHint() int {
+	return surge.SizeHint(v.round) + surge.SizeHint(v.from) + surge.SizeHint(v.hash)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (v vote) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(v.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(v.from, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(v.hash, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (v *vote) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&v.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&v.from, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&v.hash, buf, rem)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (a ack) SizeHint() int {
+	return surge.SizeHint(a.dealer) + surge.SizeHint(a.from) + surge.SizeHint(a.hash)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (a ack) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(a.dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(a.from, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(a.hash, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (a *ack) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&a.dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&a.from, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&a.hash, buf, rem)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (c complaint) SizeHint() int {
+	return surge.SizeHint(c.dealer) + surge.SizeHint(c.from)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (c complaint) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(c.dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.MarshalU32(c.from, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (c *complaint) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&c.dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.UnmarshalU32(&c.from, buf, rem)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (r reveal) SizeHint() int {
+	return surge.SizeHint(r.dealer) + surge.SizeHint(r.to) + surge.SizeHint(r.hash)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (r reveal) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(r.dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(r.to, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(r.hash, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (r *reveal) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&r.dealer, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&r.to, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&r.hash, buf, rem)
+}
+
+func randomProposal(rand *rand.Rand, size int) proposal {
+	p := proposal{
+		round:        rand.Uint32(),
+		validRound:   rand.Int31(),
+		contributors: randomContributors(rand, size),
+		commitments:  randomCommitments(rand),
+		valid:        rand.Int()&1 == 1,
+	}
+	rand.Read(p.hash[:])
+	return p
+}
+
+func randomContributors(rand *rand.Rand, size int) []uint32 {
+	contributors := make([]uint32, rand.Intn(size+1))
+	for i := range contributors {
+		contributors[i] = rand.Uint32()
+	}
+	return contributors
+}
+
+func randomCommitments(rand *rand.Rand) [][]shamir.Commitment {
+	commitments := make([][]shamir.Commitment, rand.Intn(3))
+	for i := range commitments {
+		commitments[i] = make([]shamir.Commitment, rand.Intn(3))
+		for j := range commitments[i] {
+			commitments[i][j] = shamir.Commitment{}.Generate(rand, 3).Interface().(shamir.Commitment)
+		}
+	}
+	return commitments
+}
+
+func randomRow(rand *rand.Rand, size int) []brng.Sharing {
+	row := make([]brng.Sharing, rand.Intn(3))
+	for i := range row {
+		shares := make(shamir.VerifiableShares, rand.Intn(size/2+1))
+		for j := range shares {
+			shares[j] = shamir.VerifiableShare{
+				Share: shamir.Share{
+					Index: secp256k1.RandomFn(),
+					Value: secp256k1.RandomFn(),
+				},
+				Decommitment: secp256k1.RandomFn(),
+			}
+		}
+		row[i] = brng.Sharing{
+			Shares:     shares,
+			Commitment: shamir.Commitment{}.Generate(rand, 3).Interface().(shamir.Commitment),
+		}
+	}
+	return row
+}
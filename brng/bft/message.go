@@ -0,0 +1,67 @@
+package bft
+
+import (
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/shamir"
+)
+
+// MessageType represents the different types of messages that are sent between
+// the replicas.
+type MessageType uint8
+
+const (
+	// TypeRow is the type of the message that a player uses to send the
+	// shares in its row of sharings to one of the other players. Unlike the
+	// other messages, it is only sent to the player given by the To field.
+	TypeRow = MessageType(iota)
+
+	// TypeAck is the type of the message that a player uses to acknowledge
+	// that it has received valid shares from a dealer.
+	TypeAck
+
+	// TypeProposal is the type of the message that the proposer for a round
+	// uses to propose a table.
+	TypeProposal
+
+	// TypePrevote is the type of a prevote for a table, or for nil.
+	TypePrevote
+
+	// TypePrecommit is the type of a precommit for a table, or for nil.
+	TypePrecommit
+
+	// TypeComplaint is the type of the message that a player uses to
+	// complain that it has not received valid shares from a dealer.
+	TypeComplaint
+
+	// TypeReveal is the type of the message that a dealer uses to reveal the
+	// shares of a player that complained about it.
+	TypeReveal
+)
+
+// A Message is sent between replicas during the consensus protocol. Which of
+// the fields are used depends on the type of the message:
+//   - TypeRow: To and Row. The row only contains the shares for the player at
+//     position To, along with the commitments for each sharing.
+//   - TypeAck: Dealer and Hash, which is the hash of the commitments that the
+//     dealer sent.
+//   - TypeProposal: Round, ValidRound, Contributors and Commitments, where
+//     Commitments[i] are the commitments in the row of Contributors[i].
+//   - TypePrevote and TypePrecommit: Round and Hash. A zero hash represents a
+//     vote for nil.
+//   - TypeComplaint: Dealer.
+//   - TypeReveal: To and Row, where the row is the same as for TypeRow and To
+//     is the player that complained.
+//
+// The sender of a message is not included; it is assumed that the sender is
+// authenticated by the underlying network.
+type Message struct {
+	Type         MessageType
+	To           uint32
+	Dealer       uint32
+	Round        uint32
+	ValidRound   int32
+	Hash         [32]byte
+	Row          []brng.Sharing
+	Contributors []uint32
+	Commitments  [][]shamir.Commitment
+}
@@ -2,8 +2,10 @@ package brng
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/renproject/mpc/params"
+	"github.com/renproject/mpc/random"
 	"github.com/renproject/secp256k1"
 	"github.com/renproject/shamir"
 )
@@ -18,6 +20,19 @@ import (
 // reconstruction threshold (k) are less than 1, or if the Pedersen parameter
 // is known to be insecure.
 func New(batchSize, k uint32, indices []secp256k1.Fn, index secp256k1.Fn, h secp256k1.Point) []Sharing {
+	return NewWithRand(random.Reader, batchSize, k, indices, index, h)
+}
+
+// NewWithRand is the same as New, except that the randomness for the sharings
+// is read from the given source.
+//
+// Panics: This function will panic in the same cases as New, or if the source
+// of randomness returns an error.
+func NewWithRand(
+	r io.Reader,
+	batchSize, k uint32,
+	indices []secp256k1.Fn, index secp256k1.Fn, h secp256k1.Point,
+) []Sharing {
 	if batchSize < 1 {
 		panic(fmt.Sprintf("batch size must be at least 1: got %v", batchSize))
 	}
@@ -32,8 +47,8 @@ func New(batchSize, k uint32, indices []secp256k1.Fn, index secp256k1.Fn, h secp
 	for i := range sharings {
 		sharings[i].Shares = make(shamir.VerifiableShares, n)
 		sharings[i].Commitment = shamir.NewCommitmentWithCapacity(int(k))
-		shamir.VShareSecret(&sharings[i].Shares, &sharings[i].Commitment,
-			indices, h, secp256k1.RandomFn(), int(k))
+		random.VShareSecret(r, &sharings[i].Shares, &sharings[i].Commitment,
+			indices, h, random.Fn(r), int(k))
 	}
 	return sharings
 }
@@ -45,7 +60,8 @@ func New(batchSize, k uint32, indices []secp256k1.Fn, index secp256k1.Fn, h secp
 // A return value of true means that this consensus output can be used to
 // construct the output shares and commitments for BRNG. If the return value is
 // false, then either the shares or the commitments or both are not valid, and
-// a corresponding error is returned based on how they are invalid.
+// a corresponding error is returned based on how they are invalid. To find out
+// which of the contributions are invalid, use CheckValidity.
 //
 // Panics: This function will panic if the given required contributions is less
 // than 1.
@@ -74,6 +90,9 @@ func IsValid(
 		}
 	}
 	k := commitmentsBatch[0][0].Len()
+	if k == 0 {
+		return ErrInvalidCommitmentDimensions
+	}
 	for _, commitments := range commitmentsBatch {
 		for _, commitment := range commitments {
 			if commitment.Len() != k {
@@ -69,6 +69,20 @@ var _ = Describe("BRNG", func() {
 				}
 			}
 		})
+
+		Specify("sharings created from the same seed should be the same", func() {
+			_, k, b, _, indices, index, h := RandomTestParameters()
+			seed := rand.Int63()
+			sharingBatch := NewWithRand(rand.New(rand.NewSource(seed)), b, k, indices, index, h)
+			Expect(NewWithRand(rand.New(rand.NewSource(seed)), b, k, indices, index, h)).To(Equal(sharingBatch))
+			Expect(NewWithRand(rand.New(rand.NewSource(seed+1)), b, k, indices, index, h)).ToNot(Equal(sharingBatch))
+			for _, sharing := range sharingBatch {
+				Expect(shamirutil.VsharesAreConsistent(sharing.Shares, int(k))).To(BeTrue())
+				for _, share := range sharing.Shares {
+					Expect(shamir.IsValid(h, &sharing.Commitment, &share)).To(BeTrue())
+				}
+			}
+		})
 	})
 
 	Context("checking if consensus outputs are valid", func() {
@@ -176,6 +190,157 @@ var _ = Describe("BRNG", func() {
 		})
 	})
 
+	Context("batched verification", func() {
+		Specify("valid share and commitment batches", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t, indices, index, h)
+			err := IsValidBatched(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Specify("a single invalid share should be detected", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t, indices, index, h)
+			i, j := rand.Intn(int(b)), rand.Intn(t)
+			sharesBatch[i][j].Decommitment = secp256k1.RandomFn()
+			err := IsValidBatched(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(err).To(Equal(ErrInvalidShares))
+		})
+
+		Specify("invalid shares that cancel in an unweighted sum should be detected", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t+1, indices, index, h)
+			offset := secp256k1.RandomFn()
+			sharesBatch[0][0].Share.Value.Add(&sharesBatch[0][0].Share.Value, &offset)
+			offset.Negate(&offset)
+			sharesBatch[0][1].Share.Value.Add(&sharesBatch[0][1].Share.Value, &offset)
+			err := IsValidBatched(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(err).To(Equal(ErrInvalidShares))
+		})
+
+		Specify("the errors should be the same as for IsValid", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t, indices, index, h)
+
+			err := IsValidBatched(b, index, h, sharesBatch[1:], commitmentsBatch, t)
+			Expect(err).To(Equal(ErrIncorrectSharesBatchSize))
+
+			err = IsValidBatched(b, index, h, sharesBatch, commitmentsBatch[1:], t)
+			Expect(err).To(Equal(ErrIncorrectCommitmentsBatchSize))
+
+			err = IsValidBatched(b, index, h, sharesBatch, commitmentsBatch, t+1)
+			Expect(err).To(Equal(ErrNotEnoughContributions))
+
+			badIndex := indices[0]
+			if badIndex.Eq(&index) {
+				badIndex = indices[1]
+			}
+			sharesBatch[0][0].Share.Index = badIndex
+			err = IsValidBatched(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(err).To(Equal(ErrIncorrectIndex))
+
+			commitmentsBatch[0][0] = shamir.NewCommitmentWithCapacity(int(k) - 1)
+			err = IsValidBatched(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(err).To(Equal(IsValid(b, index, h, sharesBatch, commitmentsBatch, t)))
+		})
+
+		Specify("invalid required contributions should panic", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t, indices, index, h)
+			Expect(func() { IsValidBatched(b, index, h, sharesBatch, commitmentsBatch, 0) }).To(Panic())
+		})
+	})
+
+	Context("validity reports", func() {
+		Specify("valid batches should have every check pass", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t, indices, index, h)
+			report := CheckValidity(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(report.Err).ToNot(HaveOccurred())
+			Expect(report.Contributions).To(HaveLen(int(b)))
+			for i := range report.Contributions {
+				Expect(report.Contributions[i]).To(HaveLen(t))
+				for j := range report.Contributions[i] {
+					Expect(report.Contributions[i][j].Valid()).To(BeTrue())
+				}
+			}
+			Expect(report.ValidContributions()).To(HaveLen(t))
+		})
+
+		Specify("the report should identify the invalid contributions", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t+3, indices, index, h)
+
+			badIndex := indices[0]
+			if badIndex.Eq(&index) {
+				badIndex = indices[1]
+			}
+			i := rand.Intn(int(b))
+			commitmentsBatch[i][0] = shamir.NewCommitmentWithCapacity(int(k) + 1)
+			sharesBatch[i][1].Share.Index = badIndex
+			sharesBatch[i][2].Share.Value = secp256k1.RandomFn()
+
+			report := CheckValidity(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(report.Err).To(Equal(IsValid(b, index, h, sharesBatch, commitmentsBatch, t)))
+			Expect(report.Contributions[i][0]).To(Equal(ContributionValidity{}))
+			Expect(report.Contributions[i][1]).To(Equal(ContributionValidity{Shape: true}))
+			Expect(report.Contributions[i][2]).To(Equal(ContributionValidity{Shape: true, Index: true}))
+			valid := report.ValidContributions()
+			Expect(valid).To(HaveLen(t))
+			for l := range valid {
+				Expect(valid[l]).To(Equal(l + 3))
+			}
+		})
+
+		Specify("the report error should be the same as for IsValid", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t, indices, index, h)
+
+			report := CheckValidity(b, index, h, sharesBatch[1:], commitmentsBatch, t)
+			Expect(report.Err).To(Equal(ErrIncorrectSharesBatchSize))
+			Expect(report.Contributions).To(BeNil())
+
+			report = CheckValidity(b, index, h, sharesBatch, commitmentsBatch[1:], t)
+			Expect(report.Err).To(Equal(ErrIncorrectCommitmentsBatchSize))
+			Expect(report.Contributions).To(BeNil())
+
+			report = CheckValidity(b, index, h, sharesBatch, commitmentsBatch, t+1)
+			Expect(report.Err).To(Equal(ErrNotEnoughContributions))
+
+			sharesBatch[0] = sharesBatch[0][1:]
+			report = CheckValidity(b, index, h, sharesBatch, commitmentsBatch, t)
+			Expect(report.Err).To(Equal(ErrInvalidShareDimensions))
+			Expect(report.Contributions[0][t-1].Shape).To(BeFalse())
+		})
+
+		Specify("filtering should allow the valid contributions to be used", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t+1, indices, index, h)
+			j := rand.Intn(t + 1)
+			sharesBatch[0][j].Share.Value = secp256k1.RandomFn()
+			Expect(IsValid(b, index, h, sharesBatch, commitmentsBatch, t)).To(Equal(ErrInvalidShares))
+
+			report := CheckValidity(b, index, h, sharesBatch, commitmentsBatch, t)
+			filteredShares, filteredCommitments := report.Filter(sharesBatch, commitmentsBatch)
+			Expect(filteredShares).To(HaveLen(int(b)))
+			Expect(filteredCommitments).To(HaveLen(int(b)))
+			for i := range filteredShares {
+				Expect(filteredShares[i]).To(HaveLen(t))
+				Expect(filteredCommitments[i]).To(HaveLen(t))
+			}
+			Expect(IsValid(b, index, h, filteredShares, filteredCommitments, t)).To(Succeed())
+		})
+
+		Specify("filtering with a report that has no contributions", func() {
+			_, k, b, t, indices, index, h := RandomTestParameters()
+			sharesBatch, commitmentsBatch := ValidBatches(k, b, t, indices, index, h)
+			report := CheckValidity(b, index, h, sharesBatch[1:], commitmentsBatch, t)
+			filteredShares, filteredCommitments := report.Filter(sharesBatch, commitmentsBatch)
+			Expect(filteredShares).To(BeNil())
+			Expect(filteredCommitments).To(BeNil())
+		})
+	})
+
 	Context("constructing output shares and commitments", func() {
 		It("should return nil shares when the corresponding argument is nil", func() {
 			_, k, b, t, indices, index, h := RandomTestParameters()
@@ -207,6 +372,100 @@ var _ = Describe("BRNG", func() {
 		})
 	})
 
+	Context("encrypted rows", func() {
+		RandomKeys := func(n int) ([]secp256k1.Fn, []secp256k1.Point) {
+			privKeys := make([]secp256k1.Fn, n)
+			pubKeys := make([]secp256k1.Point, n)
+			for i := range privKeys {
+				privKeys[i] = secp256k1.RandomFn()
+				pubKeys[i].BaseExp(&privKeys[i])
+			}
+			return privKeys, pubKeys
+		}
+
+		Specify("shares should decrypt correctly with the right private key", func() {
+			n, k, b, _, indices, index, h := RandomTestParameters()
+			privKeys, pubKeys := RandomKeys(n)
+			row := New(b, k, indices, index, h)
+			encRow := EncryptRow(row, pubKeys)
+
+			Expect(len(encRow)).To(Equal(int(b)))
+			for i := range encRow {
+				Expect(encRow[i].Commitment.Eq(row[i].Commitment)).To(BeTrue())
+				for j, encShare := range encRow[i].Shares {
+					share, err := DecryptShare(encShare, privKeys[j])
+					Expect(err).ToNot(HaveOccurred())
+					Expect(share.Eq(&row[i].Shares[j])).To(BeTrue())
+				}
+			}
+		})
+
+		Specify("decryption should fail with the wrong key or a modified ciphertext", func() {
+			n, k, b, _, indices, index, h := RandomTestParameters()
+			privKeys, pubKeys := RandomKeys(n)
+			encRow := NewEncrypted(b, k, indices, pubKeys, index, h)
+
+			encShare := encRow[0].Shares[0]
+			_, err := DecryptShare(encShare, privKeys[1])
+			Expect(err).To(Equal(ErrDecryptionFailed))
+
+			encShare.Ciphertext = append([]byte{}, encShare.Ciphertext...)
+			encShare.Ciphertext[rand.Intn(len(encShare.Ciphertext))] ^= 1
+			_, err = DecryptShare(encShare, privKeys[0])
+			Expect(err).To(Equal(ErrDecryptionFailed))
+
+			encShare = encRow[0].Shares[0]
+			encShare.Index = indices[1]
+			_, err = DecryptShare(encShare, privKeys[0])
+			Expect(err).To(Equal(ErrDecryptionFailed))
+		})
+
+		Specify("players should compute valid and consistent outputs from a table of encrypted rows", func() {
+			n, k, b, _, indices, _, h := RandomTestParameters()
+			privKeys, pubKeys := RandomKeys(n)
+			table := make([][]EncryptedSharing, k)
+			for i := range table {
+				table[i] = NewEncrypted(b, k, indices, pubKeys, indices[i], h)
+			}
+
+			sharesBatch := make([]shamir.VerifiableShares, n)
+			var commitments []shamir.Commitment
+			for i := range indices {
+				shares, coms, err := HandleEncryptedConsensusOutput(table, indices[i], privKeys[i], h)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(shares)).To(Equal(int(b)))
+				if commitments == nil {
+					commitments = coms
+				}
+				for j := range coms {
+					Expect(coms[j].Eq(commitments[j])).To(BeTrue())
+					Expect(shamir.IsValid(h, &coms[j], &shares[j])).To(BeTrue())
+				}
+				sharesBatch[i] = shares
+			}
+			for j := uint32(0); j < b; j++ {
+				shares := make(shamir.VerifiableShares, n)
+				for i := range shares {
+					shares[i] = sharesBatch[i][j]
+				}
+				Expect(shamirutil.VsharesAreConsistent(shares, int(k))).To(BeTrue())
+			}
+		})
+
+		Specify("a player with the wrong private key should get nil output shares", func() {
+			n, k, b, _, indices, _, h := RandomTestParameters()
+			_, pubKeys := RandomKeys(n)
+			table := make([][]EncryptedSharing, k)
+			for i := range table {
+				table[i] = NewEncrypted(b, k, indices, pubKeys, indices[i], h)
+			}
+			shares, coms, err := HandleEncryptedConsensusOutput(table, indices[0], secp256k1.RandomFn(), h)
+			Expect(err).To(Equal(ErrDecryptionFailed))
+			Expect(shares).To(BeNil())
+			Expect(len(coms)).To(Equal(int(b)))
+		})
+	})
+
 	Context("panics", func() {
 		Context("when creating a new BRNGer", func() {
 			Specify("batch size less than 1", func() {
@@ -224,6 +483,12 @@ var _ = Describe("BRNG", func() {
 				h := secp256k1.NewPointInfinity()
 				Expect(func() { New(b, k, indices, index, h) }).To(Panic())
 			})
+
+			Specify("wrong number of public keys for encrypted rows", func() {
+				_, k, b, _, indices, index, h := RandomTestParameters()
+				pubKeys := make([]secp256k1.Point, len(indices)-1)
+				Expect(func() { NewEncrypted(b, k, indices, pubKeys, index, h) }).To(Panic())
+			})
 		})
 
 		Context("when checking validity", func() {
@@ -318,6 +583,76 @@ var _ = Describe("BRNG", func() {
 
 				Expect(shamirutil.VsharesAreConsistent(shares, int(k))).To(BeTrue())
 			}
+
+			// Only the online players have outputs, and the consensus trusted
+			// party is not included.
+			outputs := network.Outputs()
+			Expect(outputs).ToNot(HaveKey(consID))
+			for i, id := range playerIDs {
+				if isOffline[id] {
+					Expect(outputs).ToNot(HaveKey(id))
+				} else {
+					Expect(outputs).To(HaveKeyWithValue(id, machines[i].(*brngutil.BrngMachine).Shares()))
+				}
+			}
+		})
+
+		Specify("BRNG should function correctly without a trusted party using the BFT consensus", func() {
+			n, k, b, _, indices, _, h := RandomTestParameters()
+
+			// The consensus protocol requires that k >= t+1.
+			t := (n - 1) / 3
+			if t > int(k)-1 {
+				t = int(k) - 1
+			}
+
+			playerIDs := make([]ID, len(indices))
+			for i := range playerIDs {
+				playerIDs[i] = ID(i + 1)
+			}
+			shuffleMsgs, isOffline := MessageShufflerDropper(playerIDs, t)
+
+			machines := make([]Machine, 0, len(indices))
+			for i, id := range playerIDs {
+				machine := brngutil.NewBFTMachine(id, playerIDs, indices, indices[i], h, int(k), int(b), t, 10)
+				machines = append(machines, &machine)
+			}
+
+			network := NewNetwork(machines, shuffleMsgs)
+			network.SetCaptureHist(true)
+
+			err := network.Run()
+			Expect(err).ToNot(HaveOccurred())
+
+			// Check that for each batch, the online players have the same
+			// output commitment and their output shares form a consistent
+			// and valid sharing.
+			var ref *brngutil.BrngMachine
+			for i := range machines {
+				if !isOffline[playerIDs[i]] {
+					ref = machines[i].(*brngutil.BrngMachine)
+					break
+				}
+			}
+			for j := uint32(0); j < b; j++ {
+				shares := make(shamir.VerifiableShares, 0, n-t)
+				for i := range machines {
+					if isOffline[playerIDs[i]] {
+						continue
+					}
+
+					machine := machines[i].(*brngutil.BrngMachine)
+					machineShares := machine.Shares()
+					machineCommitments := machine.Commitments()
+
+					Expect(machineCommitments[j].Eq(ref.Commitments()[j])).To(BeTrue())
+					Expect(shamir.IsValid(h, &machineCommitments[j], &machineShares[j])).To(BeTrue())
+
+					shares = append(shares, machineShares[j])
+				}
+
+				Expect(shamirutil.VsharesAreConsistent(shares, int(k))).To(BeTrue())
+			}
 		})
 	})
 })
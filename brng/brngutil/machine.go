@@ -2,14 +2,18 @@ package brngutil
 
 import (
 	"fmt"
+	"io"
+	"math/rand"
 
 	"github.com/renproject/secp256k1"
 	"github.com/renproject/shamir"
 	"github.com/renproject/surge"
 
 	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/brng/bft"
 	"github.com/renproject/mpc/brng/mock"
 	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/random"
 )
 
 // PlayerMachine represents one of the players participating in the BRNG
@@ -112,6 +116,9 @@ func (pm *PlayerMachine) Handle(msg mpcutil.Message) []mpcutil.Message {
 	return nil
 }
 
+// Done implements the mpcutil.Terminator interface.
+func (pm PlayerMachine) Done() bool { return len(pm.Commitments) != 0 }
+
 // ConsensusMachine represents the trusted party for the consensus algorithm
 // used by the BRNG algorithm.
 type ConsensusMachine struct {
@@ -197,6 +204,9 @@ func (cm *ConsensusMachine) Handle(msg mpcutil.Message) []mpcutil.Message {
 	return nil
 }
 
+// Done implements the mpcutil.Terminator interface.
+func (cm ConsensusMachine) Done() bool { return cm.engine.Done() }
+
 func (cm ConsensusMachine) formConsensusMessages() []mpcutil.Message {
 	var messages []mpcutil.Message
 
@@ -238,6 +248,178 @@ func (cm ConsensusMachine) formConsensusMessages() []mpcutil.Message {
 	return messages
 }
 
+// BFTMachine represents one of the players participating in the BRNG
+// algorithm, where the players use the local BFT consensus protocol to agree
+// on the table of sharings instead of relying on a trusted party.
+type BFTMachine struct {
+	id      mpcutil.ID
+	ids     []mpcutil.ID
+	index   secp256k1.Fn
+	h       secp256k1.Point
+	k       uint32
+	replica bft.Replica
+
+	Shares      shamir.VerifiableShares
+	Commitments []shamir.Commitment
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (bm BFTMachine) SizeHint() int {
+	return bm.id.SizeHint() +
+		surge.SizeHint(bm.ids) +
+		bm.index.SizeHint() +
+		bm.h.SizeHint() +
+		surge.SizeHint(bm.k) +
+		bm.replica.SizeHint() +
+		bm.Shares.SizeHint() +
+		surge.SizeHint(bm.Commitments)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (bm BFTMachine) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := bm.id.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(bm.ids, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.index.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.h.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(bm.k, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.replica.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.Shares.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(bm.Commitments, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (bm *BFTMachine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := bm.id.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&bm.ids, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.index.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.h.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&bm.k, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.replica.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.Shares.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&bm.Commitments, buf, rem)
+}
+
+// ID implements the Machine interface.
+func (bm BFTMachine) ID() mpcutil.ID {
+	return bm.id
+}
+
+// InitialMessages implements the Machine intercace. The row of the player is
+// sent on the first tick of the consensus protocol, and so the only initial
+// message is a tick message from the player to itself.
+func (bm BFTMachine) InitialMessages() []mpcutil.Message {
+	return []mpcutil.Message{
+		&BrngMessage{
+			msg: &BFTMessage{from: bm.id, to: bm.id, tick: true},
+		},
+	}
+}
+
+// Handle implements the Machine interface.
+func (bm *BFTMachine) Handle(msg mpcutil.Message) []mpcutil.Message {
+	bmsg := msg.(*BFTMessage)
+	if bm.replica.Done() {
+		return nil
+	}
+	if bmsg.tick {
+		return bm.tick()
+	}
+
+	var from uint32
+	for from = 0; bm.ids[from] != bmsg.from; from++ {
+	}
+	msgs := bm.replica.HandleMessage(from, bmsg.msg)
+	bm.handleDecision()
+	return bm.broadcast(msgs)
+}
+
+// Done implements the mpcutil.Terminator interface.
+func (bm BFTMachine) Done() bool { return bm.replica.Done() }
+
+// tick advances the clock of the replica and returns the resulting messages,
+// along with a tick message to itself if the replica has not yet decided.
+func (bm *BFTMachine) tick() []mpcutil.Message {
+	msgs := bm.broadcast(bm.replica.Tick())
+	bm.handleDecision()
+	if !bm.replica.Done() {
+		msgs = append(msgs, &BrngMessage{
+			msg: &BFTMessage{from: bm.id, to: bm.id, tick: true},
+		})
+	}
+	return msgs
+}
+
+func (bm *BFTMachine) handleDecision() {
+	if !bm.replica.Done() || len(bm.Commitments) != 0 {
+		return
+	}
+	sharesBatch, commitmentsBatch := brng.TableSharesAndCommitments(bm.replica.Table(), bm.index)
+	if brng.IsValid(uint32(len(commitmentsBatch)), bm.index, bm.h, sharesBatch, commitmentsBatch, int(bm.k)) != nil {
+		sharesBatch = nil
+	}
+	bm.Shares, bm.Commitments = brng.HandleConsensusOutput(sharesBatch, commitmentsBatch)
+}
+
+// broadcast sends each of the given messages to all of the other players,
+// except for messages of type bft.TypeRow, which are only sent to the player
+// that they are addressed to.
+func (bm BFTMachine) broadcast(msgs []bft.Message) []mpcutil.Message {
+	var messages []mpcutil.Message
+	for _, msg := range msgs {
+		for i, id := range bm.ids {
+			if id == bm.id || (msg.Type == bft.TypeRow && uint32(i) != msg.To) {
+				continue
+			}
+			messages = append(messages, &BrngMessage{
+				msg: &BFTMessage{from: bm.id, to: id, msg: msg},
+			})
+		}
+	}
+	return messages
+}
+
 // BrngMachine represents a participant in the BRNG algorithm and can be either
 // a player or the consensus trusted party.
 type BrngMachine struct {
@@ -261,9 +443,28 @@ func NewMachine(
 	index secp256k1.Fn,
 	h secp256k1.Point,
 	k, b int,
+) BrngMachine {
+	return NewMachineWithRand(
+		rand.New(rand.NewSource(rand.Int63())),
+		machineType, id, consID, playerIDs, indices, honestIndices, index, h, k, b,
+	)
+}
+
+// NewMachineWithRand is the same as NewMachine, except that the sharings of a
+// player and the honest players chosen by the consensus trusted party are
+// generated using the given source of randomness.
+func NewMachineWithRand(
+	r *rand.Rand,
+	machineType TypeID,
+	id, consID mpcutil.ID,
+	playerIDs []mpcutil.ID,
+	indices, honestIndices []secp256k1.Fn,
+	index secp256k1.Fn,
+	h secp256k1.Point,
+	k, b int,
 ) BrngMachine {
 	if machineType == BrngTypePlayer {
-		row := brng.New(uint32(b), uint32(k), indices, index, h)
+		row := brng.NewWithRand(r, uint32(b), uint32(k), indices, index, h)
 
 		pmachine := PlayerMachine{
 			id:          id,
@@ -282,7 +483,7 @@ func NewMachine(
 	}
 
 	if machineType == BrngTypeConsensus {
-		engine := mock.NewPullConsensus(indices, honestIndices, k-1, h)
+		engine := mock.NewPullConsensusWithRand(r, indices, honestIndices, k-1, h)
 
 		cmachine := ConsensusMachine{
 			id:        consID,
@@ -299,6 +500,53 @@ func NewMachine(
 	panic("unexpected machine type")
 }
 
+// NewBFTMachine constructs a new player machine for the BRNG algorithm tests
+// that uses the local BFT consensus protocol. The IDs of all of the players
+// are given by ids, and the corresponding Shamir indices are given by indices.
+// The protocol will tolerate t faulty players, and the timeout is the number
+// of network rounds that a player will wait for progress in a step of the
+// consensus protocol. h is the Pedersen parameter, k is the Shamir threshold
+// and b is the batch size.
+func NewBFTMachine(
+	id mpcutil.ID,
+	ids []mpcutil.ID,
+	indices []secp256k1.Fn,
+	index secp256k1.Fn,
+	h secp256k1.Point,
+	k, b, t, timeout int,
+) BrngMachine {
+	return NewBFTMachineWithRand(random.Reader, id, ids, indices, index, h, k, b, t, timeout)
+}
+
+// NewBFTMachineWithRand is the same as NewBFTMachine, except that the
+// randomness for the sharings that the player deals is read from the given
+// source.
+func NewBFTMachineWithRand(
+	r io.Reader,
+	id mpcutil.ID,
+	ids []mpcutil.ID,
+	indices []secp256k1.Fn,
+	index secp256k1.Fn,
+	h secp256k1.Point,
+	k, b, t, timeout int,
+) BrngMachine {
+	replica := bft.New(index, indices, uint32(b), uint32(k), uint32(t), uint32(timeout), h)
+	replica.Propose(brng.NewWithRand(r, uint32(b), uint32(k), indices, index, h))
+
+	bmachine := BFTMachine{
+		id:      id,
+		ids:     ids,
+		index:   index,
+		h:       h,
+		k:       uint32(k),
+		replica: replica,
+	}
+
+	return BrngMachine{
+		machine: &bmachine,
+	}
+}
+
 // SizeHint implements the surge.SizeHinter interface.
 func (bm BrngMachine) SizeHint() int {
 	return 1 + bm.machine.SizeHint()
@@ -312,6 +560,8 @@ func (bm BrngMachine) Marshal(buf []byte, rem int) ([]byte, int, error) {
 		ty = BrngTypePlayer
 	case *ConsensusMachine:
 		ty = BrngTypeConsensus
+	case *BFTMachine:
+		ty = BrngTypeBFT
 	default:
 		panic(fmt.Sprintf("unexpected machine type %T", bm.machine))
 	}
@@ -337,6 +587,8 @@ func (bm *BrngMachine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 		bm.machine = new(PlayerMachine)
 	case BrngTypeConsensus:
 		bm.machine = new(ConsensusMachine)
+	case BrngTypeBFT:
+		bm.machine = new(BFTMachine)
 	default:
 		return buf, rem, fmt.Errorf("invalid machine type %v", ty)
 	}
@@ -359,29 +611,50 @@ func (bm *BrngMachine) Handle(msg mpcutil.Message) []mpcutil.Message {
 	bmsg := msg.(*BrngMessage)
 
 	switch msg := bmsg.msg.(type) {
-	case *PlayerMessage, *ConsensusMessage:
+	case *PlayerMessage, *ConsensusMessage, *BFTMessage:
 		return bm.machine.Handle(msg)
 	default:
 		panic(fmt.Sprintf("unexpected message type %T", msg))
 	}
 }
 
+// Done implements the mpcutil.Terminator interface.
+func (bm BrngMachine) Done() bool {
+	return bm.machine.(mpcutil.Terminator).Done()
+}
+
+// Result implements the mpcutil.Outputter interface. The output of a player
+// machine is the same as for Shares, and the consensus trusted party has no
+// output, so that only the players are included in the outputs of a network.
+func (bm BrngMachine) Result() interface{} {
+	if _, ok := bm.machine.(*ConsensusMachine); ok {
+		return nil
+	}
+	return bm.Shares()
+}
+
 // Shares returns the output shares of the player if the machine represents a
 // player machine, and nil otherwise.
 func (bm BrngMachine) Shares() shamir.VerifiableShares {
-	pm, ok := bm.machine.(*PlayerMachine)
-	if !ok {
+	switch m := bm.machine.(type) {
+	case *PlayerMachine:
+		return m.Shares
+	case *BFTMachine:
+		return m.Shares
+	default:
 		return nil
 	}
-	return pm.Shares
 }
 
 // Commitments returns the output commitments of the player if the machine
 // represents a player machine, and nil otherwise.
 func (bm BrngMachine) Commitments() []shamir.Commitment {
-	pm, ok := bm.machine.(*PlayerMachine)
-	if !ok {
+	switch m := bm.machine.(type) {
+	case *PlayerMachine:
+		return m.Commitments
+	case *BFTMachine:
+		return m.Commitments
+	default:
 		return nil
 	}
-	return pm.Commitments
 }
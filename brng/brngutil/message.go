@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/brng/bft"
 	"github.com/renproject/mpc/mpcutil"
 	"github.com/renproject/shamir"
 	"github.com/renproject/surge"
@@ -120,6 +121,68 @@ func (cm *ConsensusMessage) Unmarshal(buf []byte, rem int) ([]byte, int, error)
 	return surge.Unmarshal(&cm.commitmentsBatch, buf, rem)
 }
 
+// BFTMessage represents a message that a player sends to another player when
+// running the BRNG algorithm with the local BFT consensus protocol. A tick
+// message is sent by a player to itself to drive the timeouts of the
+// consensus protocol.
+type BFTMessage struct {
+	from, to mpcutil.ID
+	tick     bool
+	msg      bft.Message
+}
+
+// From implements the Message interface.
+func (bm BFTMessage) From() mpcutil.ID {
+	return bm.from
+}
+
+// To implements the Message interface.
+func (bm BFTMessage) To() mpcutil.ID {
+	return bm.to
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (bm BFTMessage) SizeHint() int {
+	return bm.from.SizeHint() +
+		bm.to.SizeHint() +
+		surge.SizeHint(bm.tick) +
+		bm.msg.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (bm BFTMessage) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := bm.from.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.to.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(bm.tick, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return bm.msg.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (bm *BFTMessage) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := bm.from.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = bm.to.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&bm.tick, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return bm.msg.Unmarshal(buf, rem)
+}
+
 // BrngMessage is a wrapper for any of the messages that can be sent during an
 // invocation of the BRNG algorithm.
 type BrngMessage struct {
@@ -149,6 +212,8 @@ func (bm BrngMessage) Marshal(buf []byte, rem int) ([]byte, int, error) {
 		ty = BrngTypePlayer
 	case *ConsensusMessage:
 		ty = BrngTypeConsensus
+	case *BFTMessage:
+		ty = BrngTypeBFT
 	default:
 		panic(fmt.Sprintf("unexpected message type %T", bm.msg))
 	}
@@ -174,6 +239,8 @@ func (bm *BrngMessage) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 		bm.msg = new(PlayerMessage)
 	case BrngTypeConsensus:
 		bm.msg = new(ConsensusMessage)
+	case BrngTypeBFT:
+		bm.msg = new(BFTMessage)
 	default:
 		return buf, rem, fmt.Errorf("invalid message type %v", ty)
 	}
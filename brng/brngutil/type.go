@@ -14,6 +14,10 @@ const (
 
 	// BrngTypeConsensus represents the consensus trusted party.
 	BrngTypeConsensus = TypeID(2)
+
+	// BrngTypeBFT represents the player type that runs the BRNG algorithm
+	// using the local BFT consensus protocol instead of a trusted party.
+	BrngTypeBFT = TypeID(3)
 )
 
 // SizeHint implements the surge.SizeHinter interface.
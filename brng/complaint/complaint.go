@@ -0,0 +1,407 @@
+// Package complaint implements an alternative flow for the BRNG algorithm in
+// the style of the distributed key generation protocols of Feldman and
+// Pedersen, where consensus is only needed on the set of qualified dealers
+// instead of on a whole table of sharings.
+//
+// The protocol has the following steps:
+//  1. Each player acts as a dealer and creates a row of sharings (see
+//     brng.New). Each share is sent privately to the player that it is for,
+//     along with the commitments (see Deal).
+//  2. Each player broadcasts a complaint against each of the dealers from
+//     which it did not receive valid shares (see Complain).
+//  3. Each dealer responds to the complaints against it by publicly revealing
+//     the shares of the complaining players (see Justify).
+//  4. A dealer is qualified if it has no more than t complaints against it and
+//     it has revealed a valid share for each complaint (see Qualified). The
+//     players agree on the qualified set, along with a hash of the
+//     commitments of each qualified dealer, using a consensus protocol, and
+//     then compute their output from the shares and commitments of the
+//     qualified dealers (see HandleOutcome).
+//
+// Since the complaints and justifications are broadcast, and the dealers are
+// only qualified based on these messages, the consensus protocol only needs
+// to agree on a list of at most n positions and hashes; this is much smaller
+// than the table of sharings that is otherwise needed. The commitments are
+// sent in the deals, so a dishonest dealer could send different commitments
+// to different players. The hashes in the agreed outcome ensure that all of
+// the players that compute an output use the same commitments; a player that
+// received different commitments can obtain the agreed commitments from
+// another player (see HandleCommitments). Honest dealers will always be
+// qualified.
+package complaint
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/params"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+// A Player is a state machine for one of the players in the complaint based
+// BRNG flow. Players are identified by their position in the list of indices.
+type Player struct {
+	ownPos           uint32
+	indices          []secp256k1.Fn
+	batchSize, k, t  uint32
+	h                secp256k1.Point
+	row              []brng.Sharing
+	commitments      [][]shamir.Commitment
+	shares           []shamir.VerifiableShares
+	valid            []bool
+	complaints       [][]uint32
+	justified        [][]uint32
+	badJustification []bool
+}
+
+// New creates a new player with the given index, and returns the deals that
+// need to be sent to each of the players; the ith deal is for the player with
+// the ith index. The deal for this player is handled internally. The batch
+// size and reconstruction threshold (k) are as in brng.New, and t is the
+// maximum number of complaints that a dealer can have while still being
+// qualified.
+//
+// Panics: This function will panic if the given index is not in the list of
+// indices, if either the batch size or k are less than 1, if t is not less
+// than n, or if the Pedersen parameter is known to be insecure.
+func New(
+	ownIndex secp256k1.Fn, indices []secp256k1.Fn,
+	batchSize, k, t uint32,
+	h secp256k1.Point,
+) (Player, []Deal) {
+	return NewWithRand(random.Reader, ownIndex, indices, batchSize, k, t, h)
+}
+
+// NewWithRand is the same as New, except that the randomness for the deals is
+// read from the given source.
+//
+// Panics: This function will panic in the same cases as New, or if the source
+// of randomness returns an error.
+func NewWithRand(
+	r io.Reader,
+	ownIndex secp256k1.Fn, indices []secp256k1.Fn,
+	batchSize, k, t uint32,
+	h secp256k1.Point,
+) (Player, []Deal) {
+	n := uint32(len(indices))
+	if batchSize < 1 {
+		panic(fmt.Sprintf("batch size must be at least 1: got %v", batchSize))
+	}
+	if k < 1 {
+		panic(fmt.Sprintf("k must be at least 1: got %v", k))
+	}
+	if t >= n {
+		panic(fmt.Sprintf("t must be less than n = %v: got %v", n, t))
+	}
+	if !params.ValidPedersenParameter(h) {
+		panic("insecure choice of pedersen parameter")
+	}
+	ownPos := -1
+	for i := range indices {
+		if indices[i].Eq(&ownIndex) {
+			ownPos = i
+			break
+		}
+	}
+	if ownPos == -1 {
+		panic("own index is not in the list of indices")
+	}
+	indicesCopy := make([]secp256k1.Fn, n)
+	copy(indicesCopy, indices)
+
+	row := brng.NewWithRand(r, batchSize, k, indices, ownIndex, h)
+	deals := make([]Deal, n)
+	for j := range deals {
+		deals[j] = dealFor(row, uint32(j))
+	}
+
+	player := Player{
+		ownPos:           uint32(ownPos),
+		indices:          indicesCopy,
+		batchSize:        batchSize,
+		k:                k,
+		t:                t,
+		h:                h,
+		row:              row,
+		commitments:      make([][]shamir.Commitment, n),
+		shares:           make([]shamir.VerifiableShares, n),
+		valid:            make([]bool, n),
+		complaints:       make([][]uint32, n),
+		justified:        make([][]uint32, n),
+		badJustification: make([]bool, n),
+	}
+	_ = player.HandleDeal(uint32(ownPos), deals[ownPos])
+
+	return player, deals
+}
+
+// HandleDeal handles a deal from the dealer at the given position. Only the
+// first deal from each dealer is used. The commitments are kept as long as
+// they have the correct dimensions, even if the shares are not valid, so that
+// a justification from the dealer can later be checked. A nil error is
+// returned if the deal is valid, and otherwise the error indicates why it is
+// invalid; an invalid deal will result in a complaint against the dealer.
+func (player *Player) HandleDeal(from uint32, deal Deal) error {
+	if from >= uint32(len(player.indices)) || len(player.commitments[from]) != 0 {
+		return nil
+	}
+	if !player.validDimensions(deal.Commitments) {
+		return ErrInvalidDealDimensions
+	}
+	player.commitments[from] = deal.Commitments
+	if !player.validShares(from, player.ownPos, deal.Shares) {
+		return ErrInvalidDealShares
+	}
+	player.shares[from] = deal.Shares
+	player.valid[from] = true
+	return nil
+}
+
+// Complain returns the complaint that this player needs to broadcast, which
+// lists the dealers from which it has not received valid shares. It should be
+// called once the time for receiving deals has passed.
+func (player *Player) Complain() Complaint {
+	dealers := []uint32{}
+	for d, valid := range player.valid {
+		if !valid {
+			dealers = append(dealers, uint32(d))
+		}
+	}
+	player.HandleComplaint(player.ownPos, Complaint{Dealers: dealers})
+	return Complaint{Dealers: dealers}
+}
+
+// HandleComplaint handles a complaint from the player at the given position.
+func (player *Player) HandleComplaint(from uint32, complaint Complaint) {
+	n := uint32(len(player.indices))
+	if from >= n {
+		return
+	}
+	for _, d := range complaint.Dealers {
+		if d < n && !contains(player.complaints[d], from) {
+			player.complaints[d] = append(player.complaints[d], from)
+		}
+	}
+}
+
+// Justify returns the justification that this player, as a dealer, needs to
+// broadcast in response to the complaints against it. It should be called
+// once the time for receiving complaints has passed.
+func (player *Player) Justify() Justification {
+	complaints := player.complaints[player.ownPos]
+	justification := Justification{
+		Complainants: make([]uint32, len(complaints)),
+		Shares:       make([]shamir.VerifiableShares, len(complaints)),
+	}
+	for i, c := range complaints {
+		justification.Complainants[i] = c
+		justification.Shares[i] = dealFor(player.row, c).Shares
+	}
+	player.HandleJustification(player.ownPos, justification)
+	return justification
+}
+
+// HandleJustification handles a justification from the dealer at the given
+// position. Each revealed share is checked against the commitments of the
+// dealer, and if any of them are invalid the dealer is disqualified. If one
+// of the revealed shares is for this player, it replaces the share that was
+// received in the deal.
+func (player *Player) HandleJustification(from uint32, justification Justification) {
+	if from >= uint32(len(player.indices)) || len(player.justified[from]) != 0 || player.badJustification[from] {
+		return
+	}
+	if len(justification.Complainants) != len(justification.Shares) {
+		player.badJustification[from] = true
+		return
+	}
+	for i, c := range justification.Complainants {
+		if !contains(player.complaints[from], c) || contains(player.justified[from], c) {
+			continue
+		}
+		if !player.validShares(from, c, justification.Shares[i]) {
+			player.badJustification[from] = true
+			return
+		}
+		player.justified[from] = append(player.justified[from], c)
+		if c == player.ownPos {
+			player.shares[from] = justification.Shares[i]
+			player.valid[from] = true
+		}
+	}
+}
+
+// Qualified returns the outcome made up of the qualified dealers and the
+// hashes of their commitments. A dealer is qualified if its commitments have
+// been received, there are at most t complaints against it, and it has
+// revealed valid shares for every complaint. It should be called once the
+// time for receiving justifications has passed, and the result proposed to
+// the consensus protocol.
+func (player *Player) Qualified() Outcome {
+	outcome := Outcome{Dealers: []uint32{}, Hashes: [][32]byte{}}
+	for d := range player.indices {
+		if len(player.commitments[d]) == 0 || player.badJustification[d] {
+			continue
+		}
+		if uint32(len(player.complaints[d])) > player.t {
+			continue
+		}
+		justified := true
+		for _, c := range player.complaints[d] {
+			if !contains(player.justified[d], c) {
+				justified = false
+				break
+			}
+		}
+		if justified {
+			outcome.Dealers = append(outcome.Dealers, uint32(d))
+			outcome.Hashes = append(outcome.Hashes, commitmentsHash(player.commitments[d]))
+		}
+	}
+	return outcome
+}
+
+// HandleOutcome computes the output shares and commitments for the BRNG
+// algorithm upon receiving the outcome that was decided by the consensus
+// protocol. The output commitments are the same for every player. If the
+// shares of this player from the qualified dealers are not valid, the output
+// shares will be nil and the corresponding error will be returned, as for
+// brng.IsValid. If the qualified set has fewer than t+1 dealers, or the
+// commitments of one of the dealers are missing or do not have the hash in
+// the outcome, both outputs will be nil. In the latter two cases, the player
+// can obtain the commitments from another player (see HandleCommitments) and
+// then try again.
+func (player *Player) HandleOutcome(outcome Outcome) (shamir.VerifiableShares, []shamir.Commitment, error) {
+	qualified := outcome.Dealers
+	if uint32(len(qualified)) < player.t+1 {
+		return nil, nil, ErrNotEnoughQualified
+	}
+	if len(outcome.Hashes) != len(qualified) {
+		return nil, nil, ErrInconsistentCommitments
+	}
+	sharesBatch := make([]shamir.VerifiableShares, player.batchSize)
+	commitmentsBatch := make([][]shamir.Commitment, player.batchSize)
+	for i := range sharesBatch {
+		sharesBatch[i] = make(shamir.VerifiableShares, len(qualified))
+		commitmentsBatch[i] = make([]shamir.Commitment, len(qualified))
+	}
+	for j, d := range qualified {
+		if d >= uint32(len(player.indices)) || len(player.commitments[d]) == 0 {
+			return nil, nil, ErrMissingCommitments
+		}
+		if commitmentsHash(player.commitments[d]) != outcome.Hashes[j] {
+			return nil, nil, ErrInconsistentCommitments
+		}
+		for i := range sharesBatch {
+			commitmentsBatch[i][j] = player.commitments[d][i]
+			if player.valid[d] {
+				sharesBatch[i][j] = player.shares[d][i]
+			}
+		}
+	}
+	err := brng.IsValidBatched(
+		player.batchSize, player.indices[player.ownPos], player.h,
+		sharesBatch, commitmentsBatch, len(qualified),
+	)
+	if err != nil {
+		sharesBatch = nil
+	}
+	shares, commitments := brng.HandleConsensusOutput(sharesBatch, commitmentsBatch)
+	return shares, commitments, err
+}
+
+// Commitments returns the commitments that this player received from the
+// dealer at the given position, or nil if it has not received them.
+func (player *Player) Commitments(dealer uint32) []shamir.Commitment {
+	if dealer >= uint32(len(player.indices)) {
+		return nil
+	}
+	return player.commitments[dealer]
+}
+
+// HandleCommitments replaces the commitments of the given qualified dealer
+// with the given commitments, which can be obtained from another player (see
+// Commitments), if they have the hash for the dealer in the given outcome.
+// The shares that this player received from the dealer are only used if they
+// are valid with respect to the new commitments. A nil error is returned if
+// the commitments were replaced, and otherwise ErrInconsistentCommitments is
+// returned.
+func (player *Player) HandleCommitments(outcome Outcome, dealer uint32, commitments []shamir.Commitment) error {
+	if len(outcome.Hashes) != len(outcome.Dealers) {
+		return ErrInconsistentCommitments
+	}
+	for j, d := range outcome.Dealers {
+		if d != dealer || d >= uint32(len(player.indices)) {
+			continue
+		}
+		if !player.validDimensions(commitments) || commitmentsHash(commitments) != outcome.Hashes[j] {
+			return ErrInconsistentCommitments
+		}
+		player.commitments[d] = commitments
+		player.valid[d] = player.shares[d] != nil && player.validShares(d, player.ownPos, player.shares[d])
+		return nil
+	}
+	return ErrInconsistentCommitments
+}
+
+// validDimensions returns true if there is one commitment for each sharing in
+// the batch, and each commitment has threshold k.
+func (player *Player) validDimensions(commitments []shamir.Commitment) bool {
+	if uint32(len(commitments)) != player.batchSize {
+		return false
+	}
+	for _, commitment := range commitments {
+		if uint32(commitment.Len()) != player.k {
+			return false
+		}
+	}
+	return true
+}
+
+// validShares returns true if the given shares are valid shares for the player
+// at the given position with respect to the commitments of the given dealer.
+func (player *Player) validShares(dealer, pos uint32, shares shamir.VerifiableShares) bool {
+	if uint32(len(shares)) != player.batchSize {
+		return false
+	}
+	for i := range shares {
+		if !shares[i].Share.IndexEq(&player.indices[pos]) ||
+			!shamir.IsValid(player.h, &player.commitments[dealer][i], &shares[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func dealFor(row []brng.Sharing, pos uint32) Deal {
+	deal := Deal{
+		Commitments: make([]shamir.Commitment, len(row)),
+		Shares:      make(shamir.VerifiableShares, len(row)),
+	}
+	for i, sharing := range row {
+		deal.Commitments[i] = sharing.Commitment
+		deal.Shares[i] = sharing.Shares[pos]
+	}
+	return deal
+}
+
+func commitmentsHash(commitments []shamir.Commitment) [32]byte {
+	buf, err := surge.ToBinary(commitments)
+	if err != nil {
+		panic(fmt.Sprintf("marshaling commitments: %v", err))
+	}
+	return sha256.Sum256(buf)
+}
+
+func contains(xs []uint32, x uint32) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
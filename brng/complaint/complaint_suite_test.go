@@ -0,0 +1,13 @@
+package complaint_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestComplaint(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Complaint Suite")
+}
@@ -0,0 +1,378 @@
+package complaint_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/brng/complaint"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/brng/complaint/complaintutil"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+)
+
+var _ = Describe("Complaint based BRNG", func() {
+	n := 7
+	t := 2
+	k := t + 1
+	b := 2
+
+	var indices []secp256k1.Fn
+	var h secp256k1.Point
+
+	BeforeEach(func() {
+		indices = shamirutil.RandomIndices(n)
+		h = secp256k1.RandomPoint()
+	})
+
+	// Setup creates the players and delivers all of the deals. The deal from
+	// dealer d to player p will have an invalid share if corrupt[d][p] is true.
+	Setup := func(corrupt map[int]map[int]bool) ([]Player, [][]Deal) {
+		players := make([]Player, n)
+		deals := make([][]Deal, n)
+		for i := range players {
+			players[i], deals[i] = New(indices[i], indices, uint32(b), uint32(k), uint32(t), h)
+		}
+		for i := range players {
+			for j := range players {
+				if i == j {
+					continue
+				}
+				deal := deals[i][j]
+				if corrupt[i][j] {
+					deal.Shares = append(shamir.VerifiableShares{}, deal.Shares...)
+					deal.Shares[0].Decommitment = secp256k1.RandomFn()
+					Expect(players[j].HandleDeal(uint32(i), deal)).To(Equal(ErrInvalidDealShares))
+				} else {
+					Expect(players[j].HandleDeal(uint32(i), deal)).To(Succeed())
+				}
+			}
+		}
+		return players, deals
+	}
+
+	// Run carries out the complaint and justification steps, where the
+	// dealers in the given set do not justify.
+	Run := func(players []Player, noJustify map[int]bool) {
+		complaints := make([]Complaint, n)
+		for i := range players {
+			complaints[i] = players[i].Complain()
+		}
+		for i := range players {
+			for j := range players {
+				if i != j {
+					players[j].HandleComplaint(uint32(i), complaints[i])
+				}
+			}
+		}
+		for i := range players {
+			justification := players[i].Justify()
+			if noJustify[i] {
+				continue
+			}
+			for j := range players {
+				if i != j {
+					players[j].HandleJustification(uint32(i), justification)
+				}
+			}
+		}
+	}
+
+	CheckOutputs := func(players []Player, outcome Outcome) {
+		outputShares := make([]shamir.VerifiableShares, n)
+		var outputCommitments []shamir.Commitment
+		for i := range players {
+			shares, commitments, err := players[i].HandleOutcome(outcome)
+			Expect(err).ToNot(HaveOccurred())
+			if outputCommitments == nil {
+				outputCommitments = commitments
+			}
+			for j := range commitments {
+				Expect(commitments[j].Eq(outputCommitments[j])).To(BeTrue())
+			}
+			outputShares[i] = shares
+		}
+		for j := 0; j < b; j++ {
+			shares := make(shamir.VerifiableShares, n)
+			for i := range shares {
+				shares[i] = outputShares[i][j]
+				Expect(shamir.IsValid(h, &outputCommitments[j], &shares[i])).To(BeTrue())
+			}
+			Expect(shamirutil.VsharesAreConsistent(shares, k)).To(BeTrue())
+		}
+	}
+
+	Context("honest dealers", func() {
+		Specify("there should be no complaints and all dealers should be qualified", func() {
+			players, _ := Setup(nil)
+			for i := range players {
+				Expect(players[i].Complain().Dealers).To(BeEmpty())
+			}
+			Run(players, nil)
+			for i := range players {
+				Expect(players[i].Qualified().Dealers).To(HaveLen(n))
+			}
+			CheckOutputs(players, players[0].Qualified())
+		})
+	})
+
+	Context("seeded randomness", func() {
+		Specify("deals created from the same seed should be the same", func() {
+			dealsWithSeed := func(seed int64) []Deal {
+				_, deals := NewWithRand(rand.New(rand.NewSource(seed)), indices[0], indices, uint32(b), uint32(k), uint32(t), h)
+				return deals
+			}
+			seed := rand.Int63()
+			deals := dealsWithSeed(seed)
+			Expect(dealsWithSeed(seed)).To(Equal(deals))
+			Expect(dealsWithSeed(seed + 1)).ToNot(Equal(deals))
+		})
+	})
+
+	Context("invalid deals", func() {
+		Specify("deals with invalid shares or dimensions should return an error", func() {
+			_, deals := Setup(nil)
+			player, _ := New(indices[0], indices, uint32(b), uint32(k), uint32(t), h)
+
+			deal := deals[1][0]
+			deal.Commitments = deal.Commitments[1:]
+			Expect(player.HandleDeal(1, deal)).To(Equal(ErrInvalidDealDimensions))
+
+			deal = deals[2][0]
+			deal.Shares = append(shamir.VerifiableShares{}, deal.Shares...)
+			deal.Shares[0].Share.Value = secp256k1.RandomFn()
+			Expect(player.HandleDeal(2, deal)).To(Equal(ErrInvalidDealShares))
+
+			deal = deals[3][1]
+			Expect(player.HandleDeal(3, deal)).To(Equal(ErrInvalidDealShares))
+		})
+
+		Specify("a dealer that justifies its complaints should be qualified", func() {
+			dealer := rand.Intn(n)
+			complainant := (dealer + 1) % n
+			players, _ := Setup(map[int]map[int]bool{dealer: {complainant: true}})
+
+			Expect(players[complainant].Complain().Dealers).To(Equal([]uint32{uint32(dealer)}))
+			Run(players, nil)
+			for i := range players {
+				Expect(players[i].Qualified().Dealers).To(HaveLen(n))
+			}
+			CheckOutputs(players, players[0].Qualified())
+		})
+
+		Specify("a dealer that does not justify its complaints should not be qualified", func() {
+			dealer := rand.Intn(n)
+			complainant := (dealer + 1) % n
+			players, _ := Setup(map[int]map[int]bool{dealer: {complainant: true}})
+
+			// The dealer still handles its own justification, so only the
+			// views of the other players are checked.
+			Run(players, map[int]bool{dealer: true})
+			for i := range players {
+				if i == dealer {
+					continue
+				}
+				qualified := players[i].Qualified().Dealers
+				Expect(qualified).To(HaveLen(n - 1))
+				Expect(qualified).ToNot(ContainElement(uint32(dealer)))
+			}
+			CheckOutputs(players, players[complainant].Qualified())
+		})
+
+		Specify("a dealer with more than t complaints should not be qualified", func() {
+			dealer := rand.Intn(n)
+			corrupt := map[int]map[int]bool{dealer: {}}
+			for c := 1; c <= t+1; c++ {
+				corrupt[dealer][(dealer+c)%n] = true
+			}
+			players, _ := Setup(corrupt)
+
+			Run(players, nil)
+			for i := range players {
+				Expect(players[i].Qualified().Dealers).ToNot(ContainElement(uint32(dealer)))
+			}
+		})
+	})
+
+	Context("outcomes", func() {
+		Specify("a qualified set with fewer than t+1 dealers should return an error", func() {
+			players, _ := Setup(nil)
+			Run(players, nil)
+			outcome := players[0].Qualified()
+			outcome.Dealers, outcome.Hashes = outcome.Dealers[:t], outcome.Hashes[:t]
+			shares, commitments, err := players[0].HandleOutcome(outcome)
+			Expect(err).To(Equal(ErrNotEnoughQualified))
+			Expect(shares).To(BeNil())
+			Expect(commitments).To(BeNil())
+		})
+
+		Specify("an outcome with hashes that do not match the commitments should return an error", func() {
+			players, _ := Setup(nil)
+			Run(players, nil)
+			outcome := players[0].Qualified()
+			outcome.Hashes = append([][32]byte{}, outcome.Hashes...)
+			outcome.Hashes[rand.Intn(n)][0] ^= 1
+			shares, commitments, err := players[0].HandleOutcome(outcome)
+			Expect(err).To(Equal(ErrInconsistentCommitments))
+			Expect(shares).To(BeNil())
+			Expect(commitments).To(BeNil())
+
+			outcome.Hashes = outcome.Hashes[1:]
+			_, _, err = players[0].HandleOutcome(outcome)
+			Expect(err).To(Equal(ErrInconsistentCommitments))
+		})
+
+		Specify("players should use the agreed commitments of a dealer that sends inconsistent commitments", func() {
+			dealer := rand.Intn(n)
+			victim := (dealer + 1) % n
+			players := make([]Player, n)
+			deals := make([][]Deal, n)
+			for i := range players {
+				players[i], deals[i] = New(indices[i], indices, uint32(b), uint32(k), uint32(t), h)
+			}
+
+			// The dealer sends the victim valid shares for a different row,
+			// along with the commitments for that row.
+			row := brng.New(uint32(b), uint32(k), indices, indices[dealer], h)
+			deals[dealer][victim] = Deal{
+				Commitments: make([]shamir.Commitment, b),
+				Shares:      make(shamir.VerifiableShares, b),
+			}
+			for j := range row {
+				deals[dealer][victim].Commitments[j] = row[j].Commitment
+				deals[dealer][victim].Shares[j] = row[j].Shares[victim]
+			}
+			for i := range players {
+				for j := range players {
+					if i != j {
+						Expect(players[j].HandleDeal(uint32(i), deals[i][j])).To(Succeed())
+					}
+				}
+			}
+			Run(players, nil)
+
+			outcome := players[dealer].Qualified()
+			Expect(outcome.Dealers).To(HaveLen(n))
+			Expect(players[victim].Qualified()).ToNot(Equal(outcome))
+
+			// The victim can not use the commitments that it received.
+			shares, commitments, err := players[victim].HandleOutcome(outcome)
+			Expect(err).To(Equal(ErrInconsistentCommitments))
+			Expect(shares).To(BeNil())
+			Expect(commitments).To(BeNil())
+
+			// Only the agreed commitments can replace them.
+			Expect(players[victim].HandleCommitments(outcome, uint32(dealer), players[victim].Commitments(uint32(dealer)))).To(Equal(ErrInconsistentCommitments))
+			Expect(players[victim].HandleCommitments(outcome, uint32(dealer), players[dealer].Commitments(uint32(dealer)))).To(Succeed())
+
+			// The shares of the victim are not valid for the agreed
+			// commitments, but it computes the same output commitments as
+			// the other players, whose outputs are valid.
+			_, victimCommitments, err := players[victim].HandleOutcome(outcome)
+			Expect(err).To(HaveOccurred())
+			var outputCommitments []shamir.Commitment
+			outputShares := make(shamir.VerifiableShares, 0, n-1)
+			for i := range players {
+				if i == victim {
+					continue
+				}
+				shares, commitments, err := players[i].HandleOutcome(outcome)
+				Expect(err).ToNot(HaveOccurred())
+				outputCommitments = commitments
+				outputShares = append(outputShares, shares[0])
+			}
+			for j := range outputCommitments {
+				Expect(victimCommitments[j].Eq(outputCommitments[j])).To(BeTrue())
+			}
+			Expect(shamirutil.VsharesAreConsistent(outputShares, k)).To(BeTrue())
+		})
+	})
+
+	Context("network", func() {
+		Specify("honest players should agree on the qualified set and have valid outputs", func() {
+			n := 10
+			t := 3
+			k := t + 1
+			indices := shamirutil.RandomIndices(n)
+			ids := make([]mpcutil.ID, n)
+			for i := range ids {
+				ids[i] = mpcutil.ID(i + 1)
+			}
+			shuffleMsgs, isOffline := mpcutil.MessageShufflerDropper(ids, 1)
+
+			// Choose two of the online players to be dishonest.
+			behaviours := make([]complaintutil.Behaviour, n)
+			dishonest := []complaintutil.Behaviour{complaintutil.BadShares, complaintutil.BadDealer}
+			for i := 0; len(dishonest) > 0; i++ {
+				if !isOffline[ids[i]] {
+					behaviours[i] = dishonest[0]
+					dishonest = dishonest[1:]
+				}
+			}
+
+			machines := make([]mpcutil.Machine, n)
+			for i := range machines {
+				machine := complaintutil.NewMachine(ids[i], ids, indices, b, k, t, h, behaviours[i])
+				machines[i] = &machine
+			}
+
+			network := mpcutil.NewNetwork(machines, shuffleMsgs)
+			network.SetCaptureHist(true)
+			Expect(network.Run()).To(Succeed())
+
+			var outcome *Outcome
+			var commitments []shamir.Commitment
+			outputShares := make([]shamir.VerifiableShares, 0, n)
+			for i := range machines {
+				if isOffline[ids[i]] || behaviours[i] != complaintutil.Honest {
+					continue
+				}
+				machine := machines[i].(*complaintutil.Machine)
+				if outcome == nil {
+					outcome = &machine.Outcome
+					commitments = machine.OutputCommitments
+				}
+				Expect(machine.Outcome).To(Equal(*outcome))
+				for j := range commitments {
+					Expect(machine.OutputCommitments[j].Eq(commitments[j])).To(BeTrue())
+				}
+				outputShares = append(outputShares, machine.OutputShares)
+			}
+
+			qualified := outcome.Dealers
+			for i := range machines {
+				switch {
+				case isOffline[ids[i]] || behaviours[i] == complaintutil.BadDealer:
+					Expect(qualified).ToNot(ContainElement(uint32(i)))
+				default:
+					Expect(qualified).To(ContainElement(uint32(i)))
+				}
+			}
+
+			for j := 0; j < b; j++ {
+				shares := make(shamir.VerifiableShares, len(outputShares))
+				for i := range shares {
+					shares[i] = outputShares[i][j]
+					Expect(shamir.IsValid(h, &commitments[j], &shares[i])).To(BeTrue())
+				}
+				Expect(shamirutil.VsharesAreConsistent(shares, k)).To(BeTrue())
+			}
+		})
+	})
+
+	Context("panics", func() {
+		Specify("invalid parameters", func() {
+			Expect(func() { New(indices[0], indices, 0, uint32(k), uint32(t), h) }).To(Panic())
+			Expect(func() { New(indices[0], indices, uint32(b), 0, uint32(t), h) }).To(Panic())
+			Expect(func() { New(indices[0], indices, uint32(b), uint32(k), uint32(n), h) }).To(Panic())
+			Expect(func() { New(secp256k1.RandomFn(), indices, uint32(b), uint32(k), uint32(t), h) }).To(Panic())
+			Expect(func() {
+				New(indices[0], indices, uint32(b), uint32(k), uint32(t), secp256k1.NewPointInfinity())
+			}).To(Panic())
+		})
+	})
+})
@@ -0,0 +1,278 @@
+package complaintutil
+
+import (
+	"io"
+
+	"github.com/renproject/mpc/brng/complaint"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+// Behaviour represents the way in which a player acts in the network.
+type Behaviour uint8
+
+const (
+	// Honest represents a player that follows the protocol as specified.
+	Honest = Behaviour(iota)
+
+	// BadShares represents a dealer that sends invalid shares to some of the
+	// players, but then reveals the correct shares when they complain.
+	BadShares
+
+	// BadDealer represents a dealer that sends invalid shares to some of the
+	// players, and then does not respond to their complaints.
+	BadDealer
+)
+
+const (
+	complainTick = 2
+	justifyTick  = 4
+	outputTick   = 6
+)
+
+// Machine represents a player in the complaint based BRNG flow. The steps of
+// the protocol are driven by tick messages that the player sends to itself
+// each round; since all of the messages sent in a round are delivered in the
+// next round, in an arbitrary order, a step is taken every second round so
+// that all of the messages from the previous step have been handled. Instead
+// of running a consensus protocol on the outcome, each player uses the
+// outcome that it computes locally.
+type Machine struct {
+	OwnID     mpcutil.ID
+	IDs       []mpcutil.ID
+	Behaviour Behaviour
+	Ticks     uint32
+	Player    complaint.Player
+	InitMsgs  []Message
+
+	Outcome           complaint.Outcome
+	OutputShares      shamir.VerifiableShares
+	OutputCommitments []shamir.Commitment
+}
+
+// NewMachine constructs a new machine for a network test. The ID of the player
+// with the ith index is the ith ID. A dishonest machine will send invalid
+// shares to t of the other players, so that as long as fewer than t players
+// are offline, at least one honest player will complain.
+func NewMachine(
+	ownID mpcutil.ID, ids []mpcutil.ID,
+	indices []secp256k1.Fn,
+	b, k, t int,
+	h secp256k1.Point,
+	behaviour Behaviour,
+) Machine {
+	return NewMachineWithRand(random.Reader, ownID, ids, indices, b, k, t, h, behaviour)
+}
+
+// NewMachineWithRand is the same as NewMachine, except that the randomness for
+// the deals, including the invalid shares of a dishonest machine, is read from
+// the given source.
+func NewMachineWithRand(
+	r io.Reader,
+	ownID mpcutil.ID, ids []mpcutil.ID,
+	indices []secp256k1.Fn,
+	b, k, t int,
+	h secp256k1.Point,
+	behaviour Behaviour,
+) Machine {
+	var ownPos int
+	for ownPos = 0; ids[ownPos] != ownID; ownPos++ {
+	}
+	player, deals := complaint.NewWithRand(r, indices[ownPos], indices, uint32(b), uint32(k), uint32(t), h)
+
+	m := Machine{
+		OwnID:     ownID,
+		IDs:       ids,
+		Behaviour: behaviour,
+		Player:    player,
+	}
+
+	numBad := 0
+	if behaviour != Honest {
+		numBad = t
+	}
+	for j, id := range ids {
+		if id == ownID {
+			continue
+		}
+		deal := deals[j]
+		if numBad > 0 {
+			deal.Shares = append(shamir.VerifiableShares{}, deal.Shares...)
+			deal.Shares[0].Share.Value = random.Fn(r)
+			numBad--
+		}
+		m.InitMsgs = append(m.InitMsgs, Message{FromID: ownID, ToID: id, Type: TypeDeal, Deal: deal})
+	}
+	m.InitMsgs = append(m.InitMsgs, m.tick())
+	return m
+}
+
+// ID implements the Machine interface.
+func (m Machine) ID() mpcutil.ID { return m.OwnID }
+
+// InitialMessages implements the Machine interface.
+func (m Machine) InitialMessages() []mpcutil.Message {
+	msgs := make([]mpcutil.Message, len(m.InitMsgs))
+	for i := range m.InitMsgs {
+		msgs[i] = &m.InitMsgs[i]
+	}
+	return msgs
+}
+
+// Handle implements the Machine interface.
+func (m *Machine) Handle(msg mpcutil.Message) []mpcutil.Message {
+	message := msg.(*Message)
+	from := m.pos(message.FromID)
+
+	switch message.Type {
+	case TypeDeal:
+		_ = m.Player.HandleDeal(from, message.Deal)
+
+	case TypeComplaint:
+		m.Player.HandleComplaint(from, message.Complaint)
+
+	case TypeJustification:
+		m.Player.HandleJustification(from, message.Justification)
+
+	case TypeTick:
+		return m.handleTick()
+	}
+	return nil
+}
+
+func (m *Machine) handleTick() []mpcutil.Message {
+	m.Ticks++
+	var msgs []mpcutil.Message
+	switch m.Ticks {
+	case complainTick:
+		msgs = m.broadcast(Message{Type: TypeComplaint, Complaint: m.Player.Complain()})
+
+	case justifyTick:
+		justification := m.Player.Justify()
+		if m.Behaviour != BadDealer {
+			msgs = m.broadcast(Message{Type: TypeJustification, Justification: justification})
+		}
+
+	case outputTick:
+		m.Outcome = m.Player.Qualified()
+		m.OutputShares, m.OutputCommitments, _ = m.Player.HandleOutcome(m.Outcome)
+		return nil
+	}
+	tick := m.tick()
+	return append(msgs, &tick)
+}
+
+func (m Machine) tick() Message {
+	return Message{FromID: m.OwnID, ToID: m.OwnID, Type: TypeTick}
+}
+
+func (m Machine) pos(id mpcutil.ID) uint32 {
+	var pos uint32
+	for pos = 0; m.IDs[pos] != id; pos++ {
+	}
+	return pos
+}
+
+func (m Machine) broadcast(msg Message) []mpcutil.Message {
+	msgs := make([]mpcutil.Message, 0, len(m.IDs)-1)
+	for _, id := range m.IDs {
+		if id == m.OwnID {
+			continue
+		}
+		msg := msg
+		msg.FromID = m.OwnID
+		msg.ToID = id
+		msgs = append(msgs, &msg)
+	}
+	return msgs
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (m Machine) SizeHint() int {
+	return m.OwnID.SizeHint() +
+		surge.SizeHint(m.IDs) +
+		surge.SizeHint(uint8(m.Behaviour)) +
+		surge.SizeHint(m.Ticks) +
+		m.Player.SizeHint() +
+		surge.SizeHint(m.InitMsgs) +
+		m.Outcome.SizeHint() +
+		m.OutputShares.SizeHint() +
+		surge.SizeHint(m.OutputCommitments)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (m Machine) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.OwnID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.IDs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU8(uint8(m.Behaviour), buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(m.Ticks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.Player.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.InitMsgs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.Outcome.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.OutputShares.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(m.OutputCommitments, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (m *Machine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.OwnID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.IDs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU8((*uint8)(&m.Behaviour), buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&m.Ticks, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.Player.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.InitMsgs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.Outcome.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.OutputShares.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&m.OutputCommitments, buf, rem)
+}
@@ -0,0 +1,102 @@
+package complaintutil
+
+import (
+	"github.com/renproject/mpc/brng/complaint"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/surge"
+)
+
+// MessageType represents the type of a message.
+type MessageType uint8
+
+const (
+	// TypeTick represents a message that a player sends to itself to keep
+	// track of the progress of the network rounds.
+	TypeTick = MessageType(iota)
+
+	// TypeDeal represents a message containing a deal.
+	TypeDeal
+
+	// TypeComplaint represents a message containing a complaint.
+	TypeComplaint
+
+	// TypeJustification represents a message containing a justification.
+	TypeJustification
+)
+
+// Message is the message type that players send to eachother during an
+// instance of the complaint based BRNG flow. Only the field corresponding to
+// the type of the message is used.
+type Message struct {
+	FromID, ToID  mpcutil.ID
+	Type          MessageType
+	Deal          complaint.Deal
+	Complaint     complaint.Complaint
+	Justification complaint.Justification
+}
+
+// From implements the mpcutil.Message interface.
+func (msg Message) From() mpcutil.ID { return msg.FromID }
+
+// To implements the mpcutil.Message interface.
+func (msg Message) To() mpcutil.ID { return msg.ToID }
+
+// SizeHint implements the surge.SizeHinter interface.
+func (msg Message) SizeHint() int {
+	return msg.FromID.SizeHint() +
+		msg.ToID.SizeHint() +
+		surge.SizeHint(uint8(msg.Type)) +
+		msg.Deal.SizeHint() +
+		msg.Complaint.SizeHint() +
+		msg.Justification.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (msg Message) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.FromID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.ToID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU8(uint8(msg.Type), buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.Deal.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.Complaint.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return msg.Justification.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (msg *Message) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.FromID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.ToID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU8((*uint8)(&msg.Type), buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.Deal.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.Complaint.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return msg.Justification.Unmarshal(buf, rem)
+}
@@ -0,0 +1,28 @@
+package complaint
+
+import "errors"
+
+var (
+	// ErrInvalidDealDimensions is returned when a deal does not contain the
+	// expected number of commitments or shares, or when the commitments do
+	// not have the expected threshold.
+	ErrInvalidDealDimensions = errors.New("invalid deal dimensions")
+
+	// ErrInvalidDealShares is returned when the shares in a deal are not
+	// valid with respect to the commitments in the deal.
+	ErrInvalidDealShares = errors.New("invalid deal shares")
+
+	// ErrNotEnoughQualified is returned when the qualified set of dealers has
+	// fewer than t+1 members, and so might not contain any honest dealers.
+	ErrNotEnoughQualified = errors.New("not enough qualified dealers")
+
+	// ErrMissingCommitments is returned when the commitments for one of the
+	// qualified dealers have not been received.
+	ErrMissingCommitments = errors.New("missing commitments")
+
+	// ErrInconsistentCommitments is returned when the commitments that were
+	// received from one of the qualified dealers do not have the hash given
+	// in the outcome, or when an outcome does not have one hash for each
+	// dealer.
+	ErrInconsistentCommitments = errors.New("inconsistent commitments")
+)
@@ -0,0 +1,324 @@
+package complaint
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+	"github.com/renproject/surge"
+)
+
+// SizeHint implements the surge.SizeHinter interface.
+func (player Player) SizeHint() int {
+	return surge.SizeHint(player.ownPos) +
+		surge.SizeHint(player.indices) +
+		surge.SizeHint(player.batchSize) +
+		surge.SizeHint(player.k) +
+		surge.SizeHint(player.t) +
+		player.h.SizeHint() +
+		surge.SizeHint(player.row) +
+		surge.SizeHint(player.commitments) +
+		surge.SizeHint(player.shares) +
+		surge.SizeHint(player.valid) +
+		surge.SizeHint(player.complaints) +
+		surge.SizeHint(player.justified) +
+		surge.SizeHint(player.badJustification)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (player Player) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(player.ownPos, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(player.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(player.batchSize, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(player.k, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(player.t, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = player.h.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(player.row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(player.commitments, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(player.shares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(player.valid, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(player.complaints, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(player.justified, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(player.badJustification, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (player *Player) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&player.ownPos, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&player.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&player.batchSize, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&player.k, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&player.t, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = player.h.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&player.row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&player.commitments, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&player.shares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&player.valid, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&player.complaints, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&player.justified, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&player.badJustification, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (player Player) Generate(rand *rand.Rand, size int) reflect.Value {
+	n := rand.Intn(4) + 1
+	b := uint32(rand.Intn(2) + 1)
+	k := uint32(rand.Intn(3) + 1)
+	indices := shamirutil.RandomIndices(n)
+	h := secp256k1.RandomPoint()
+	row := make([]brng.Sharing, b)
+	for i := range row {
+		row[i] = brng.Sharing{
+			Shares:     randomShares(rand, n),
+			Commitment: shamir.Commitment{}.Generate(rand, int(k)).Interface().(shamir.Commitment),
+		}
+	}
+	commitments := make([][]shamir.Commitment, n)
+	shares := make([]shamir.VerifiableShares, n)
+	valid := make([]bool, n)
+	complaints := make([][]uint32, n)
+	justified := make([][]uint32, n)
+	badJustification := make([]bool, n)
+	for i := 0; i < n; i++ {
+		commitments[i] = make([]shamir.Commitment, b)
+		for j := range commitments[i] {
+			commitments[i][j] = shamir.Commitment{}.Generate(rand, int(k)).Interface().(shamir.Commitment)
+		}
+		shares[i] = randomShares(rand, int(b))
+		valid[i] = rand.Int()&1 == 1
+		complaints[i] = randomPositions(rand, n)
+		justified[i] = randomPositions(rand, n)
+		badJustification[i] = rand.Int()&1 == 1
+	}
+	return reflect.ValueOf(Player{
+		ownPos:           uint32(rand.Intn(n)),
+		indices:          indices,
+		batchSize:        b,
+		k:                k,
+		t:                uint32(rand.Intn(n)),
+		h:                h,
+		row:              row,
+		commitments:      commitments,
+		shares:           shares,
+		valid:            valid,
+		complaints:       complaints,
+		justified:        justified,
+		badJustification: badJustification,
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (deal Deal) SizeHint() int {
+	return surge.SizeHint(deal.Commitments) + deal.Shares.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (deal Deal) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Marshal(deal.Commitments, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return deal.Shares.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (deal *Deal) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Unmarshal(&deal.Commitments, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return deal.Shares.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (deal Deal) Generate(rand *rand.Rand, size int) reflect.Value {
+	b := rand.Intn(3)
+	commitments := make([]shamir.Commitment, b)
+	for i := range commitments {
+		commitments[i] = shamir.Commitment{}.Generate(rand, 3).Interface().(shamir.Commitment)
+	}
+	return reflect.ValueOf(Deal{
+		Commitments: commitments,
+		Shares:      randomShares(rand, b),
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (complaint Complaint) SizeHint() int {
+	return surge.SizeHint(complaint.Dealers)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (complaint Complaint) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	return surge.Marshal(complaint.Dealers, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (complaint *Complaint) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	return surge.Unmarshal(&complaint.Dealers, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (complaint Complaint) Generate(rand *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Complaint{Dealers: randomPositions(rand, size)})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (justification Justification) SizeHint() int {
+	return surge.SizeHint(justification.Complainants) + surge.SizeHint(justification.Shares)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (justification Justification) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Marshal(justification.Complainants, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(justification.Shares, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (justification *Justification) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Unmarshal(&justification.Complainants, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&justification.Shares, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (justification Justification) Generate(rand *rand.Rand, size int) reflect.Value {
+	complainants := randomPositions(rand, 4)
+	shares := make([]shamir.VerifiableShares, len(complainants))
+	for i := range shares {
+		shares[i] = randomShares(rand, rand.Intn(3))
+	}
+	return reflect.ValueOf(Justification{
+		Complainants: complainants,
+		Shares:       shares,
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (outcome Outcome) SizeHint() int {
+	return surge.SizeHint(outcome.Dealers) + surge.SizeHint(outcome.Hashes)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (outcome Outcome) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Marshal(outcome.Dealers, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(outcome.Hashes, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (outcome *Outcome) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Unmarshal(&outcome.Dealers, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&outcome.Hashes, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (outcome Outcome) Generate(rand *rand.Rand, size int) reflect.Value {
+	dealers := randomPositions(rand, size)
+	hashes := make([][32]byte, len(dealers))
+	for i := range hashes {
+		rand.Read(hashes[i][:])
+	}
+	return reflect.ValueOf(Outcome{Dealers: dealers, Hashes: hashes})
+}
+
+func randomPositions(rand *rand.Rand, n int) []uint32 {
+	positions := make([]uint32, rand.Intn(n+1))
+	for i := range positions {
+		positions[i] = uint32(rand.Intn(n + 1))
+	}
+	return positions
+}
+
+func randomShares(rand *rand.Rand, n int) shamir.VerifiableShares {
+	shares := make(shamir.VerifiableShares, n)
+	for i := range shares {
+		shares[i] = shamir.VerifiableShare{}.Generate(rand, 0).Interface().(shamir.VerifiableShare)
+	}
+	return shares
+}
@@ -0,0 +1,37 @@
+package complaint
+
+import "github.com/renproject/shamir"
+
+// A Deal is sent privately by a dealer to each of the other players. It
+// contains the commitments for each of the sharings in the batch, and the
+// shares for the recipient. An honest dealer sends the same commitments to
+// every player, but since the deals are not broadcast a dishonest dealer can
+// send different commitments to different players; this is resolved by
+// including a hash of the commitments of each dealer in the Outcome.
+type Deal struct {
+	Commitments []shamir.Commitment
+	Shares      shamir.VerifiableShares
+}
+
+// A Complaint is broadcast by a player, and contains the positions of the
+// dealers from which the player did not receive valid shares.
+type Complaint struct {
+	Dealers []uint32
+}
+
+// A Justification is broadcast by a dealer in response to the complaints
+// against it. For each complaining player, it contains the batch of shares
+// that the dealer created for that player, which are thereby made public.
+type Justification struct {
+	Complainants []uint32
+	Shares       []shamir.VerifiableShares
+}
+
+// An Outcome is the value that the players agree on using the consensus
+// protocol. It contains the positions of the qualified dealers, in increasing
+// order, and for each of them the hash of the commitments that were received
+// from it by the player that computed the outcome.
+type Outcome struct {
+	Dealers []uint32
+	Hashes  [][32]byte
+}
@@ -0,0 +1,63 @@
+package brng
+
+import (
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// Consensus represents an instance of a consensus protocol that is used by the
+// players in the BRNG algorithm to agree on a table of sharings. Each player
+// proposes its own row of sharings (see New), and once the protocol has
+// completed every honest player learns the same table, which consists of the
+// rows from some subset of the players. A player should only consider a table
+// to be acceptable if its own shares in the table are valid (see IsValid and
+// TableSharesAndCommitments), and the protocol should only decide on a table
+// that enough honest players find acceptable.
+//
+// How the messages for the protocol are sent between the players is left to
+// the implementation.
+type Consensus interface {
+	// Propose submits the row of sharings for this player.
+	Propose(row []Sharing)
+
+	// Done returns true if the protocol has decided on a table, and false
+	// otherwise.
+	Done() bool
+
+	// Table returns the table that was decided on by the protocol, where
+	// each element of the table is the row from one of the players. The
+	// return value will be nil if the protocol has not yet decided.
+	Table() [][]Sharing
+}
+
+// TableSharesAndCommitments returns the shares for the player with the given
+// index and the commitments contained in the given table, in the form that is
+// used by IsValid and HandleConsensusOutput. If a row does not contain a share
+// for the given index, the corresponding share will be the zero value, which
+// IsValid will consider to have an incorrect index.
+func TableSharesAndCommitments(table [][]Sharing, index secp256k1.Fn) (
+	[]shamir.VerifiableShares, [][]shamir.Commitment,
+) {
+	if len(table) == 0 {
+		return nil, nil
+	}
+	b := len(table[0])
+	sharesBatch := make([]shamir.VerifiableShares, b)
+	commitmentsBatch := make([][]shamir.Commitment, b)
+	for i := range sharesBatch {
+		sharesBatch[i] = make(shamir.VerifiableShares, len(table))
+		commitmentsBatch[i] = make([]shamir.Commitment, len(table))
+	}
+	for j, row := range table {
+		for i := 0; i < b && i < len(row); i++ {
+			commitmentsBatch[i][j] = row[i].Commitment
+			for _, share := range row[i].Shares {
+				if share.Share.IndexEq(&index) {
+					sharesBatch[i][j] = share
+					break
+				}
+			}
+		}
+	}
+	return sharesBatch, commitmentsBatch
+}
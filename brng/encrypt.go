@@ -0,0 +1,245 @@
+package brng
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// An EncryptedShare is a verifiable share that has been encrypted to the
+// static public key of the player that it is for, using ECIES over secp256k1.
+// The index of the share is left in the clear so that players can find their
+// shares in a table.
+type EncryptedShare struct {
+	Index      secp256k1.Fn
+	Ephemeral  secp256k1.Point
+	Ciphertext []byte
+}
+
+// An EncryptedSharing is the same as a Sharing, except that each of the shares
+// is encrypted to its recipient. The commitment is left in the clear, and so
+// a row of encrypted sharings can be made public, for example by including it
+// in a block of an external consensus protocol.
+type EncryptedSharing struct {
+	Shares     []EncryptedShare
+	Commitment shamir.Commitment
+}
+
+// NewEncrypted is the same as New, except that each share in the created
+// sharings is encrypted to the public key of the player that it is for. The
+// public key for the player with the ith index is the ith public key.
+//
+// Panics: This function will panic in the same cases as New, or if the number
+// of public keys is not equal to the number of indices.
+func NewEncrypted(
+	batchSize, k uint32,
+	indices []secp256k1.Fn, pubKeys []secp256k1.Point,
+	index secp256k1.Fn, h secp256k1.Point,
+) []EncryptedSharing {
+	return NewEncryptedWithRand(random.Reader, batchSize, k, indices, pubKeys, index, h)
+}
+
+// NewEncryptedWithRand is the same as NewEncrypted, except that the randomness
+// for the sharings and the encryptions is read from the given source.
+//
+// Panics: This function will panic in the same cases as NewEncrypted, or if
+// the source of randomness returns an error.
+func NewEncryptedWithRand(
+	r io.Reader,
+	batchSize, k uint32,
+	indices []secp256k1.Fn, pubKeys []secp256k1.Point,
+	index secp256k1.Fn, h secp256k1.Point,
+) []EncryptedSharing {
+	if len(pubKeys) != len(indices) {
+		panic(fmt.Sprintf(
+			"number of public keys must be equal to the number of indices: got %v, expected %v",
+			len(pubKeys), len(indices),
+		))
+	}
+	return EncryptRowWithRand(r, NewWithRand(r, batchSize, k, indices, index, h), pubKeys)
+}
+
+// EncryptRow encrypts each share in the given row of sharings. The ith share
+// of each sharing is encrypted to the ith public key.
+//
+// Panics: This function will panic if the number of shares in one of the
+// sharings is not equal to the number of public keys.
+func EncryptRow(row []Sharing, pubKeys []secp256k1.Point) []EncryptedSharing {
+	return EncryptRowWithRand(random.Reader, row, pubKeys)
+}
+
+// EncryptRowWithRand is the same as EncryptRow, except that the ephemeral keys
+// for the encryptions are read from the given source.
+//
+// Panics: This function will panic in the same cases as EncryptRow, or if the
+// source of randomness returns an error.
+func EncryptRowWithRand(r io.Reader, row []Sharing, pubKeys []secp256k1.Point) []EncryptedSharing {
+	encRow := make([]EncryptedSharing, len(row))
+	for i, sharing := range row {
+		if len(sharing.Shares) != len(pubKeys) {
+			panic(fmt.Sprintf(
+				"number of shares must be equal to the number of public keys: got %v, expected %v",
+				len(sharing.Shares), len(pubKeys),
+			))
+		}
+		encRow[i].Shares = make([]EncryptedShare, len(sharing.Shares))
+		for j := range sharing.Shares {
+			encRow[i].Shares[j] = EncryptShareWithRand(r, sharing.Shares[j], pubKeys[j])
+		}
+		encRow[i].Commitment.Set(sharing.Commitment)
+	}
+	return encRow
+}
+
+// EncryptShare encrypts the given share to the given public key. A fresh
+// ephemeral key is used for each encryption, and the share value and
+// decommitment are encrypted using AES-GCM with a key derived from the
+// Diffie-Hellman shared secret. The index of the share is authenticated but
+// not encrypted.
+func EncryptShare(share shamir.VerifiableShare, pubKey secp256k1.Point) EncryptedShare {
+	return EncryptShareWithRand(random.Reader, share, pubKey)
+}
+
+// EncryptShareWithRand is the same as EncryptShare, except that the ephemeral
+// key is read from the given source.
+//
+// Panics: This function will panic if the source of randomness returns an
+// error.
+func EncryptShareWithRand(r io.Reader, share shamir.VerifiableShare, pubKey secp256k1.Point) EncryptedShare {
+	ephemeralKey := random.Fn(r)
+	var ephemeral, shared secp256k1.Point
+	ephemeral.BaseExp(&ephemeralKey)
+	shared.Scale(&pubKey, &ephemeralKey)
+
+	plaintext := make([]byte, 64)
+	share.Share.Value.PutB32(plaintext[:32])
+	share.Decommitment.PutB32(plaintext[32:])
+
+	aead := newAEAD(&shared, &ephemeral)
+	return EncryptedShare{
+		Index:      share.Share.Index,
+		Ephemeral:  ephemeral,
+		Ciphertext: aead.Seal(nil, make([]byte, aead.NonceSize()), plaintext, indexBytes(share.Share.Index)),
+	}
+}
+
+// DecryptShare decrypts the given encrypted share using the given private key.
+// An error is returned if the share was not encrypted to the corresponding
+// public key, or if the ciphertext has been modified.
+func DecryptShare(encShare EncryptedShare, privKey secp256k1.Fn) (shamir.VerifiableShare, error) {
+	var shared secp256k1.Point
+	shared.Scale(&encShare.Ephemeral, &privKey)
+
+	aead := newAEAD(&shared, &encShare.Ephemeral)
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), encShare.Ciphertext, indexBytes(encShare.Index))
+	if err != nil || len(plaintext) != 64 {
+		return shamir.VerifiableShare{}, ErrDecryptionFailed
+	}
+
+	var share shamir.VerifiableShare
+	share.Share.Index = encShare.Index
+	share.Share.Value.SetB32(plaintext[:32])
+	share.Decommitment.SetB32(plaintext[32:])
+	return share, nil
+}
+
+// DecryptTable is the same as TableSharesAndCommitments for a table of
+// encrypted sharings; the shares for the player with the given index are
+// decrypted using the given private key. An error is returned if any of these
+// shares are missing or can not be decrypted, in which case the returned
+// shares will be nil.
+func DecryptTable(table [][]EncryptedSharing, index, privKey secp256k1.Fn) (
+	[]shamir.VerifiableShares, [][]shamir.Commitment, error,
+) {
+	if len(table) == 0 {
+		return nil, nil, nil
+	}
+	b := len(table[0])
+	sharesBatch := make([]shamir.VerifiableShares, b)
+	commitmentsBatch := make([][]shamir.Commitment, b)
+	for i := range sharesBatch {
+		sharesBatch[i] = make(shamir.VerifiableShares, len(table))
+		commitmentsBatch[i] = make([]shamir.Commitment, len(table))
+	}
+	var err error
+	for j, row := range table {
+		for i := 0; i < b && i < len(row); i++ {
+			commitmentsBatch[i][j] = row[i].Commitment
+			if err != nil {
+				continue
+			}
+			found := false
+			for _, encShare := range row[i].Shares {
+				if encShare.Index.Eq(&index) {
+					sharesBatch[i][j], err = DecryptShare(encShare, privKey)
+					found = true
+					break
+				}
+			}
+			if !found {
+				err = ErrIncorrectIndex
+			}
+		}
+	}
+	if err != nil {
+		return nil, commitmentsBatch, err
+	}
+	return sharesBatch, commitmentsBatch, nil
+}
+
+// HandleEncryptedConsensusOutput is the same as HandleConsensusOutput for a
+// table of encrypted sharings that was output by the consensus protocol. The
+// shares for this player are decrypted using the given private key and checked
+// for validity (see IsValid) against the commitments in the table; if this
+// fails, the output shares will be nil. The returned error indicates why the
+// shares were not valid.
+//
+// Panics: This function will panic if the table is empty.
+func HandleEncryptedConsensusOutput(
+	table [][]EncryptedSharing,
+	index, privKey secp256k1.Fn,
+	h secp256k1.Point,
+) (
+	shamir.VerifiableShares, []shamir.Commitment, error,
+) {
+	if len(table) == 0 {
+		panic("table must not be empty")
+	}
+	sharesBatch, commitmentsBatch, err := DecryptTable(table, index, privKey)
+	if err == nil {
+		err = IsValid(uint32(len(table[0])), index, h, sharesBatch, commitmentsBatch, len(table))
+	}
+	if err != nil {
+		sharesBatch = nil
+	}
+	shares, commitments := HandleConsensusOutput(sharesBatch, commitmentsBatch)
+	return shares, commitments, err
+}
+
+func newAEAD(shared, ephemeral *secp256k1.Point) cipher.AEAD {
+	buf := make([]byte, 2*secp256k1.PointSizeMarshalled)
+	shared.PutBytes(buf[:secp256k1.PointSizeMarshalled])
+	ephemeral.PutBytes(buf[secp256k1.PointSizeMarshalled:])
+	key := sha256.Sum256(buf)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(fmt.Sprintf("creating cipher: %v", err))
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(fmt.Sprintf("creating aead: %v", err))
+	}
+	return aead
+}
+
+func indexBytes(index secp256k1.Fn) []byte {
+	buf := make([]byte, 32)
+	index.PutB32(buf)
+	return buf
+}
@@ -14,8 +14,8 @@ var (
 	// ErrInvalidCommitmentDimensions is returned when the batch of commitments
 	// has inconsistent dimensions. This can occur when not all slices in the
 	// batch have the same length (this length is equal to the number of
-	// contributions for the batch), or when not all commitments have the same
-	// threshold.
+	// contributions for the batch), when not all commitments have the same
+	// threshold, or when the commitments are empty.
 	ErrInvalidCommitmentDimensions = errors.New("invalid commitment dimensions")
 
 	// ErrInvalidShareDimensions is returned when the batch of shares has
@@ -35,4 +35,9 @@ var (
 	// ErrNotEnoughContributions is returned when the number of contributions
 	// from other players is smaller than the number of required contributions.
 	ErrNotEnoughContributions = errors.New("not enough contributions")
+
+	// ErrDecryptionFailed is returned when an encrypted share can not be
+	// decrypted, which happens when it was not encrypted to the public key
+	// that corresponds to the given private key, or when it has been modified.
+	ErrDecryptionFailed = errors.New("decryption failed")
 )
@@ -0,0 +1,89 @@
+package brng
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+// SizeHint implements the surge.SizeHinter interface.
+func (encShare EncryptedShare) SizeHint() int {
+	return encShare.Index.SizeHint() +
+		encShare.Ephemeral.SizeHint() +
+		surge.SizeHint(encShare.Ciphertext)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (encShare EncryptedShare) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := encShare.Index.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = encShare.Ephemeral.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(encShare.Ciphertext, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (encShare *EncryptedShare) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := encShare.Index.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = encShare.Ephemeral.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&encShare.Ciphertext, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (encShare EncryptedShare) Generate(rand *rand.Rand, size int) reflect.Value {
+	ciphertext := make([]byte, rand.Intn(size+1))
+	rand.Read(ciphertext)
+	return reflect.ValueOf(EncryptedShare{
+		Index:      secp256k1.RandomFn(),
+		Ephemeral:  secp256k1.RandomPoint(),
+		Ciphertext: ciphertext,
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (sharing EncryptedSharing) SizeHint() int {
+	return surge.SizeHint(sharing.Shares) + sharing.Commitment.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (sharing EncryptedSharing) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Marshal(sharing.Shares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return sharing.Commitment.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (sharing *EncryptedSharing) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Unmarshal(&sharing.Shares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return sharing.Commitment.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (sharing EncryptedSharing) Generate(rand *rand.Rand, size int) reflect.Value {
+	shares := make([]EncryptedShare, rand.Intn(3))
+	for i := range shares {
+		shares[i] = EncryptedShare{}.Generate(rand, size).Interface().(EncryptedShare)
+	}
+	return reflect.ValueOf(EncryptedSharing{
+		Shares:     shares,
+		Commitment: shamir.Commitment{}.Generate(rand, 3).Interface().(shamir.Commitment),
+	})
+}
@@ -95,6 +95,18 @@ func (pc PullConsensus) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 // represents the maximum number of adversaries that there will be. `h`
 // represents the Pedersen commitment parameter.
 func NewPullConsensus(inds, honestIndices []secp256k1.Fn, advCount int, h secp256k1.Point) PullConsensus {
+	return NewPullConsensusWithRand(rand.New(rand.NewSource(rand.Int63())), inds, honestIndices, advCount, h)
+}
+
+// NewPullConsensusWithRand is the same as NewPullConsensus, except that the
+// random subset of honest players is chosen using the given source of
+// randomness.
+func NewPullConsensusWithRand(
+	r *rand.Rand,
+	inds, honestIndices []secp256k1.Fn,
+	advCount int,
+	h secp256k1.Point,
+) PullConsensus {
 	var table [][]brng.Sharing
 
 	done := false
@@ -106,7 +118,7 @@ func NewPullConsensus(inds, honestIndices []secp256k1.Fn, advCount int, h secp25
 	// consensus.
 	honestSubset := make([]secp256k1.Fn, len(honestIndices))
 	copy(honestSubset, honestIndices)
-	rand.Shuffle(len(honestSubset), func(i, j int) {
+	r.Shuffle(len(honestSubset), func(i, j int) {
 		honestSubset[i], honestSubset[j] = honestSubset[j], honestSubset[i]
 	})
 	honestSubset = honestSubset[:advCount+1]
@@ -164,3 +176,9 @@ func (pc *PullConsensus) HandleRow(row []brng.Sharing) bool {
 
 	return pc.done
 }
+
+// Propose implements the brng.Consensus interface. It is equivalent to
+// HandleRow.
+func (pc *PullConsensus) Propose(row []brng.Sharing) {
+	pc.HandleRow(row)
+}
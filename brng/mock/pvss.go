@@ -0,0 +1,171 @@
+package mock
+
+import (
+	"fmt"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/surge"
+
+	"github.com/renproject/mpc/brng/pvss"
+)
+
+// PVSSConsensus represents an ideal trusted party for achieving consensus on a
+// table of encrypted sharings when BRNG is run in the PVSS mode. Unlike
+// PullConsensus, it does not need to know which of the players are honest or
+// to have access to any plaintext shares, as each row is checked using
+// pvss.IsValidRow.
+type PVSSConsensus struct {
+	done      bool
+	indices   []secp256k1.Fn
+	pubKeys   []secp256k1.Point
+	batchSize uint32
+	k         uint32
+	threshold int32
+	table     [][]pvss.Sharing
+	h         secp256k1.Point
+}
+
+// NewPVSSConsensus constructs a new mock PVSS consensus object. The public key
+// for the player with the ith index is the ith public key. The batch size and
+// k are those used by the players to create their rows, and the adversary
+// count represents the maximum number of adversaries that there will be. `h`
+// represents the Pedersen commitment parameter.
+func NewPVSSConsensus(
+	inds []secp256k1.Fn, pubKeys []secp256k1.Point,
+	batchSize, k uint32, advCount int,
+	h secp256k1.Point,
+) PVSSConsensus {
+	indices := make([]secp256k1.Fn, len(inds))
+	copy(indices, inds)
+	keys := make([]secp256k1.Point, len(pubKeys))
+	copy(keys, pubKeys)
+
+	return PVSSConsensus{
+		done:      false,
+		indices:   indices,
+		pubKeys:   keys,
+		batchSize: batchSize,
+		k:         k,
+		threshold: int32(advCount) + 1,
+		table:     nil,
+		h:         h,
+	}
+}
+
+// Table returns the output table of the consensus algorithm. This table will
+// only be correct if `HandleRow` has returned `true`.
+func (pc PVSSConsensus) Table() [][]pvss.Sharing {
+	return pc.table
+}
+
+// Done returns if the consensus engine has already reached consensus or not
+// yet.
+func (pc PVSSConsensus) Done() bool {
+	return pc.done
+}
+
+// HandleRow processes a row received from a player. Rows that are not valid
+// are ignored. It returns true if consensus has completed, at which point the
+// complete output table can be accessed, and false otherwise.
+func (pc *PVSSConsensus) HandleRow(row []pvss.Sharing) bool {
+	if pc.done {
+		return true
+	}
+
+	if pvss.IsValidRow(row, pc.batchSize, pc.k, pc.indices, pc.pubKeys, pc.h) != nil {
+		return pc.done
+	}
+
+	pc.table = append(pc.table, row)
+	if len(pc.table) == int(pc.threshold) {
+		pc.done = true
+	}
+
+	return pc.done
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (pc PVSSConsensus) SizeHint() int {
+	return surge.SizeHint(pc.done) +
+		surge.SizeHint(pc.indices) +
+		surge.SizeHint(pc.pubKeys) +
+		surge.SizeHint(pc.batchSize) +
+		surge.SizeHint(pc.k) +
+		surge.SizeHint(pc.threshold) +
+		surge.SizeHint(pc.table) +
+		pc.h.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (pc PVSSConsensus) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalBool(pc.done, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling done: %v", err)
+	}
+	buf, rem, err = surge.Marshal(pc.indices, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling indices: %v", err)
+	}
+	buf, rem, err = surge.Marshal(pc.pubKeys, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling public keys: %v", err)
+	}
+	buf, rem, err = surge.MarshalU32(pc.batchSize, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling batch size: %v", err)
+	}
+	buf, rem, err = surge.MarshalU32(pc.k, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling k: %v", err)
+	}
+	buf, rem, err = surge.MarshalI32(pc.threshold, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling threshold: %v", err)
+	}
+	buf, rem, err = surge.Marshal(pc.table, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling table: %v", err)
+	}
+	buf, rem, err = pc.h.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error marshaling h: %v", err)
+	}
+	return buf, rem, nil
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (pc *PVSSConsensus) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalBool(&pc.done, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling done: %v", err)
+	}
+	buf, rem, err = surge.Unmarshal(&pc.indices, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling indices: %v", err)
+	}
+	buf, rem, err = surge.Unmarshal(&pc.pubKeys, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling public keys: %v", err)
+	}
+	buf, rem, err = surge.UnmarshalU32(&pc.batchSize, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling batch size: %v", err)
+	}
+	buf, rem, err = surge.UnmarshalU32(&pc.k, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling k: %v", err)
+	}
+	buf, rem, err = surge.UnmarshalI32(&pc.threshold, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling threshold: %v", err)
+	}
+	buf, rem, err = surge.Unmarshal(&pc.table, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling table: %v", err)
+	}
+	buf, rem, err = pc.h.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("error unmarshaling h: %v", err)
+	}
+	return buf, rem, nil
+}
@@ -0,0 +1,184 @@
+package pvss
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+)
+
+// A Complaint is made by a player that can not decrypt one of its encrypted
+// shares in a decided table. It identifies the encrypted share by the
+// position of the row in the table, the position of the sharing in the row
+// and the index of the player, and contains the decryption M = mG of one of
+// the chunks of the encrypted share along with a proof that the decryption is
+// correct. The chunk is given by its position in the concatenation of the
+// value and decommitment ciphertexts. The complaint is valid if the proof is
+// valid and m is not in the range of a chunk, which proves that the dealer of
+// the row created an encrypted share that can not be decrypted.
+type Complaint struct {
+	Row, Sharing uint32
+	Index        secp256k1.Fn
+	Chunk        uint32
+	M            secp256k1.Point
+	Proof        DecryptionProof
+}
+
+// NewComplaints returns a complaint for each row in the given table that
+// contains an encrypted share for the player with the given index that can not
+// be decrypted using the given private key. At most one complaint is made for
+// each row. If all of the shares for the player can be decrypted, the returned
+// slice will be empty.
+func NewComplaints(table [][]Sharing, index, privKey secp256k1.Fn) []Complaint {
+	return NewComplaintsWithRand(random.Reader, table, index, privKey)
+}
+
+// NewComplaintsWithRand is the same as NewComplaints, except that the
+// randomness for the proofs is read from the given source.
+//
+// Panics: This function will panic if the source of randomness returns an
+// error.
+func NewComplaintsWithRand(r io.Reader, table [][]Sharing, index, privKey secp256k1.Fn) []Complaint {
+	var pubKey secp256k1.Point
+	pubKey.BaseExp(&privKey)
+
+	complaints := []Complaint{}
+	for j, row := range table {
+	sharings:
+		for i := range row {
+			encShare := findShare(row[i].Shares, index)
+			if encShare == nil {
+				continue
+			}
+			for c := range encShare.Value {
+				if complaint, ok := complain(r, encShare, uint32(c), &pubKey, privKey); ok {
+					complaint.Row, complaint.Sharing = uint32(j), uint32(i)
+					complaints = append(complaints, complaint)
+					break sharings
+				}
+			}
+			for c := range encShare.Decommitment {
+				if complaint, ok := complain(r, encShare, uint32(len(encShare.Value)+c), &pubKey, privKey); ok {
+					complaint.Row, complaint.Sharing = uint32(j), uint32(i)
+					complaints = append(complaints, complaint)
+					break sharings
+				}
+			}
+		}
+	}
+	return complaints
+}
+
+// VerifyComplaint returns true if the given complaint against the given table
+// is valid for the given public key, which should be the public key of the
+// player with the index in the complaint, and false otherwise. This check can
+// be performed by anyone.
+func VerifyComplaint(table [][]Sharing, complaint *Complaint, pubKey secp256k1.Point) bool {
+	if complaint.Row >= uint32(len(table)) || complaint.Sharing >= uint32(len(table[complaint.Row])) {
+		return false
+	}
+	encShare := findShare(table[complaint.Row][complaint.Sharing].Shares, complaint.Index)
+	if encShare == nil {
+		return false
+	}
+	ct := chunk(encShare, complaint.Chunk)
+	if ct == nil {
+		return false
+	}
+	if _, ok := chunkDlog(complaint.M); ok {
+		return false
+	}
+	return verifyDecryptionProof(&pubKey, ct, &complaint.M, &complaint.Proof)
+}
+
+// Disqualify returns the given table without the rows that have a valid
+// complaint against them. The public key for the player with the ith index is
+// the ith public key, and complaints with an index that is not in the list of
+// indices are ignored. Since the complaints can be verified by anyone, all
+// players that see the same complaints will compute the same table, and all
+// of the encrypted shares in the returned table for the players that
+// complained can be decrypted. The required contributions is the smallest
+// number of rows that the table can have to be used (see brng.IsValid); if
+// fewer rows remain, the returned table will be nil and the error will be
+// ErrNotEnoughContributions.
+//
+// Panics: This function will panic if the given required contributions is less
+// than 1.
+func Disqualify(
+	table [][]Sharing,
+	complaints []Complaint,
+	indices []secp256k1.Fn, pubKeys []secp256k1.Point,
+	requiredContributions int,
+) ([][]Sharing, error) {
+	if requiredContributions < 1 {
+		panic(fmt.Sprintf("required contributions must be at least 1: got %v", requiredContributions))
+	}
+	disqualified := make([]bool, len(table))
+	for i := range complaints {
+		for j := range indices {
+			if indices[j].Eq(&complaints[i].Index) {
+				if j < len(pubKeys) && VerifyComplaint(table, &complaints[i], pubKeys[j]) {
+					disqualified[complaints[i].Row] = true
+				}
+				break
+			}
+		}
+	}
+	filtered := make([][]Sharing, 0, len(table))
+	for j, row := range table {
+		if !disqualified[j] {
+			filtered = append(filtered, row)
+		}
+	}
+	if len(filtered) < requiredContributions {
+		return nil, ErrNotEnoughContributions
+	}
+	return filtered, nil
+}
+
+// complain returns a complaint for the given chunk of the given encrypted
+// share, and true if the chunk can not be decrypted. The row and sharing of
+// the returned complaint are not set.
+func complain(
+	r io.Reader,
+	encShare *EncryptedShare,
+	c uint32,
+	pubKey *secp256k1.Point,
+	privKey secp256k1.Fn,
+) (Complaint, bool) {
+	ct := chunk(encShare, c)
+	m := decryptChunk(ct, &privKey)
+	if _, ok := chunkDlog(m); ok {
+		return Complaint{}, false
+	}
+	return Complaint{
+		Index: encShare.Index,
+		Chunk: c,
+		M:     m,
+		Proof: createDecryptionProof(r, pubKey, ct, &m, privKey),
+	}, true
+}
+
+// chunk returns the ciphertext at the given position in the concatenation of
+// the value and decommitment ciphertexts of the given encrypted share, or nil
+// if the position is out of range.
+func chunk(encShare *EncryptedShare, c uint32) *Ciphertext {
+	if c < uint32(len(encShare.Value)) {
+		return &encShare.Value[c]
+	}
+	c -= uint32(len(encShare.Value))
+	if c < uint32(len(encShare.Decommitment)) {
+		return &encShare.Decommitment[c]
+	}
+	return nil
+}
+
+func findShare(shares []EncryptedShare, index secp256k1.Fn) *EncryptedShare {
+	for i := range shares {
+		if shares[i].Index.Eq(&index) {
+			return &shares[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package pvss
+
+import "errors"
+
+var (
+	// ErrIncorrectBatchSize is returned when a row does not contain the
+	// expected number of sharings.
+	ErrIncorrectBatchSize = errors.New("incorrect batch size")
+
+	// ErrIncorrectThreshold is returned when the commitment for a sharing does
+	// not have the expected threshold.
+	ErrIncorrectThreshold = errors.New("incorrect threshold")
+
+	// ErrIncorrectNumShares is returned when a sharing does not contain one
+	// encrypted share for each player.
+	ErrIncorrectNumShares = errors.New("incorrect number of shares")
+
+	// ErrIncorrectIndex is returned when an encrypted share does not have the
+	// expected index, or when there is no share for a given index.
+	ErrIncorrectIndex = errors.New("incorrect index")
+
+	// ErrInvalidProof is returned when the proof for an encrypted share is not
+	// valid.
+	ErrInvalidProof = errors.New("invalid proof")
+
+	// ErrDecryptionFailed is returned when an encrypted share can not be
+	// decrypted, which happens when it was not encrypted to the public key
+	// that corresponds to the given private key, or when one of the encrypted
+	// chunks is out of range.
+	ErrDecryptionFailed = errors.New("decryption failed")
+
+	// ErrNotEnoughContributions is returned when fewer rows than the number
+	// of required contributions remain after the rows with valid complaints
+	// against them are removed from a table.
+	ErrNotEnoughContributions = errors.New("not enough contributions")
+)
@@ -0,0 +1,344 @@
+package pvss
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+// SizeHint implements the surge.SizeHinter interface.
+func (ct Ciphertext) SizeHint() int { return ct.R.SizeHint() + ct.C.SizeHint() }
+
+// Marshal implements the surge.Marshaler interface.
+func (ct Ciphertext) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := ct.R.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return ct.C.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (ct *Ciphertext) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := ct.R.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return ct.C.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (ct Ciphertext) Generate(_ *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(Ciphertext{R: secp256k1.RandomPoint(), C: secp256k1.RandomPoint()})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (p Proof) SizeHint() int {
+	return p.t1.SizeHint() +
+		p.t2.SizeHint() +
+		p.t3.SizeHint() +
+		p.t4.SizeHint() +
+		p.t5.SizeHint() +
+		p.zs.SizeHint() +
+		p.zd.SizeHint() +
+		p.zr.SizeHint() +
+		p.zq.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (p Proof) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := p.t1.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t2.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t3.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t4.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t5.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.zs.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.zd.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.zr.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return p.zq.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (p *Proof) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := p.t1.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t2.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t3.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t4.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t5.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.zs.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.zd.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.zr.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return p.zq.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (p Proof) Generate(_ *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(Proof{
+		t1: secp256k1.RandomPoint(),
+		t2: secp256k1.RandomPoint(),
+		t3: secp256k1.RandomPoint(),
+		t4: secp256k1.RandomPoint(),
+		t5: secp256k1.RandomPoint(),
+		zs: secp256k1.RandomFn(),
+		zd: secp256k1.RandomFn(),
+		zr: secp256k1.RandomFn(),
+		zq: secp256k1.RandomFn(),
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (encShare EncryptedShare) SizeHint() int {
+	return encShare.Index.SizeHint() +
+		surge.SizeHint(encShare.Value) +
+		surge.SizeHint(encShare.Decommitment) +
+		encShare.Proof.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (encShare EncryptedShare) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := encShare.Index.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(encShare.Value, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(encShare.Decommitment, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return encShare.Proof.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (encShare *EncryptedShare) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := encShare.Index.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&encShare.Value, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&encShare.Decommitment, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return encShare.Proof.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (encShare EncryptedShare) Generate(rand *rand.Rand, size int) reflect.Value {
+	randomCiphertexts := func() []Ciphertext {
+		cts := make([]Ciphertext, rand.Intn(3))
+		for i := range cts {
+			cts[i] = Ciphertext{}.Generate(rand, size).Interface().(Ciphertext)
+		}
+		return cts
+	}
+	return reflect.ValueOf(EncryptedShare{
+		Index:        secp256k1.RandomFn(),
+		Value:        randomCiphertexts(),
+		Decommitment: randomCiphertexts(),
+		Proof:        Proof{}.Generate(rand, size).Interface().(Proof),
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (sharing Sharing) SizeHint() int {
+	return surge.SizeHint(sharing.Shares) + sharing.Commitment.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (sharing Sharing) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Marshal(sharing.Shares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return sharing.Commitment.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (sharing *Sharing) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Unmarshal(&sharing.Shares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return sharing.Commitment.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (sharing Sharing) Generate(rand *rand.Rand, size int) reflect.Value {
+	shares := make([]EncryptedShare, rand.Intn(3))
+	for i := range shares {
+		shares[i] = EncryptedShare{}.Generate(rand, size).Interface().(EncryptedShare)
+	}
+	return reflect.ValueOf(Sharing{
+		Shares:     shares,
+		Commitment: shamir.Commitment{}.Generate(rand, 3).Interface().(shamir.Commitment),
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (p DecryptionProof) SizeHint() int {
+	return p.t1.SizeHint() + p.t2.SizeHint() + p.z.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (p DecryptionProof) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := p.t1.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t2.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return p.z.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (p *DecryptionProof) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := p.t1.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = p.t2.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return p.z.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (p DecryptionProof) Generate(_ *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(DecryptionProof{
+		t1: secp256k1.RandomPoint(),
+		t2: secp256k1.RandomPoint(),
+		z:  secp256k1.RandomFn(),
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (complaint Complaint) SizeHint() int {
+	return surge.SizeHint(complaint.Row) +
+		surge.SizeHint(complaint.Sharing) +
+		complaint.Index.SizeHint() +
+		surge.SizeHint(complaint.Chunk) +
+		complaint.M.SizeHint() +
+		complaint.Proof.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (complaint Complaint) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(complaint.Row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(complaint.Sharing, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = complaint.Index.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(complaint.Chunk, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = complaint.M.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return complaint.Proof.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (complaint *Complaint) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&complaint.Row, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&complaint.Sharing, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = complaint.Index.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&complaint.Chunk, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = complaint.M.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return complaint.Proof.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (complaint Complaint) Generate(rand *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Complaint{
+		Row:     rand.Uint32(),
+		Sharing: rand.Uint32(),
+		Index:   secp256k1.RandomFn(),
+		Chunk:   rand.Uint32(),
+		M:       secp256k1.RandomPoint(),
+		Proof:   DecryptionProof{}.Generate(rand, size).Interface().(DecryptionProof),
+	})
+}
@@ -0,0 +1,196 @@
+package pvss
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/surge"
+)
+
+// A Proof attests that an encrypted share is consistent with a Pedersen
+// commitment. Let (A1, A2) and (B1, B2) be the recombined ciphertexts (see
+// combine) for the value and decommitment respectively, P be the public key of
+// the recipient and D be the commitment to the share, obtained by evaluating
+// the commitment for the sharing at the index of the share. The proof is a
+// non interactive (via the Fiat Shamir transform) Schnorr style proof of
+// knowledge of s, d, r and q such that
+//
+//	A1 = rG, A2 = sG + rP,
+//	B1 = qG, B2 = dG + qP, and
+//	D  = sG + dH.
+type Proof struct {
+	t1, t2, t3, t4, t5 secp256k1.Point
+	zs, zd, zr, zq     secp256k1.Fn
+}
+
+func createProof(
+	rand io.Reader,
+	h, pubKey, com *secp256k1.Point,
+	encShare *EncryptedShare,
+	s, d, r, q secp256k1.Fn,
+) Proof {
+	as, ad := random.Fn(rand), random.Fn(rand)
+	ar, aq := random.Fn(rand), random.Fn(rand)
+
+	var p Proof
+	var tmp secp256k1.Point
+	p.t1.BaseExp(&ar)
+	p.t2.BaseExp(&as)
+	tmp.Scale(pubKey, &ar)
+	p.t2.Add(&p.t2, &tmp)
+	p.t3.BaseExp(&aq)
+	p.t4.BaseExp(&ad)
+	tmp.Scale(pubKey, &aq)
+	p.t4.Add(&p.t4, &tmp)
+	p.t5.BaseExp(&as)
+	tmp.Scale(h, &ad)
+	p.t5.Add(&p.t5, &tmp)
+
+	e := computeChallenge(h, pubKey, com, encShare, &p)
+	response := func(a, x secp256k1.Fn) secp256k1.Fn {
+		var z secp256k1.Fn
+		z.Mul(&e, &x)
+		z.Add(&z, &a)
+		return z
+	}
+	p.zs = response(as, s)
+	p.zd = response(ad, d)
+	p.zr = response(ar, r)
+	p.zq = response(aq, q)
+	return p
+}
+
+func verifyProof(h, pubKey, com *secp256k1.Point, encShare *EncryptedShare) bool {
+	p := &encShare.Proof
+	e := computeChallenge(h, pubKey, com, encShare, p)
+	a1, a2 := combine(encShare.Value)
+	b1, b2 := combine(encShare.Decommitment)
+
+	// check returns true if xG + yQ = t + eA, where the yQ term is omitted if
+	// q is nil.
+	check := func(x, y *secp256k1.Fn, q, t, a *secp256k1.Point) bool {
+		var lhs, rhs, tmp secp256k1.Point
+		lhs.BaseExp(x)
+		if q != nil {
+			tmp.Scale(q, y)
+			lhs.Add(&lhs, &tmp)
+		}
+		rhs.Scale(a, &e)
+		rhs.Add(&rhs, t)
+		return lhs.Eq(&rhs)
+	}
+
+	return check(&p.zr, nil, nil, &p.t1, &a1) &&
+		check(&p.zs, &p.zr, pubKey, &p.t2, &a2) &&
+		check(&p.zq, nil, nil, &p.t3, &b1) &&
+		check(&p.zd, &p.zq, pubKey, &p.t4, &b2) &&
+		check(&p.zs, &p.zd, h, &p.t5, com)
+}
+
+func computeChallenge(
+	h, pubKey, com *secp256k1.Point,
+	encShare *EncryptedShare,
+	p *Proof,
+) secp256k1.Fn {
+	hasher := sha256.New()
+	write := func(v interface{}) {
+		buf, err := surge.ToBinary(v)
+		if err != nil {
+			panic("unreachable")
+		}
+		hasher.Write(buf)
+	}
+	write(h)
+	write(pubKey)
+	write(com)
+	write(encShare.Index)
+	write(encShare.Value)
+	write(encShare.Decommitment)
+	write(p.t1)
+	write(p.t2)
+	write(p.t3)
+	write(p.t4)
+	write(p.t5)
+	hash := hasher.Sum(nil)
+
+	var e secp256k1.Fn
+	_ = e.SetB32(hash)
+	return e
+}
+
+// A DecryptionProof attests that a point M is the decryption of a ciphertext
+// (R, C) for the public key P, without revealing the private key. It is a non
+// interactive Chaum Pedersen proof of knowledge of s such that
+//
+//	P = sG and C - M = sR.
+type DecryptionProof struct {
+	t1, t2 secp256k1.Point
+	z      secp256k1.Fn
+}
+
+func createDecryptionProof(
+	rand io.Reader,
+	pubKey *secp256k1.Point,
+	ct *Ciphertext,
+	m *secp256k1.Point,
+	privKey secp256k1.Fn,
+) DecryptionProof {
+	a := random.Fn(rand)
+
+	var p DecryptionProof
+	p.t1.BaseExp(&a)
+	p.t2.Scale(&ct.R, &a)
+
+	e := computeDecryptionChallenge(pubKey, ct, m, &p)
+	p.z.Mul(&e, &privKey)
+	p.z.Add(&p.z, &a)
+	return p
+}
+
+func verifyDecryptionProof(pubKey *secp256k1.Point, ct *Ciphertext, m *secp256k1.Point, p *DecryptionProof) bool {
+	e := computeDecryptionChallenge(pubKey, ct, m, p)
+
+	// The second equation is checked in the form zR + eM = t2 + eC, which
+	// avoids computing C - M.
+	var lhs, rhs, tmp secp256k1.Point
+	lhs.BaseExp(&p.z)
+	rhs.Scale(pubKey, &e)
+	rhs.Add(&rhs, &p.t1)
+	if !lhs.Eq(&rhs) {
+		return false
+	}
+	lhs.Scale(&ct.R, &p.z)
+	tmp.Scale(m, &e)
+	lhs.Add(&lhs, &tmp)
+	rhs.Scale(&ct.C, &e)
+	rhs.Add(&rhs, &p.t2)
+	return lhs.Eq(&rhs)
+}
+
+func computeDecryptionChallenge(
+	pubKey *secp256k1.Point,
+	ct *Ciphertext,
+	m *secp256k1.Point,
+	p *DecryptionProof,
+) secp256k1.Fn {
+	hasher := sha256.New()
+	write := func(v interface{}) {
+		buf, err := surge.ToBinary(v)
+		if err != nil {
+			panic("unreachable")
+		}
+		hasher.Write(buf)
+	}
+	write(pubKey)
+	write(ct)
+	write(m)
+	write(p.t1)
+	write(p.t2)
+	hash := hasher.Sum(nil)
+
+	var e secp256k1.Fn
+	_ = e.SetB32(hash)
+	return e
+}
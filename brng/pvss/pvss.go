@@ -0,0 +1,385 @@
+// Package pvss implements a publicly verifiable secret sharing mode for the
+// BRNG algorithm. In this mode, each share in a row is encrypted to the static
+// public key of the player that it is for, and is accompanied by a proof that
+// the encrypted share is consistent with the Pedersen commitment for the
+// sharing. Anyone can therefore check that a row is valid, without needing
+// access to any of the plaintext shares, and so rows can be validated by the
+// consensus protocol before they are agreed on.
+//
+// Shares are encrypted using ElGamal encryption "in the exponent". Since the
+// recipient needs to recover the share values as field elements, the value
+// and decommitment of a share are each split into 16 bit chunks, and each
+// chunk is encrypted separately; the recipient decrypts a chunk by computing a
+// small discrete logarithm. The proof attests that the chunks, when
+// recombined, encrypt a value and decommitment that are consistent with the
+// commitment. The proof does not show that each chunk is in range, and so a
+// malicious dealer can create an encrypted share that passes verification but
+// that can not be efficiently decrypted. In this case decryption will fail
+// with ErrDecryptionFailed; it can not however produce a share that is
+// inconsistent with the commitment. A player that can not decrypt one of its
+// shares in a decided table can instead publish a complaint (see
+// NewComplaints), which proves that the chunk is out of range without
+// revealing the private key of the player. Anyone can verify the complaint,
+// and the rows of the dealers with valid complaints against them are removed
+// from the table by all of the players (see Disqualify).
+package pvss
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+const (
+	// ChunkBits is the number of bits in each of the chunks that are
+	// encrypted separately.
+	ChunkBits = 16
+
+	// NumChunks is the number of chunks that a field element is split into.
+	NumChunks = 256 / ChunkBits
+
+	babyStepBits = 12
+)
+
+// A Ciphertext is an ElGamal encryption of a chunk m using the randomness r,
+// and is of the form (R, C) = (rG, mG + rP), where P is the public key of the
+// recipient.
+type Ciphertext struct {
+	R, C secp256k1.Point
+}
+
+// An EncryptedShare is a verifiable share that has been encrypted to the
+// public key of the player that it is for, along with a proof that the
+// encrypted share is consistent with the commitment for the sharing. The
+// index of the share is left in the clear.
+type EncryptedShare struct {
+	Index        secp256k1.Fn
+	Value        []Ciphertext
+	Decommitment []Ciphertext
+	Proof        Proof
+}
+
+// A Sharing is the same as a brng.Sharing, except that each share is an
+// encrypted share.
+type Sharing struct {
+	Shares     []EncryptedShare
+	Commitment shamir.Commitment
+}
+
+// New is the same as brng.New, except that each share in the created sharings
+// is encrypted to the public key of the player that it is for. The public key
+// for the player with the ith index is the ith public key.
+//
+// Panics: This function will panic in the same cases as brng.New, or if the
+// number of public keys is not equal to the number of indices.
+func New(
+	batchSize, k uint32,
+	indices []secp256k1.Fn, pubKeys []secp256k1.Point,
+	index secp256k1.Fn, h secp256k1.Point,
+) []Sharing {
+	return NewWithRand(random.Reader, batchSize, k, indices, pubKeys, index, h)
+}
+
+// NewWithRand is the same as New, except that the randomness for the
+// sharings, the encryptions and the proofs is read from the given source.
+//
+// Panics: This function will panic in the same cases as New, or if the source
+// of randomness returns an error.
+func NewWithRand(
+	r io.Reader,
+	batchSize, k uint32,
+	indices []secp256k1.Fn, pubKeys []secp256k1.Point,
+	index secp256k1.Fn, h secp256k1.Point,
+) []Sharing {
+	if len(pubKeys) != len(indices) {
+		panic(fmt.Sprintf(
+			"number of public keys must be equal to the number of indices: got %v, expected %v",
+			len(pubKeys), len(indices),
+		))
+	}
+	row := brng.NewWithRand(r, batchSize, k, indices, index, h)
+	sharings := make([]Sharing, len(row))
+	for i, sharing := range row {
+		sharings[i].Shares = make([]EncryptedShare, len(sharing.Shares))
+		for j := range sharing.Shares {
+			sharings[i].Shares[j] = EncryptShareWithRand(r, sharing.Shares[j], pubKeys[j], h)
+		}
+		sharings[i].Commitment = sharing.Commitment
+	}
+	return sharings
+}
+
+// EncryptShare encrypts the given share to the given public key, and creates
+// a proof that the encrypted share is consistent with the Pedersen commitment
+// to the share (for the Pedersen parameter h).
+func EncryptShare(share shamir.VerifiableShare, pubKey, h secp256k1.Point) EncryptedShare {
+	return EncryptShareWithRand(random.Reader, share, pubKey, h)
+}
+
+// EncryptShareWithRand is the same as EncryptShare, except that the randomness
+// for the encryption and the proof is read from the given source.
+//
+// Panics: This function will panic if the source of randomness returns an
+// error.
+func EncryptShareWithRand(r io.Reader, share shamir.VerifiableShare, pubKey, h secp256k1.Point) EncryptedShare {
+	value, valueRand := encrypt(r, share.Share.Value, &pubKey)
+	decom, decomRand := encrypt(r, share.Decommitment, &pubKey)
+	com := pedersenCommit(&share.Share.Value, &share.Decommitment, &h)
+
+	encShare := EncryptedShare{
+		Index:        share.Share.Index,
+		Value:        value,
+		Decommitment: decom,
+	}
+	encShare.Proof = createProof(
+		r, &h, &pubKey, &com, &encShare,
+		share.Share.Value, share.Decommitment, valueRand, decomRand,
+	)
+	return encShare
+}
+
+// VerifyShare returns true if the given encrypted share was correctly
+// encrypted to the given public key, and is consistent with the given
+// commitment, and false otherwise. This check can be performed by anyone.
+func VerifyShare(encShare *EncryptedShare, commitment shamir.Commitment, pubKey, h secp256k1.Point) bool {
+	if len(encShare.Value) != NumChunks || len(encShare.Decommitment) != NumChunks || commitment.Len() == 0 {
+		return false
+	}
+	com := polyEvalPoint(commitment, encShare.Index)
+	return verifyProof(&h, &pubKey, &com, encShare)
+}
+
+// IsValidRow checks the validity of the given row, as created by New, for the
+// given parameters. The ith share of each sharing must have the ith index and
+// be correctly encrypted to the ith public key. This check does not require
+// any of the private keys, and so can be performed by anyone. A nil error
+// is returned if the row is valid, and otherwise the error indicates how the
+// row is invalid.
+func IsValidRow(
+	row []Sharing,
+	batchSize, k uint32,
+	indices []secp256k1.Fn, pubKeys []secp256k1.Point,
+	h secp256k1.Point,
+) error {
+	if uint32(len(row)) != batchSize {
+		return ErrIncorrectBatchSize
+	}
+	for _, sharing := range row {
+		if uint32(sharing.Commitment.Len()) != k {
+			return ErrIncorrectThreshold
+		}
+		if len(sharing.Shares) != len(indices) || len(pubKeys) != len(indices) {
+			return ErrIncorrectNumShares
+		}
+		for j := range sharing.Shares {
+			if !sharing.Shares[j].Index.Eq(&indices[j]) {
+				return ErrIncorrectIndex
+			}
+			if !VerifyShare(&sharing.Shares[j], sharing.Commitment, pubKeys[j], h) {
+				return ErrInvalidProof
+			}
+		}
+	}
+	return nil
+}
+
+// DecryptShare decrypts the given encrypted share using the given private key.
+// If the encrypted share is valid (see VerifyShare), the decrypted share will
+// be valid with respect to the commitment. An error is returned if the share
+// can not be decrypted.
+func DecryptShare(encShare *EncryptedShare, privKey secp256k1.Fn) (shamir.VerifiableShare, error) {
+	value, err := decrypt(encShare.Value, &privKey)
+	if err != nil {
+		return shamir.VerifiableShare{}, err
+	}
+	decom, err := decrypt(encShare.Decommitment, &privKey)
+	if err != nil {
+		return shamir.VerifiableShare{}, err
+	}
+	return shamir.NewVerifiableShare(shamir.NewShare(encShare.Index, value), decom), nil
+}
+
+// DecryptTable returns the decrypted shares for the player with the given
+// index and the commitments contained in the given table, in the form that is
+// used by brng.HandleConsensusOutput. If the rows in the table are valid (see
+// IsValidRow), the decrypted shares will be valid. An error is returned if
+// any of the shares are missing or can not be decrypted, in which case the
+// returned shares will be nil.
+func DecryptTable(table [][]Sharing, index, privKey secp256k1.Fn) (
+	[]shamir.VerifiableShares, [][]shamir.Commitment, error,
+) {
+	if len(table) == 0 {
+		return nil, nil, nil
+	}
+	b := len(table[0])
+	sharesBatch := make([]shamir.VerifiableShares, b)
+	commitmentsBatch := make([][]shamir.Commitment, b)
+	for i := range sharesBatch {
+		sharesBatch[i] = make(shamir.VerifiableShares, len(table))
+		commitmentsBatch[i] = make([]shamir.Commitment, len(table))
+	}
+	var err error
+	for j, row := range table {
+		for i := 0; i < b && i < len(row); i++ {
+			commitmentsBatch[i][j] = row[i].Commitment
+			if err != nil {
+				continue
+			}
+			err = ErrIncorrectIndex
+			for l := range row[i].Shares {
+				if row[i].Shares[l].Index.Eq(&index) {
+					sharesBatch[i][j], err = DecryptShare(&row[i].Shares[l], privKey)
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, commitmentsBatch, err
+	}
+	return sharesBatch, commitmentsBatch, nil
+}
+
+// encrypt splits the given field element into chunks and encrypts each chunk
+// to the given public key. It also returns the combined randomness
+// sum_j 2^(16j) r_j, where r_j is the randomness for the jth chunk, which is
+// read from the given source.
+func encrypt(rand io.Reader, x secp256k1.Fn, pubKey *secp256k1.Point) ([]Ciphertext, secp256k1.Fn) {
+	var bs [32]byte
+	x.PutB32(bs[:])
+
+	cts := make([]Ciphertext, NumChunks)
+	var combinedRand secp256k1.Fn
+	shift := chunkShift()
+	for j := NumChunks - 1; j >= 0; j-- {
+		chunk := uint16(bs[31-2*j]) | uint16(bs[30-2*j])<<8
+		m := secp256k1.NewFnFromU16(chunk)
+		r := random.Fn(rand)
+
+		var mG, rP secp256k1.Point
+		mG.BaseExp(&m)
+		rP.Scale(pubKey, &r)
+		cts[j].R.BaseExp(&r)
+		cts[j].C.Add(&mG, &rP)
+
+		combinedRand.Mul(&combinedRand, &shift)
+		combinedRand.Add(&combinedRand, &r)
+	}
+	return cts, combinedRand
+}
+
+// decrypt decrypts and recombines the given chunks.
+func decrypt(cts []Ciphertext, privKey *secp256k1.Fn) (secp256k1.Fn, error) {
+	if len(cts) != NumChunks {
+		return secp256k1.Fn{}, ErrDecryptionFailed
+	}
+	var bs [32]byte
+	for j := range cts {
+		chunk, ok := chunkDlog(decryptChunk(&cts[j], privKey))
+		if !ok {
+			return secp256k1.Fn{}, ErrDecryptionFailed
+		}
+		bs[31-2*j] = byte(chunk)
+		bs[30-2*j] = byte(chunk >> 8)
+	}
+	var x secp256k1.Fn
+	x.SetB32(bs[:])
+	return x, nil
+}
+
+// decryptChunk returns mG for the chunk m that is encrypted in the given
+// ciphertext.
+func decryptChunk(ct *Ciphertext, privKey *secp256k1.Fn) secp256k1.Point {
+	var negPrivKey secp256k1.Fn
+	negPrivKey.Negate(privKey)
+	var mG, sR secp256k1.Point
+	sR.Scale(&ct.R, &negPrivKey)
+	mG.Add(&ct.C, &sR)
+	return mG
+}
+
+// combine computes sum_j 2^(16j) P_j for the R and C components of the given
+// ciphertexts.
+func combine(cts []Ciphertext) (secp256k1.Point, secp256k1.Point) {
+	shift := chunkShift()
+	r, c := cts[len(cts)-1].R, cts[len(cts)-1].C
+	for j := len(cts) - 2; j >= 0; j-- {
+		r.Scale(&r, &shift)
+		r.Add(&r, &cts[j].R)
+		c.Scale(&c, &shift)
+		c.Add(&c, &cts[j].C)
+	}
+	return r, c
+}
+
+func chunkShift() secp256k1.Fn {
+	var shift secp256k1.Fn
+	var bs [32]byte
+	bs[31-ChunkBits/8] = 1
+	shift.SetB32(bs[:])
+	return shift
+}
+
+var (
+	babySteps     map[[33]byte]uint16
+	giantStep     secp256k1.Point
+	babyStepsOnce sync.Once
+)
+
+// chunkDlog computes the discrete logarithm of the given point, if it is less
+// than 2^ChunkBits, using the baby step giant step algorithm.
+func chunkDlog(p secp256k1.Point) (uint16, bool) {
+	babyStepsOnce.Do(func() {
+		babySteps = make(map[[33]byte]uint16, 1<<babyStepBits)
+		var acc, g secp256k1.Point
+		one := secp256k1.NewFnFromU16(1)
+		g.BaseExp(&one)
+		acc = g
+		var key [33]byte
+		for i := 1; i < 1<<babyStepBits; i++ {
+			acc.PutBytes(key[:])
+			babySteps[key] = uint16(i)
+			acc.Add(&acc, &g)
+		}
+		// acc is now 2^babyStepBits G.
+		minusOne := secp256k1.NewFnFromU16(1)
+		minusOne.Negate(&minusOne)
+		giantStep.Scale(&acc, &minusOne)
+	})
+
+	var key [33]byte
+	for i := 0; i < 1<<(ChunkBits-babyStepBits); i++ {
+		if p.IsInfinity() {
+			return uint16(i << babyStepBits), true
+		}
+		p.PutBytes(key[:])
+		if m, ok := babySteps[key]; ok {
+			return uint16(i<<babyStepBits) + m, true
+		}
+		p.Add(&p, &giantStep)
+	}
+	return 0, false
+}
+
+func polyEvalPoint(commitment shamir.Commitment, index secp256k1.Fn) secp256k1.Point {
+	var acc secp256k1.Point
+	acc = commitment[len(commitment)-1]
+	for l := len(commitment) - 2; l >= 0; l-- {
+		acc.Scale(&acc, &index)
+		acc.Add(&acc, &commitment[l])
+	}
+	return acc
+}
+
+func pedersenCommit(value, decommitment *secp256k1.Fn, h *secp256k1.Point) secp256k1.Point {
+	var commitment, hPow secp256k1.Point
+	commitment.BaseExp(value)
+	hPow.Scale(h, decommitment)
+	commitment.Add(&commitment, &hPow)
+	return commitment
+}
@@ -0,0 +1,295 @@
+package pvss_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/brng/pvss"
+
+	"github.com/renproject/mpc/brng"
+	"github.com/renproject/mpc/brng/mock"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+)
+
+var _ = Describe("PVSS", func() {
+	n := 5
+	k := uint32(2)
+	b := uint32(2)
+
+	var indices []secp256k1.Fn
+	var privKeys []secp256k1.Fn
+	var pubKeys []secp256k1.Point
+	var h secp256k1.Point
+
+	BeforeEach(func() {
+		indices = shamirutil.RandomIndices(n)
+		privKeys = make([]secp256k1.Fn, n)
+		pubKeys = make([]secp256k1.Point, n)
+		for i := range privKeys {
+			privKeys[i] = secp256k1.RandomFn()
+			pubKeys[i].BaseExp(&privKeys[i])
+		}
+		h = secp256k1.RandomPoint()
+	})
+
+	RandomShare := func() (shamir.VerifiableShare, shamir.Commitment) {
+		shares := make(shamir.VerifiableShares, n)
+		commitment := shamir.NewCommitmentWithCapacity(int(k))
+		shamir.VShareSecret(&shares, &commitment, indices, h, secp256k1.RandomFn(), int(k))
+		return shares[0], commitment
+	}
+
+	Context("encrypting shares", func() {
+		Specify("rows created from the same seed should be the same", func() {
+			seed := rand.Int63()
+			row := NewWithRand(rand.New(rand.NewSource(seed)), b, k, indices, pubKeys, indices[0], h)
+			Expect(NewWithRand(rand.New(rand.NewSource(seed)), b, k, indices, pubKeys, indices[0], h)).To(Equal(row))
+			Expect(IsValidRow(row, b, k, indices, pubKeys, h)).To(Succeed())
+		})
+
+		Specify("valid encrypted shares should verify and decrypt correctly", func() {
+			share, commitment := RandomShare()
+			encShare := EncryptShare(share, pubKeys[0], h)
+			Expect(VerifyShare(&encShare, commitment, pubKeys[0], h)).To(BeTrue())
+
+			decShare, err := DecryptShare(&encShare, privKeys[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decShare.Eq(&share)).To(BeTrue())
+		})
+
+		Specify("shares with small values should decrypt correctly", func() {
+			share, _ := RandomShare()
+			share.Share.Value = secp256k1.NewFnFromU16(0)
+			share.Decommitment = secp256k1.NewFnFromU16(uint16(rand.Intn(1 << 16)))
+			encShare := EncryptShare(share, pubKeys[0], h)
+
+			decShare, err := DecryptShare(&encShare, privKeys[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decShare.Eq(&share)).To(BeTrue())
+		})
+
+		Specify("encrypted shares should not verify for a different commitment or public key", func() {
+			share, commitment := RandomShare()
+			_, otherCommitment := RandomShare()
+			encShare := EncryptShare(share, pubKeys[0], h)
+			Expect(VerifyShare(&encShare, otherCommitment, pubKeys[0], h)).To(BeFalse())
+			Expect(VerifyShare(&encShare, commitment, pubKeys[1], h)).To(BeFalse())
+		})
+
+		Specify("encrypted shares with modified ciphertexts or indices should not verify", func() {
+			share, commitment := RandomShare()
+			encShare := EncryptShare(share, pubKeys[0], h)
+
+			modified := encShare
+			modified.Value = append([]Ciphertext{}, encShare.Value...)
+			modified.Value[rand.Intn(NumChunks)].C = secp256k1.RandomPoint()
+			Expect(VerifyShare(&modified, commitment, pubKeys[0], h)).To(BeFalse())
+
+			modified = encShare
+			modified.Decommitment = append([]Ciphertext{}, encShare.Decommitment...)
+			modified.Decommitment[rand.Intn(NumChunks)].R = secp256k1.RandomPoint()
+			Expect(VerifyShare(&modified, commitment, pubKeys[0], h)).To(BeFalse())
+
+			modified = encShare
+			modified.Index = indices[1]
+			Expect(VerifyShare(&modified, commitment, pubKeys[0], h)).To(BeFalse())
+
+			modified = encShare
+			modified.Value = encShare.Value[1:]
+			Expect(VerifyShare(&modified, commitment, pubKeys[0], h)).To(BeFalse())
+		})
+
+		Specify("a share with the wrong value should not verify", func() {
+			share, commitment := RandomShare()
+			share.Share.Value = secp256k1.RandomFn()
+			encShare := EncryptShare(share, pubKeys[0], h)
+			Expect(VerifyShare(&encShare, commitment, pubKeys[0], h)).To(BeFalse())
+		})
+
+		Specify("decryption with the wrong private key should fail", func() {
+			share, _ := RandomShare()
+			encShare := EncryptShare(share, pubKeys[0], h)
+			_, err := DecryptShare(&encShare, privKeys[1])
+			Expect(err).To(Equal(ErrDecryptionFailed))
+		})
+	})
+
+	Context("validating rows", func() {
+		Specify("rows created with New should be valid", func() {
+			row := New(b, k, indices, pubKeys, indices[0], h)
+			Expect(IsValidRow(row, b, k, indices, pubKeys, h)).To(Succeed())
+		})
+
+		Specify("invalid rows should return the corresponding error", func() {
+			row := New(b, k, indices, pubKeys, indices[0], h)
+			Expect(IsValidRow(row[1:], b, k, indices, pubKeys, h)).To(Equal(ErrIncorrectBatchSize))
+			Expect(IsValidRow(row, b, k+1, indices, pubKeys, h)).To(Equal(ErrIncorrectThreshold))
+			Expect(IsValidRow(row, b, k, indices[1:], pubKeys[1:], h)).To(Equal(ErrIncorrectNumShares))
+
+			swapped := append([]secp256k1.Fn{}, indices...)
+			swapped[0], swapped[1] = swapped[1], swapped[0]
+			Expect(IsValidRow(row, b, k, swapped, pubKeys, h)).To(Equal(ErrIncorrectIndex))
+
+			otherKeys := append([]secp256k1.Point{}, pubKeys...)
+			otherKeys[n-1] = secp256k1.RandomPoint()
+			Expect(IsValidRow(row, b, k, indices, otherKeys, h)).To(Equal(ErrInvalidProof))
+		})
+	})
+
+	Context("consensus", func() {
+		Specify("the mock consensus should only accept valid rows, and the output should be valid", func() {
+			engine := mock.NewPVSSConsensus(indices, pubKeys, b, k, int(k)-1, h)
+
+			// A row with a share that is inconsistent with its commitment.
+			bad := New(b, k, indices, pubKeys, indices[0], h)
+			share, _ := RandomShare()
+			bad[0].Shares[1] = EncryptShare(share, pubKeys[1], h)
+			Expect(engine.HandleRow(bad)).To(BeFalse())
+			Expect(engine.Table()).To(BeEmpty())
+
+			for i := 0; !engine.Done(); i++ {
+				engine.HandleRow(New(b, k, indices, pubKeys, indices[i], h))
+			}
+			table := engine.Table()
+			Expect(len(table)).To(Equal(int(k)))
+
+			outputShares := make([]shamir.VerifiableShares, n)
+			var outputCommitments []shamir.Commitment
+			for i := range indices {
+				sharesBatch, commitmentsBatch, err := DecryptTable(table, indices[i], privKeys[i])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brng.IsValid(b, indices[i], h, sharesBatch, commitmentsBatch, int(k))).To(Succeed())
+				outputShares[i], outputCommitments = brng.HandleConsensusOutput(sharesBatch, commitmentsBatch)
+			}
+			for j := uint32(0); j < b; j++ {
+				shares := make(shamir.VerifiableShares, n)
+				for i := range shares {
+					shares[i] = outputShares[i][j]
+					Expect(shamir.IsValid(h, &outputCommitments[j], &shares[i])).To(BeTrue())
+				}
+				Expect(shamirutil.VsharesAreConsistent(shares, int(k))).To(BeTrue())
+			}
+		})
+	})
+
+	Context("complaints", func() {
+		// OutOfRange returns an encryption to the given public key of a
+		// random chunk, which will not be in range with overwhelming
+		// probability.
+		OutOfRange := func(pubKey secp256k1.Point) Ciphertext {
+			m, r := secp256k1.RandomFn(), secp256k1.RandomFn()
+			var ct Ciphertext
+			var rP secp256k1.Point
+			ct.R.BaseExp(&r)
+			ct.C.BaseExp(&m)
+			rP.Scale(&pubKey, &r)
+			ct.C.Add(&ct.C, &rP)
+			return ct
+		}
+
+		// BadTable returns a table decided on by the mock consensus in
+		// which the share in the first row for the player at the given
+		// position has a chunk that is out of range.
+		BadTable := func(pos, chunk int) [][]Sharing {
+			engine := mock.NewPVSSConsensus(indices, pubKeys, b, k, int(k)-1, h)
+			for i := 0; !engine.Done(); i++ {
+				engine.HandleRow(New(b, k, indices, pubKeys, indices[i], h))
+			}
+			table := engine.Table()
+			encShare := &table[0][0].Shares[pos]
+			if chunk < NumChunks {
+				encShare.Value[chunk] = OutOfRange(pubKeys[pos])
+			} else {
+				encShare.Decommitment[chunk-NumChunks] = OutOfRange(pubKeys[pos])
+			}
+			return table
+		}
+
+		Specify("a player should be able to prove that a share can not be decrypted", func() {
+			pos := rand.Intn(n)
+			chunk := rand.Intn(2 * NumChunks)
+			table := BadTable(pos, chunk)
+			_, _, err := DecryptTable(table, indices[pos], privKeys[pos])
+			Expect(err).To(Equal(ErrDecryptionFailed))
+
+			complaints := NewComplaints(table, indices[pos], privKeys[pos])
+			Expect(complaints).To(HaveLen(1))
+			Expect(complaints[0].Row).To(Equal(uint32(0)))
+			Expect(complaints[0].Sharing).To(Equal(uint32(0)))
+			Expect(complaints[0].Chunk).To(Equal(uint32(chunk)))
+			Expect(VerifyComplaint(table, &complaints[0], pubKeys[pos])).To(BeTrue())
+		})
+
+		Specify("players with shares that can be decrypted should not complain", func() {
+			pos := rand.Intn(n)
+			table := BadTable(pos, rand.Intn(2*NumChunks))
+			for i := range indices {
+				if i != pos {
+					Expect(NewComplaints(table, indices[i], privKeys[i])).To(BeEmpty())
+				}
+			}
+		})
+
+		Specify("invalid complaints should not verify", func() {
+			pos := rand.Intn(n)
+			table := BadTable(pos, rand.Intn(2*NumChunks))
+			complaint := NewComplaints(table, indices[pos], privKeys[pos])[0]
+
+			Expect(VerifyComplaint(table, &complaint, pubKeys[(pos+1)%n])).To(BeFalse())
+
+			modified := complaint
+			modified.M = secp256k1.RandomPoint()
+			Expect(VerifyComplaint(table, &modified, pubKeys[pos])).To(BeFalse())
+
+			modified = complaint
+			modified.Chunk = uint32(2 * NumChunks)
+			Expect(VerifyComplaint(table, &modified, pubKeys[pos])).To(BeFalse())
+
+			modified = complaint
+			modified.Row = 1
+			Expect(VerifyComplaint(table, &modified, pubKeys[pos])).To(BeFalse())
+
+			modified = complaint
+			modified.Index = indices[(pos+1)%n]
+			Expect(VerifyComplaint(table, &modified, pubKeys[pos])).To(BeFalse())
+		})
+
+		Specify("rows with valid complaints should be disqualified", func() {
+			pos := rand.Intn(n)
+			table := BadTable(pos, rand.Intn(2*NumChunks))
+
+			// A false complaint against the second row from another player.
+			other := (pos + 1) % n
+			falseComplaint := NewComplaints(BadTable(other, 0), indices[other], privKeys[other])[0]
+			falseComplaint.Row = 1
+
+			complaints := append(NewComplaints(table, indices[pos], privKeys[pos]), falseComplaint)
+			filtered, err := Disqualify(table, complaints, indices, pubKeys, len(table)-1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filtered).To(Equal(table[1:]))
+
+			for i := range indices {
+				sharesBatch, commitmentsBatch, err := DecryptTable(filtered, indices[i], privKeys[i])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brng.IsValid(b, indices[i], h, sharesBatch, commitmentsBatch, len(filtered))).To(Succeed())
+			}
+
+			filtered, err = Disqualify(table, complaints, indices, pubKeys, len(table))
+			Expect(err).To(Equal(ErrNotEnoughContributions))
+			Expect(filtered).To(BeNil())
+		})
+	})
+
+	Context("panics", func() {
+		Specify("wrong number of public keys", func() {
+			Expect(func() { New(b, k, indices, pubKeys[1:], indices[0], h) }).To(Panic())
+		})
+
+		Specify("required contributions less than 1", func() {
+			Expect(func() { Disqualify(nil, nil, indices, pubKeys, 0) }).To(Panic())
+		})
+	})
+})
@@ -0,0 +1,233 @@
+package brng
+
+import (
+	"fmt"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// ContributionValidity records which of the checks performed by IsValid
+// passed for a single contribution in a single element of the batch.
+type ContributionValidity struct {
+	// Shape is true if both a commitment and a share are present for the
+	// contribution, and the commitment is not empty and has the same threshold
+	// as the majority of the other commitments.
+	Shape bool
+
+	// Index is true if the share has the index of the player. It is false if
+	// the shape check did not pass.
+	Index bool
+
+	// Share is true if the share is valid with respect to the commitment. It
+	// is false if either the shape or index checks did not pass.
+	Share bool
+}
+
+// Valid returns true if all of the checks passed for the contribution.
+func (v ContributionValidity) Valid() bool {
+	return v.Shape && v.Index && v.Share
+}
+
+// A ValidityReport is a detailed version of the result of IsValid, which
+// records the outcome of the checks for each of the contributions so that the
+// contributions that caused the consensus output to be invalid can be
+// identified.
+type ValidityReport struct {
+	// Err is the error that IsValid returns for the same arguments.
+	Err error
+
+	// Contributions is indexed first by the element of the batch and then by
+	// the contribution. The number of contributions is the largest number
+	// present in any element of the commitments batch. It will be nil if the
+	// commitments or shares batch does not have the correct batch size.
+	Contributions [][]ContributionValidity
+}
+
+// ValidContributions returns, in increasing order, the contributions that
+// passed all of the checks in every element of the batch.
+func (report ValidityReport) ValidContributions() []int {
+	if len(report.Contributions) == 0 {
+		return nil
+	}
+	valid := []int{}
+	for j := range report.Contributions[0] {
+		ok := true
+		for i := range report.Contributions {
+			if !report.Contributions[i][j].Valid() {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			valid = append(valid, j)
+		}
+	}
+	return valid
+}
+
+// Filter returns the given batches with only the contributions that passed
+// all of the checks in every element of the batch (see ValidContributions).
+// The batches should be the same as those that were used to create the
+// report. Both return values will be nil if the report has no contributions.
+//
+// The report only reflects the checks of this player, and other players can
+// find different contributions to be invalid. The output commitments that
+// HandleConsensusOutput computes from the filtered batches are therefore only
+// consistent with the outputs of the other players if all of the players have
+// agreed on the set of contributions to remove, for example by publishing and
+// verifying complaints against them. The filtered batches must not be passed
+// to HandleConsensusOutput before such an agreement has been reached.
+func (report ValidityReport) Filter(
+	sharesBatch []shamir.VerifiableShares, commitmentsBatch [][]shamir.Commitment,
+) (
+	[]shamir.VerifiableShares, [][]shamir.Commitment,
+) {
+	if len(report.Contributions) == 0 {
+		return nil, nil
+	}
+	valid := report.ValidContributions()
+	filteredSharesBatch := make([]shamir.VerifiableShares, len(report.Contributions))
+	filteredCommitmentsBatch := make([][]shamir.Commitment, len(report.Contributions))
+	for i := range report.Contributions {
+		filteredSharesBatch[i] = make(shamir.VerifiableShares, len(valid))
+		filteredCommitmentsBatch[i] = make([]shamir.Commitment, len(valid))
+		for l, j := range valid {
+			filteredSharesBatch[i][l] = sharesBatch[i][j]
+			filteredCommitmentsBatch[i][l] = commitmentsBatch[i][j]
+		}
+	}
+	return filteredSharesBatch, filteredCommitmentsBatch
+}
+
+// CheckValidity performs the same checks as IsValid, but instead of only
+// returning the first error that is encountered it returns a report of the
+// outcome of the checks for each contribution. The error in the report is the
+// same as the error that IsValid would return.
+//
+// Panics: This function will panic if the given required contributions is less
+// than 1.
+func CheckValidity(
+	batchSize uint32,
+	ownIndex secp256k1.Fn,
+	h secp256k1.Point,
+	sharesBatch []shamir.VerifiableShares,
+	commitmentsBatch [][]shamir.Commitment,
+	requiredContributions int,
+) ValidityReport {
+	if requiredContributions < 1 {
+		panic(fmt.Sprintf("required contributions must be at least 1: got %v", requiredContributions))
+	}
+	if uint32(len(commitmentsBatch)) != batchSize {
+		return ValidityReport{Err: ErrIncorrectCommitmentsBatchSize}
+	}
+	if uint32(len(sharesBatch)) != batchSize {
+		return ValidityReport{Err: validityErr(sharesBatch, commitmentsBatch, requiredContributions, nil)}
+	}
+
+	contributions := checkContributions(ownIndex, h, sharesBatch, commitmentsBatch, true)
+	return ValidityReport{
+		Err:           validityErr(sharesBatch, commitmentsBatch, requiredContributions, contributions),
+		Contributions: contributions,
+	}
+}
+
+// checkContributions computes the validity of each contribution in the given
+// batches, which are assumed to have the same batch size. If check shares is
+// false, the shares are not checked against the commitments, and the share
+// check is considered to pass whenever the index check passes.
+func checkContributions(
+	ownIndex secp256k1.Fn,
+	h secp256k1.Point,
+	sharesBatch []shamir.VerifiableShares,
+	commitmentsBatch [][]shamir.Commitment,
+	checkShares bool,
+) [][]ContributionValidity {
+	numContributions := 0
+	for _, commitments := range commitmentsBatch {
+		if len(commitments) > numContributions {
+			numContributions = len(commitments)
+		}
+	}
+	k := majorityThreshold(commitmentsBatch)
+	contributions := make([][]ContributionValidity, len(commitmentsBatch))
+	for i := range contributions {
+		contributions[i] = make([]ContributionValidity, numContributions)
+		for j := range contributions[i] {
+			if j >= len(commitmentsBatch[i]) || j >= len(sharesBatch[i]) ||
+				commitmentsBatch[i][j].Len() != k || k == 0 {
+				continue
+			}
+			contributions[i][j].Shape = true
+			if !sharesBatch[i][j].Share.IndexEq(&ownIndex) {
+				continue
+			}
+			contributions[i][j].Index = true
+			contributions[i][j].Share = !checkShares ||
+				shamir.IsValid(h, &commitmentsBatch[i][j], &sharesBatch[i][j])
+		}
+	}
+	return contributions
+}
+
+// validityErr determines the error for the given batches in the same order as
+// IsValid, using the given per contribution results for the index and share
+// checks. The commitments batch is assumed to have the correct batch size.
+func validityErr(
+	sharesBatch []shamir.VerifiableShares,
+	commitmentsBatch [][]shamir.Commitment,
+	requiredContributions int,
+	contributions [][]ContributionValidity,
+) error {
+	numContributions := len(commitmentsBatch[0])
+	if numContributions < requiredContributions {
+		return ErrNotEnoughContributions
+	}
+	k := commitmentsBatch[0][0].Len()
+	if k == 0 {
+		return ErrInvalidCommitmentDimensions
+	}
+	for _, commitments := range commitmentsBatch {
+		if len(commitments) != numContributions {
+			return ErrInvalidCommitmentDimensions
+		}
+		for _, commitment := range commitments {
+			if commitment.Len() != k {
+				return ErrInvalidCommitmentDimensions
+			}
+		}
+	}
+	if len(sharesBatch) != len(commitmentsBatch) {
+		return ErrIncorrectSharesBatchSize
+	}
+	for i, shares := range sharesBatch {
+		if len(shares) != numContributions {
+			return ErrInvalidShareDimensions
+		}
+		for j := range shares {
+			if !contributions[i][j].Index {
+				return ErrIncorrectIndex
+			}
+			if !contributions[i][j].Share {
+				return ErrInvalidShares
+			}
+		}
+	}
+	return nil
+}
+
+// majorityThreshold returns the most common threshold of the given
+// commitments, with ties broken in favour of the threshold that occurs first.
+func majorityThreshold(commitmentsBatch [][]shamir.Commitment) int {
+	counts := map[int]int{}
+	k, max := 0, 0
+	for _, commitments := range commitmentsBatch {
+		for _, commitment := range commitments {
+			counts[commitment.Len()]++
+			if counts[commitment.Len()] > max {
+				k, max = commitment.Len(), counts[commitment.Len()]
+			}
+		}
+	}
+	return k
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/renproject/surge"
+)
+
+// modulePath is the import path of this module. Structs from other modules
+// that have unexported fields, such as field elements and curve points, are
+// printed as the hex encoding of their marshalled form instead of field by
+// field.
+const modulePath = "github.com/renproject/mpc"
+
+// printValue writes a human readable representation of the given value to the
+// given writer. Values that contain other values are written over multiple
+// lines, with each contained value on its own line and indented one level
+// further than the containing value.
+func printValue(w io.Writer, name string, v reflect.Value, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if !v.IsValid() {
+		fmt.Fprintf(w, "%v%v: <none>\n", indent, name)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			fmt.Fprintf(w, "%v%v: nil\n", indent, name)
+			return
+		}
+		printValue(w, name, v.Elem(), depth)
+	case reflect.Struct:
+		if isOpaque(v.Type()) {
+			fmt.Fprintf(w, "%v%v: %v\n", indent, name, formatOpaque(v))
+			return
+		}
+		fmt.Fprintf(w, "%v%v: %v\n", indent, name, v.Type())
+		for i := 0; i < v.NumField(); i++ {
+			printValue(w, v.Type().Field(i).Name, v.Field(i), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			buf := make([]byte, v.Len())
+			for i := range buf {
+				buf[i] = byte(v.Index(i).Uint())
+			}
+			fmt.Fprintf(w, "%v%v: 0x%v\n", indent, name, hex.EncodeToString(buf))
+			return
+		}
+		fmt.Fprintf(w, "%v%v: [%v]\n", indent, name, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			printValue(w, fmt.Sprintf("[%v]", i), v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		fmt.Fprintf(w, "%v%v: map[%v]\n", indent, name, v.Len())
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return formatLeaf(keys[i]) < formatLeaf(keys[j])
+		})
+		for _, key := range keys {
+			printValue(w, fmt.Sprintf("[%v]", formatLeaf(key)), v.MapIndex(key), depth+1)
+		}
+	default:
+		fmt.Fprintf(w, "%v%v: %v\n", indent, name, formatLeaf(v))
+	}
+}
+
+// isOpaque returns true if values of the given struct type should be printed
+// as a single value rather than field by field.
+func isOpaque(t reflect.Type) bool {
+	if strings.HasPrefix(t.PkgPath(), modulePath) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatOpaque formats a struct from another module. If the struct can be
+// marshalled, it is formatted as the hex encoding of its marshalled form.
+// Values that are not addressable, such as map values, fall back to the
+// default formatting.
+func formatOpaque(v reflect.Value) string {
+	if v.CanAddr() {
+		ptr := reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Interface()
+		if m, ok := ptr.(surge.Marshaler); ok {
+			buf, err := surge.ToBinary(m)
+			if err == nil {
+				return "0x" + hex.EncodeToString(buf)
+			}
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func formatLeaf(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return fmt.Sprint(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Float())
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
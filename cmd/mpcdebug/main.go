@@ -0,0 +1,410 @@
+// Command mpcdebug is an interactive debugger for the debug files that are
+// created by a Network or Scheduler when a machine panics (by default
+// panic.dump). The debug file is loaded for one of the registered protocols,
+// and the message history can then be stepped through while inspecting the
+// states of the machines and the messages that they handle.
+//
+// Usage:
+//
+//	mpcdebug -protocol <name> [-minimize <output>] <file>
+//
+// If the -minimize flag is given, the message history is first reduced to a
+// small subsequence that still causes the same panic (see mpcutil.Minimize),
+// which is saved to the output file and then debugged instead of the original.
+//
+// Type "help" at the prompt for a list of commands.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/renproject/mpc/brng/brngutil"
+	"github.com/renproject/mpc/brng/complaint/complaintutil"
+	"github.com/renproject/mpc/coin/coinutil"
+	"github.com/renproject/mpc/exp/exputil"
+	"github.com/renproject/mpc/inv/invutil"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/mulopen/mulopenutil"
+	"github.com/renproject/mpc/open/openutil"
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/mpc/rng/rngutil"
+	"github.com/renproject/mpc/zerotest/zerotestutil"
+)
+
+// A protocol is the pair of message and machine types that are needed to load
+// the debug file for a network test. All of the machines in a debug file have
+// the same type, so the machine types that deviate from a protocol are
+// registered separately, with the suffix "-malicious".
+type protocol struct {
+	messageType, machineType interface{}
+}
+
+var protocols = map[string]protocol{
+	"open":           {openutil.Message{}, openutil.Machine{}},
+	"rng":            {rngutil.RngMessage{}, rngutil.RngMachine{}},
+	"rkpg":           {rkpgutil.Message{}, rkpgutil.HonestMachine{}},
+	"rkpg-malicious": {rkpgutil.Message{}, rkpgutil.MaliciousMachine{}},
+	"mulopen":        {mulopenutil.Message{}, mulopenutil.Machine{}},
+	"inv":            {invutil.Message{}, invutil.Machine{}},
+	"inv-malicious":  {invutil.Message{}, invutil.MaliciousMachine{}},
+	"zerotest":       {zerotestutil.Message{}, zerotestutil.Machine{}},
+	"exp":            {exputil.Message{}, exputil.Machine{}},
+	"brng":           {brngutil.BrngMessage{}, brngutil.BrngMachine{}},
+	"complaint":      {complaintutil.Message{}, complaintutil.Machine{}},
+	"coin":           {coinutil.Message{}, coinutil.Machine{}},
+}
+
+const help = `commands:
+  step [n]             handle the next n messages (default 1)
+  back [n]             undo the last n handled messages (default 1)
+  goto <pos>           move to the given position in the message history
+  continue             handle messages until a breakpoint or the end
+  break from <id>      break before handling a message from the given machine
+  break to <id>        break before handling a message to the given machine
+  break                list the breakpoints
+  clear                remove all breakpoints
+  machines             list the machine IDs
+  print <id>           print the current state of the given machine
+  msg [pos]            print the message at the given position (default next)
+  history <id>         list the messages sent from or to the given machine
+  diff <id> <a> <b>    print the state changes of a machine between positions
+  pos                  print the current position
+  help                 print this message
+  quit                 exit the debugger`
+
+func main() {
+	name := flag.String("protocol", "", "protocol of the debug file (one of "+strings.Join(protocolNames(), ", ")+")")
+	minimize := flag.String("minimize", "", "minimize the message history and save it to the given file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %v -protocol <name> [-minimize <output>] <file>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	p, ok := protocols[*name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown protocol %q\n", *name)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	filename := flag.Arg(0)
+	if *minimize != "" {
+		n, err := minimizeDump(filename, *minimize, p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not minimize %v: %v\n", filename, err)
+			os.Exit(1)
+		}
+		fmt.Printf("minimized message history to %v messages\n", n)
+		filename = *minimize
+	}
+
+	dbg, err := load(filename, p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not load %v: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("loaded %v machines and %v messages\n", len(dbg.IDs()), dbg.Len())
+
+	r := repl{dbg: dbg, out: os.Stdout}
+	r.run(os.Stdin)
+}
+
+func protocolNames() []string {
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// load creates a Debugger for the given file, converting a panic while
+// unmarshalling into an error.
+func load(filename string, p protocol) (dbg mpcutil.Debugger, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return mpcutil.NewDebugger(filename, p.messageType, p.machineType), nil
+}
+
+// minimizeDump minimizes the message history of the given file, converting a
+// panic while unmarshalling into an error.
+func minimizeDump(filename, output string, p protocol) (n int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return mpcutil.Minimize(filename, output, p.messageType, p.machineType)
+}
+
+// A breakpoint triggers before a message from (or to) the given machine is
+// handled. Unlike the breakpoints of a Debugger, these trigger every time.
+type breakpoint struct {
+	from bool
+	id   mpcutil.ID
+}
+
+func (bp breakpoint) String() string {
+	if bp.from {
+		return fmt.Sprintf("from %v", bp.id)
+	}
+	return fmt.Sprintf("to %v", bp.id)
+}
+
+func (bp breakpoint) matches(msg mpcutil.Message) bool {
+	if bp.from {
+		return msg.From() == bp.id
+	}
+	return msg.To() == bp.id
+}
+
+type repl struct {
+	dbg mpcutil.Debugger
+	out io.Writer
+	bps []breakpoint
+}
+
+func (r *repl) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.out, "(mpcdebug) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return
+		}
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "q" {
+			return
+		}
+		if err := r.exec(args[0], args[1:]); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+// exec executes a single command. Machines are expected to panic when
+// handling some messages in a debug file, so a panic is reported as an error
+// instead of ending the session.
+func (r *repl) exec(cmd string, args []string) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic at position %v: %v", r.dbg.Pos(), rec)
+		}
+	}()
+
+	switch cmd {
+	case "step", "s":
+		n, err := optionalInt(args, 1)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n && r.dbg.Pos() < r.dbg.Len(); i++ {
+			r.dbg.Step()
+		}
+		r.printPos()
+	case "back", "b":
+		n, err := optionalInt(args, 1)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if !r.dbg.StepBack() {
+				break
+			}
+		}
+		r.printPos()
+	case "goto":
+		pos, err := requiredInt(args)
+		if err != nil {
+			return err
+		}
+		if !r.dbg.Goto(pos) {
+			return fmt.Errorf("position must be in the range [0, %v]", r.dbg.Len())
+		}
+		r.printPos()
+	case "continue", "c":
+		r.cont()
+	case "break":
+		return r.breakpoint(args)
+	case "clear":
+		r.bps = nil
+	case "machines":
+		for _, id := range r.dbg.IDs() {
+			fmt.Fprintln(r.out, id)
+		}
+	case "print", "p":
+		id, err := r.requiredID(args)
+		if err != nil {
+			return err
+		}
+		machine := r.dbg.MachineByID(id)
+		printValue(r.out, fmt.Sprintf("machine %v", id), reflect.ValueOf(machine), 0)
+	case "msg", "m":
+		pos, err := optionalInt(args, r.dbg.Pos())
+		if err != nil {
+			return err
+		}
+		msg := r.dbg.Message(pos)
+		if msg == nil {
+			return fmt.Errorf("position must be in the range [0, %v)", r.dbg.Len())
+		}
+		printValue(r.out, formatMessage(pos, msg), reflect.ValueOf(msg), 0)
+	case "history", "h":
+		id, err := r.requiredID(args)
+		if err != nil {
+			return err
+		}
+		r.history(id)
+	case "diff":
+		if len(args) != 3 {
+			return fmt.Errorf("expected arguments <id> <a> <b>")
+		}
+		id, err := r.requiredID(args[:1])
+		if err != nil {
+			return err
+		}
+		from, err := requiredInt(args[1:2])
+		if err != nil {
+			return err
+		}
+		to, err := requiredInt(args[2:])
+		if err != nil {
+			return err
+		}
+		if from < 0 || from > r.dbg.Len() || to < 0 || to > r.dbg.Len() {
+			return fmt.Errorf("positions must be in the range [0, %v]", r.dbg.Len())
+		}
+		diffs := r.dbg.Diff(id, from, to)
+		if len(diffs) == 0 {
+			fmt.Fprintln(r.out, "no differences")
+		}
+		for _, diff := range diffs {
+			fmt.Fprintf(r.out, "%v: %v -> %v\n", diff.Path, diff.From, diff.To)
+		}
+	case "pos":
+		r.printPos()
+	case "help":
+		fmt.Fprintln(r.out, help)
+	default:
+		return fmt.Errorf("unknown command %q (type \"help\" for a list of commands)", cmd)
+	}
+	return nil
+}
+
+// cont handles at least one message, and then continues until the next
+// message to be handled triggers a breakpoint. The breakpoints are registered
+// with the Debugger again each time, since those of the Debugger only trigger
+// once.
+func (r *repl) cont() {
+	if r.dbg.Pos() == r.dbg.Len() {
+		r.printPos()
+		return
+	}
+	r.dbg.Step()
+	r.dbg.ClearBreakPoints()
+	for _, bp := range r.bps {
+		r.dbg.SetMessageBreakPoint(bp.matches)
+	}
+	r.dbg.Continue()
+
+	if msg := r.dbg.Message(r.dbg.Pos()); msg != nil {
+		fmt.Fprintf(r.out, "breakpoint: %v\n", formatMessage(r.dbg.Pos(), msg))
+	}
+	r.printPos()
+}
+
+func (r *repl) breakpoint(args []string) error {
+	if len(args) == 0 {
+		if len(r.bps) == 0 {
+			fmt.Fprintln(r.out, "no breakpoints")
+		}
+		for _, bp := range r.bps {
+			fmt.Fprintln(r.out, bp)
+		}
+		return nil
+	}
+	if len(args) != 2 || (args[0] != "from" && args[0] != "to") {
+		return fmt.Errorf("expected arguments from <id> or to <id>")
+	}
+	id, err := r.requiredID(args[1:])
+	if err != nil {
+		return err
+	}
+	r.bps = append(r.bps, breakpoint{from: args[0] == "from", id: id})
+	return nil
+}
+
+// history lists the messages in the message history that were sent from or
+// to the machine with the given ID. Messages that have already been handled
+// are marked with an asterisk.
+func (r *repl) history(id mpcutil.ID) {
+	for pos := 0; pos < r.dbg.Len(); pos++ {
+		msg := r.dbg.Message(pos)
+		if msg.From() != id && msg.To() != id {
+			continue
+		}
+		mark := " "
+		if pos < r.dbg.Pos() {
+			mark = "*"
+		}
+		fmt.Fprintf(r.out, "%v %v\n", mark, formatMessage(pos, msg))
+	}
+}
+
+func (r *repl) printPos() {
+	fmt.Fprintf(r.out, "position %v/%v\n", r.dbg.Pos(), r.dbg.Len())
+}
+
+func (r *repl) requiredID(args []string) (mpcutil.ID, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected a machine ID")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid machine ID %q", args[0])
+	}
+	if r.dbg.MachineByID(mpcutil.ID(id)) == nil {
+		return 0, fmt.Errorf("no machine with ID %v", id)
+	}
+	return mpcutil.ID(id), nil
+}
+
+func formatMessage(pos int, msg mpcutil.Message) string {
+	return fmt.Sprintf("#%v %v -> %v (%T)", pos, msg.From(), msg.To(), msg)
+}
+
+func optionalInt(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	return requiredInt(args)
+}
+
+func requiredInt(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected a single number")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", args[0])
+	}
+	return n, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/renproject/mpc/coin/coinutil"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir/shamirutil"
+	"github.com/renproject/surge"
+)
+
+var _ = Describe("mpcdebug", func() {
+	Context("protocols", func() {
+		Specify("the registered types should be messages and machines", func() {
+			for name, p := range protocols {
+				_, ok := reflect.New(reflect.TypeOf(p.messageType)).Interface().(mpcutil.Message)
+				Expect(ok).To(BeTrue(), name)
+				_, ok = reflect.New(reflect.TypeOf(p.machineType)).Interface().(mpcutil.Machine)
+				Expect(ok).To(BeTrue(), name)
+			}
+		})
+	})
+
+	Context("debug files", func() {
+		n, k, b := 5, 2, 3
+
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "mpcdebug")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		// DumpCoin runs a coin tossing network and saves the debug file for
+		// the run, returning its filename.
+		DumpCoin := func() string {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+			shares, coms, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			ids := make([]mpcutil.ID, n)
+			for i := range ids {
+				ids[i] = mpcutil.ID(i + 1)
+			}
+			machines := make([]mpcutil.Machine, n)
+			for i := range machines {
+				machine := coinutil.NewMachine(ids[i], ids, indices, h, shares[i], coms)
+				machines[i] = &machine
+			}
+			network := mpcutil.NewNetwork(machines, func([]mpcutil.Message) {})
+			network.SetCaptureHist(true)
+			Expect(network.Run()).To(Succeed())
+
+			filename := filepath.Join(dir, "test.dump")
+			network.Dump(filename)
+			return filename
+		}
+
+		// Exec executes the given command and returns the output.
+		Exec := func(r *repl, cmd string, args ...string) string {
+			out := new(bytes.Buffer)
+			r.out = out
+			Expect(r.exec(cmd, args)).To(Succeed())
+			return out.String()
+		}
+
+		Specify("printed states should match the states that were dumped", func() {
+			dbg, err := load(DumpCoin(), protocols["coin"])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dbg.Len()).To(Equal(n * (n - 1)))
+			r := repl{dbg: dbg}
+
+			Expect(Exec(&r, "msg", "0")).To(HavePrefix("#0 "))
+			Expect(Exec(&r, "goto", fmt.Sprint(dbg.Len()))).To(Equal(fmt.Sprintf("position %v/%v\n", dbg.Len(), dbg.Len())))
+
+			// The field elements are printed as the hex encoding of their
+			// marshalled form, which should unmarshal to the values in the
+			// state of the machine.
+			out := Exec(&r, "print", "1")
+			Expect(out).To(HavePrefix("machine 1: coinutil.Machine\n"))
+			Expect(out).To(ContainSubstring(fmt.Sprintf("  Values: [%v]\n", b)))
+			values := r.dbg.MachineByID(1).(*coinutil.Machine).Values
+			for i, value := range values {
+				prefix := fmt.Sprintf("    [%v]: 0x", i)
+				var line string
+				for _, l := range strings.Split(out, "\n") {
+					if strings.HasPrefix(l, prefix) && line == "" {
+						line = l
+					}
+				}
+				buf, err := hex.DecodeString(strings.TrimPrefix(line, prefix))
+				Expect(err).ToNot(HaveOccurred())
+				var x secp256k1.Fn
+				Expect(surge.FromBinary(&x, buf)).To(Succeed())
+				Expect(x.Eq(&value)).To(BeTrue())
+			}
+
+			Expect(Exec(&r, "diff", "1", "0", "0")).To(Equal("no differences\n"))
+			Expect(Exec(&r, "diff", "1", "0", fmt.Sprint(dbg.Len()))).To(ContainSubstring(fmt.Sprintf("Values.len: 0 -> %v", b)))
+		})
+
+		Specify("files with the wrong types should return an error", func() {
+			_, err := load(DumpCoin(), protocols["open"])
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package coin
+
+import (
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+
+	"github.com/renproject/mpc/open"
+)
+
+// A Tosser is a state machine that implements public coin tossing, which can
+// be used as a random beacon. The input is the output of an instance of the
+// RNG protocol, i.e. a batch of verifiable sharings of random values that are
+// unknown to (and unbiasable by) any set of at most k-1 players. Each player
+// broadcasts its shares of the batch, and once k valid shares have been
+// received the random values are opened. Since the shares are checked against
+// the RNG commitments, malicious players can delay the opening but can not
+// change the output.
+//
+// Along with the random values, the Tosser outputs a Transcript that contains
+// the decommitments for the values. Anyone that knows the RNG commitments can
+// use the transcript to check that the values are the correct openings (see
+// Transcript.Verify), without needing to trust any of the players.
+type Tosser struct {
+	opener     open.Opener
+	transcript Transcript
+}
+
+// A Transcript is a record of the output of an instance of coin tossing. It
+// contains the opened random values and the corresponding decommitments.
+type Transcript struct {
+	Values        []secp256k1.Fn
+	Decommitments []secp256k1.Fn
+}
+
+// Verify returns true if the values in the transcript are the correct openings
+// for the given commitments (the RNG output commitments), and false otherwise.
+// This requires that for every value v and decommitment d in the transcript,
+// the constant term of the corresponding commitment is vG + dH.
+func (transcript Transcript) Verify(h secp256k1.Point, commitments []shamir.Commitment) bool {
+	b := len(commitments)
+	if len(transcript.Values) != b || len(transcript.Decommitments) != b {
+		return false
+	}
+	var com, hPow secp256k1.Point
+	for i := range commitments {
+		if commitments[i].Len() < 1 {
+			return false
+		}
+		com.BaseExp(&transcript.Values[i])
+		hPow.Scale(&h, &transcript.Decommitments[i])
+		com.Add(&com, &hPow)
+		if !com.Eq(&commitments[i][0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// New returns a new coin tossing state machine for the given RNG output, along
+// with the share batch that should be broadcast to all other players. The
+// share batch is handled by the state machine before it is returned. The
+// length of the commitment slice is the number of coins that will be tossed.
+//
+// Panics: This function will panic in the same cases as open.New, or if the
+// number of shares is not equal to the number of commitments.
+func New(
+	indices []secp256k1.Fn,
+	h secp256k1.Point,
+	shares shamir.VerifiableShares,
+	commitments []shamir.Commitment,
+) (Tosser, shamir.VerifiableShares) {
+	if len(shares) != len(commitments) {
+		panic("shares and commitments have different batch sizes")
+	}
+	tosser := Tosser{
+		opener:     open.New(commitments, indices, h),
+		transcript: Transcript{},
+	}
+	if _, err := tosser.HandleShareBatch(shares); err != nil {
+		panic("error handling own shares")
+	}
+	shareBatch := make(shamir.VerifiableShares, len(shares))
+	copy(shareBatch, shares)
+	return tosser, shareBatch
+}
+
+// HandleShareBatch handles a share batch broadcast by another player. Once
+// enough valid share batches have been received, the random values are
+// opened and returned. If not enough share batches have been received, the
+// return value is nil. Once the values have been opened, they are returned
+// for all subsequent calls without handling the given shares. If the share
+// batch is invalid, the corresponding error from open.Opener is returned.
+func (tosser *Tosser) HandleShareBatch(shares shamir.VerifiableShares) ([]secp256k1.Fn, error) {
+	if tosser.Done() {
+		return tosser.transcript.Values, nil
+	}
+	values, decommitments, err := tosser.opener.HandleShareBatch(shares)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, nil
+	}
+	tosser.transcript = Transcript{
+		Values:        values,
+		Decommitments: decommitments,
+	}
+	return values, nil
+}
+
+// Done returns true if the random values have been opened, and false
+// otherwise.
+func (tosser Tosser) Done() bool {
+	return len(tosser.transcript.Values) != 0
+}
+
+// Transcript returns the transcript for the opened random values. If the
+// values have not yet been opened, the returned transcript will be empty.
+func (tosser Tosser) Transcript() Transcript {
+	return tosser.transcript
+}
@@ -0,0 +1,172 @@
+package coin_test
+
+import (
+	"bytes"
+	"math/rand"
+	"time"
+
+	"github.com/renproject/mpc/coin/coinutil"
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/coin"
+	. "github.com/renproject/mpc/mpcutil"
+)
+
+var _ = Describe("Coin tossing", func() {
+	rand.Seed(int64(time.Now().Nanosecond()))
+	trials := 10
+
+	// Pedersen commitment system parameter. For testing this can be random,
+	// but in a real world use case this should be chosen appropriately.
+	h := secp256k1.RandomPoint()
+
+	RandomTestParams := func() (int, int, int, []secp256k1.Fn) {
+		n := shamirutil.RandRange(5, 20)
+		k := shamirutil.RandRange(2, n)
+		b := shamirutil.RandRange(1, 5)
+		indices := shamirutil.RandomIndices(n)
+		return n, k, b, indices
+	}
+
+	Context("state transitions", func() {
+		It("should open the random values once k share batches are received", func() {
+			for i := 0; i < trials; i++ {
+				n, k, b, indices := RandomTestParams()
+				shares, coms, secrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+
+				tosser, shareBatch := New(indices, h, shares[0], coms)
+				Expect(shareBatch).To(Equal(shares[0]))
+				for j := 1; j < n; j++ {
+					values, err := tosser.HandleShareBatch(shares[j])
+					Expect(err).ToNot(HaveOccurred())
+					if j < k-1 {
+						Expect(values).To(BeNil())
+						Expect(tosser.Done()).To(BeFalse())
+						continue
+					}
+					Expect(tosser.Done()).To(BeTrue())
+					Expect(values).To(Equal(secrets))
+				}
+				Expect(tosser.Transcript().Values).To(Equal(secrets))
+				Expect(tosser.Transcript().Verify(h, coms)).To(BeTrue())
+			}
+		})
+
+		It("should return an error for invalid share batches", func() {
+			for i := 0; i < trials; i++ {
+				n, k, b, indices := RandomTestParams()
+				shares, coms, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+
+				tosser, _ := New(indices, h, shares[0], coms)
+				invalid := make(shamir.VerifiableShares, b)
+				copy(invalid, shares[rand.Intn(n-1)+1])
+				invalid[rand.Intn(b)].Share.Value = secp256k1.RandomFn()
+				values, err := tosser.HandleShareBatch(invalid)
+				Expect(values).To(BeNil())
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("transcripts", func() {
+		It("should not verify against the wrong commitments", func() {
+			for i := 0; i < trials; i++ {
+				n, k, b, indices := RandomTestParams()
+				shares, coms, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+
+				tosser, _ := New(indices, h, shares[0], coms)
+				for j := 1; j < n; j++ {
+					tosser.HandleShareBatch(shares[j])
+				}
+				transcript := tosser.Transcript()
+				Expect(transcript.Verify(h, coms)).To(BeTrue())
+
+				_, otherComs, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+				Expect(transcript.Verify(h, otherComs)).To(BeFalse())
+				Expect(transcript.Verify(h, coms[1:])).To(BeFalse())
+
+				transcript.Values[rand.Intn(b)] = secp256k1.RandomFn()
+				Expect(transcript.Verify(h, coms)).To(BeFalse())
+			}
+		})
+	})
+
+	Context("streams", func() {
+		It("should be deterministic and depend on the value", func() {
+			for i := 0; i < trials; i++ {
+				value := secp256k1.RandomFn()
+				s1, s2 := NewStream(value), NewStream(value)
+				s3 := NewStream(secp256k1.RandomFn())
+
+				// Reading in different sized chunks should give the same
+				// bytes.
+				bs1, bs2, bs3 := make([]byte, 100), make([]byte, 100), make([]byte, 100)
+				s1.Read(bs1)
+				s2.Read(bs2[:17])
+				s2.Read(bs2[17:50])
+				s2.Read(bs2[50:])
+				s3.Read(bs3)
+				Expect(bytes.Equal(bs1, bs2)).To(BeTrue())
+				Expect(bytes.Equal(bs1, bs3)).To(BeFalse())
+			}
+		})
+
+		It("should sample integers in the given range", func() {
+			stream := NewStream(secp256k1.RandomFn())
+			counts := make([]int, 7)
+			for i := 0; i < 7000; i++ {
+				x := stream.Uint64n(7)
+				Expect(x < 7).To(BeTrue())
+				counts[x]++
+			}
+			for _, count := range counts {
+				Expect(count).To(BeNumerically(">", 0))
+			}
+			Expect(func() { stream.Uint64n(0) }).To(Panic())
+		})
+	})
+
+	Context("panics", func() {
+		Specify("shares and commitments with different batch sizes", func() {
+			_, k, b, indices := RandomTestParams()
+			shares, coms, _ := rkpgutil.RNGOutputBatch(indices, k, b+1, h)
+			Expect(func() { New(indices, h, shares[0][:b], coms) }).To(Panic())
+		})
+	})
+
+	Context("network", func() {
+		It("all online players should open the same verifiable random values", func() {
+			n, k, b, indices := RandomTestParams()
+			shares, coms, secrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+
+			ids := make([]ID, n)
+			for i := range ids {
+				ids[i] = ID(i + 1)
+			}
+			machines := make([]Machine, n)
+			for i := range machines {
+				machine := coinutil.NewMachine(ids[i], ids, indices, h, shares[i], coms)
+				machines[i] = &machine
+			}
+
+			shuffleMsgs, isOffline := MessageShufflerDropper(ids, n-k)
+			network := NewNetwork(machines, shuffleMsgs)
+			network.SetCaptureHist(true)
+			Expect(network.Run()).To(Succeed())
+
+			for _, machine := range machines {
+				if isOffline[machine.ID()] {
+					continue
+				}
+				m := machine.(*coinutil.Machine)
+				Expect(m.Values).To(Equal(secrets))
+				Expect(m.Transcript().Verify(h, coms)).To(BeTrue())
+			}
+		})
+	})
+})
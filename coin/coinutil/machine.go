@@ -0,0 +1,131 @@
+package coinutil
+
+import (
+	"github.com/renproject/mpc/coin"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+// The Machine type used for the coin tossing network test.
+type Machine struct {
+	ownID  mpcutil.ID
+	ids    []mpcutil.ID
+	shares shamir.VerifiableShares
+	tosser coin.Tosser
+	Values []secp256k1.Fn
+}
+
+// NewMachine constructs a new Machine for the given RNG output.
+func NewMachine(
+	ownID mpcutil.ID,
+	ids []mpcutil.ID,
+	indices []secp256k1.Fn,
+	h secp256k1.Point,
+	shares shamir.VerifiableShares,
+	commitments []shamir.Commitment,
+) Machine {
+	tosser, shareBatch := coin.New(indices, h, shares, commitments)
+	return Machine{
+		ownID:  ownID,
+		ids:    ids,
+		shares: shareBatch,
+		tosser: tosser,
+		Values: tosser.Transcript().Values,
+	}
+}
+
+// ID implements the mpcutil.Machine interface.
+func (m Machine) ID() mpcutil.ID {
+	return m.ownID
+}
+
+// Transcript returns the transcript of the coin tossing state machine.
+func (m Machine) Transcript() coin.Transcript {
+	return m.tosser.Transcript()
+}
+
+// InitialMessages implements the mpcutil.Machine interface.
+func (m Machine) InitialMessages() []mpcutil.Message {
+	messages := make([]mpcutil.Message, 0, len(m.ids)-1)
+	for _, id := range m.ids {
+		if id == m.ownID {
+			continue
+		}
+		messages = append(messages, &Message{
+			shares: m.shares,
+			from:   m.ownID,
+			to:     id,
+		})
+	}
+	return messages
+}
+
+// Handle implements the mpcutil.Machine interface.
+func (m *Machine) Handle(msg mpcutil.Message) []mpcutil.Message {
+	message := msg.(*Message)
+	values, _ := m.tosser.HandleShareBatch(message.shares)
+	if values != nil {
+		m.Values = values
+	}
+	return nil
+}
+
+// Done implements the mpcutil.Terminator interface.
+func (m Machine) Done() bool { return m.tosser.Done() }
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of coin values.
+func (m Machine) Result() interface{} { return m.Values }
+
+// SizeHint implements the surge.SizeHinter interface.
+func (m Machine) SizeHint() int {
+	return m.ownID.SizeHint() +
+		surge.SizeHint(m.ids) +
+		m.shares.SizeHint() +
+		m.tosser.SizeHint() +
+		surge.SizeHint(m.Values)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (m Machine) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.ownID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.ids, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.shares.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.tosser.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(m.Values, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (m *Machine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.ownID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.ids, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.shares.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.tosser.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&m.Values, buf, rem)
+}
@@ -0,0 +1,84 @@
+package coin
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/surge"
+
+	"github.com/renproject/mpc/open"
+)
+
+// Generate implements the quick.Generator interface.
+func (tosser Tosser) Generate(rand *rand.Rand, size int) reflect.Value {
+	size /= 2
+	opener := open.Opener{}.Generate(rand, size).Interface().(open.Opener)
+	transcript := Transcript{}.Generate(rand, size).Interface().(Transcript)
+	return reflect.ValueOf(Tosser{
+		opener:     opener,
+		transcript: transcript,
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (tosser Tosser) SizeHint() int {
+	return tosser.opener.SizeHint() + tosser.transcript.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (tosser Tosser) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := tosser.opener.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return tosser.transcript.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (tosser *Tosser) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := tosser.opener.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return tosser.transcript.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (transcript Transcript) Generate(rand *rand.Rand, size int) reflect.Value {
+	// A field element is 4 uint64s.
+	b := rand.Intn(size/64+1) + 1
+	values := make([]secp256k1.Fn, b)
+	decommitments := make([]secp256k1.Fn, b)
+	for i := range values {
+		values[i] = secp256k1.RandomFn()
+		decommitments[i] = secp256k1.RandomFn()
+	}
+	return reflect.ValueOf(Transcript{
+		Values:        values,
+		Decommitments: decommitments,
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (transcript Transcript) SizeHint() int {
+	return surge.SizeHint(transcript.Values) + surge.SizeHint(transcript.Decommitments)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (transcript Transcript) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Marshal(transcript.Values, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(transcript.Decommitments, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (transcript *Transcript) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.Unmarshal(&transcript.Values, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&transcript.Decommitments, buf, rem)
+}
@@ -0,0 +1,96 @@
+package coin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/renproject/secp256k1"
+)
+
+// streamDomain is used to separate the hashes used to derive streams from any
+// other use of hashing of random values.
+var streamDomain = []byte("renproject/mpc/coin/stream")
+
+// A Stream is a deterministic stream of random bytes derived from a random
+// value, for example a value opened by a Tosser. The stream is computed by
+// hashing the value to obtain a seed, and then hashing the seed along with a
+// counter to obtain each successive block of 32 bytes. Every player that
+// derives a stream from the same value will obtain the same bytes, and so
+// streams can be used when more shared randomness is needed than fits in a
+// single field element, or when the randomness needs to be in some specific
+// form, such as an index for leader election.
+type Stream struct {
+	seed    [sha256.Size]byte
+	counter uint64
+	block   [sha256.Size]byte
+	used    int
+}
+
+// NewStream returns a new stream of random bytes derived from the given value.
+func NewStream(value secp256k1.Fn) Stream {
+	var bs [32]byte
+	value.PutB32(bs[:])
+	hasher := sha256.New()
+	hasher.Write(streamDomain)
+	hasher.Write(bs[:])
+
+	stream := Stream{used: sha256.Size}
+	copy(stream.seed[:], hasher.Sum(nil))
+	return stream
+}
+
+// NewStreams returns a stream for each of the given values.
+func NewStreams(values []secp256k1.Fn) []Stream {
+	streams := make([]Stream, len(values))
+	for i := range values {
+		streams[i] = NewStream(values[i])
+	}
+	return streams
+}
+
+// Read implements the io.Reader interface. It always fills the given slice
+// and never returns an error.
+func (stream *Stream) Read(p []byte) (int, error) {
+	for n := 0; n < len(p); {
+		if stream.used == len(stream.block) {
+			stream.nextBlock()
+		}
+		copied := copy(p[n:], stream.block[stream.used:])
+		stream.used += copied
+		n += copied
+	}
+	return len(p), nil
+}
+
+// Uint64n returns a uniformly random integer in the range [0, n) read from
+// the stream. Rejection sampling is used so that the result is not biased
+// towards smaller integers.
+//
+// Panics: This function panics if n is 0.
+func (stream *Stream) Uint64n(n uint64) uint64 {
+	if n == 0 {
+		panic("n must be greater than 0")
+	}
+	// The largest value that is accepted, chosen so that the number of
+	// accepted values is a multiple of n.
+	max := ^uint64(0) - (^uint64(0)%n+1)%n
+	var bs [8]byte
+	for {
+		stream.Read(bs[:])
+		x := binary.BigEndian.Uint64(bs[:])
+		if x <= max {
+			return x % n
+		}
+	}
+}
+
+func (stream *Stream) nextBlock() {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], stream.counter)
+	hasher := sha256.New()
+	hasher.Write(stream.seed[:])
+	hasher.Write(counter[:])
+	copy(stream.block[:], hasher.Sum(nil))
+	stream.counter++
+	stream.used = 0
+}
@@ -0,0 +1,7 @@
+package exp
+
+import "errors"
+
+// ErrInvalidRound is returned when the round of the given message is beyond
+// the last round of the exponentiation instance.
+var ErrInvalidRound = errors.New("invalid round")
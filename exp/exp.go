@@ -0,0 +1,511 @@
+// Package exp implements a protocol for computing shares of a^e for a secret
+// a and a public exponent e, for a batch of secrets a at once.
+//
+// The protocol uses square and multiply, starting from the least significant
+// bit of the exponent, so that the squaring of the base and the
+// multiplication of the base into the accumulated result can be done in the
+// same round. The number of rounds is therefore at most the number of bits in
+// the exponent.
+//
+// Each multiplication is done using the multiply and open protocol (see the
+// mulopen package), which includes the ZKPs that ensure that each party has
+// contributed the correct product. Instead of masking the opened product with
+// a sharing of zero, it is masked with a random sharing with threshold 2k-1,
+// so that nothing about the product is revealed. If the same random value is
+// also shared with threshold k, then subtracting the latter sharing from the
+// opened product gives a sharing of the product with threshold k. Such a pair
+// of sharings is referred to as a double sharing, and one is required for
+// each multiplication (see NumMasks). Double sharings can be created from the
+// outputs of RNG and RZG (see RaiseThreshold), in which case the constant
+// terms of the commitments for the two sharings are equal, which allows every
+// party to check that they are sharings of the same value.
+package exp
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/renproject/mpc/mulopen"
+	"github.com/renproject/mpc/params"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// An Exponentiator is a state machine that implements the exponentiation
+// protocol.
+type Exponentiator struct {
+	mulopener mulopen.MulOpener
+	msgBuf    []Message
+
+	// bits contains the bits of the exponent, starting with the least
+	// significant bit, with each byte being either 0 or 1.
+	bits                          []byte
+	round, maskOffset             uint32
+	accSet, roundMul, roundSquare bool
+
+	baseShareBatch, accShareBatch           shamir.VerifiableShares
+	baseCommitmentBatch, accCommitmentBatch []shamir.Commitment
+
+	rLowShareBatch, rHighShareBatch           shamir.VerifiableShares
+	rLowCommitmentBatch, rHighCommitmentBatch []shamir.Commitment
+
+	indices []secp256k1.Fn
+	h       secp256k1.Point
+
+	// rand is the source of randomness for the multiply and open step of
+	// each round. It is not marshalled, and if it is nil then random.Reader
+	// is used.
+	rand io.Reader
+}
+
+// NumMasks returns the number of double sharings that are required for each
+// element of the batch in an instance of the exponentiation protocol with the
+// given exponent. This is equal to the number of multiplications that are
+// needed to compute the power of a single secret.
+//
+// Panics: This function will panic if the exponent is less than 2.
+func NumMasks(e secp256k1.Fn) int {
+	bits := exponentBits(e)
+	n := 0
+	accSet := false
+	for r := range bits {
+		if bits[r] == 1 {
+			if accSet {
+				n++
+			} else {
+				accSet = true
+			}
+		}
+		if r < len(bits)-1 {
+			n++
+		}
+	}
+	return n
+}
+
+// RaiseThreshold returns the high threshold halves of a batch of double
+// sharings, given the low threshold halves, which should be the output of an
+// instance of RNG with threshold k, and a batch of sharings of zero, which
+// should be the output of an instance of RZG with threshold 2k-1 that has not
+// been used for anything else. Each returned sharing is the sum of the
+// corresponding sharings of the two inputs, and so it is a sharing of the same
+// value as the low threshold sharing, with threshold 2k-1. Since the constant
+// terms of the commitments for RZG outputs are the point at infinity, the
+// constant terms of the commitments for the two halves of each double sharing
+// are the same, as is required by New.
+//
+// Panics: This function will panic if the batches do not all have the same
+// size, or if the RZG commitments do not have a threshold of 2k-1 for the
+// threshold k of the RNG commitments.
+func RaiseThreshold(
+	rngShareBatch, rzgShareBatch shamir.VerifiableShares,
+	rngCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+) (shamir.VerifiableShares, []shamir.Commitment) {
+	b := len(rngCommitmentBatch)
+	if len(rngShareBatch) != b || len(rzgShareBatch) != b || len(rzgCommitmentBatch) != b {
+		panic("inconsistent batch size")
+	}
+
+	shareBatch := make(shamir.VerifiableShares, b)
+	commitmentBatch := make([]shamir.Commitment, b)
+	for i := range shareBatch {
+		k := rngCommitmentBatch[i].Len()
+		if rzgCommitmentBatch[i].Len() != 2*k-1 {
+			panic(fmt.Sprintf(
+				"incorrect rzg k: expected %v, got %v", 2*k-1, rzgCommitmentBatch[i].Len(),
+			))
+		}
+		shareBatch[i].Add(&rngShareBatch[i], &rzgShareBatch[i])
+		commitmentBatch[i] = shamir.NewCommitmentWithCapacity(2*k - 1)
+		for j := 0; j < 2*k-1; j++ {
+			point := rzgCommitmentBatch[i][j]
+			if j < k {
+				point.Add(&point, &rngCommitmentBatch[i][j])
+			}
+			commitmentBatch[i].Append(point)
+		}
+	}
+	return shareBatch, commitmentBatch
+}
+
+// New returns a new Exponentiator state machine along with the initial
+// message that is to be broadcast to the other parties. The state machine will
+// handle this message before being returned.
+//
+// The rLow and rHigh inputs are the double sharings that are used to mask the
+// products: for each i, rLowShareBatch[i] and rHighShareBatch[i] should be
+// shares of the same random secret, with thresholds k and 2k-1 respectively.
+// The double sharings for the ith element of the batch should be at positions
+// i*m, ..., (i+1)*m - 1, where m = NumMasks(e). The constant terms of the
+// commitments for the two halves of each double sharing must be equal, which
+// shows that they are sharings of the same secret; this is the case for double
+// sharings created using RaiseThreshold.
+//
+// Panics: This function will panic if the exponent is less than 2, if the
+// constant terms of the commitments for any double sharing are not equal, or
+// if the inputs are not consistent (see mulopen.New).
+func New(
+	e secp256k1.Fn,
+	aShareBatch shamir.VerifiableShares, aCommitmentBatch []shamir.Commitment,
+	rLowShareBatch, rHighShareBatch shamir.VerifiableShares,
+	rLowCommitmentBatch, rHighCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (Exponentiator, Message) {
+	return NewWithRand(
+		random.Reader,
+		e, aShareBatch, aCommitmentBatch,
+		rLowShareBatch, rHighShareBatch,
+		rLowCommitmentBatch, rHighCommitmentBatch,
+		indices, h,
+	)
+}
+
+// NewWithRand is the same as New, except that the randomness for the messages
+// of every round is read from the given source. The source is kept by the
+// state machine, but it is not marshalled, and so an Exponentiator that has
+// been unmarshalled will use random.Reader instead.
+//
+// Panics: This function will panic in the same cases as New, or if the source
+// of randomness returns an error.
+func NewWithRand(
+	r io.Reader,
+	e secp256k1.Fn,
+	aShareBatch shamir.VerifiableShares, aCommitmentBatch []shamir.Commitment,
+	rLowShareBatch, rHighShareBatch shamir.VerifiableShares,
+	rLowCommitmentBatch, rHighCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (Exponentiator, Message) {
+	if !params.ValidPedersenParameter(h) {
+		panic("insecure choice of pedersen parameter")
+	}
+	b := len(aShareBatch)
+	if b < 1 {
+		panic(fmt.Sprintf("batch size should be at least 1: got %v", b))
+	}
+	if len(aCommitmentBatch) != b {
+		panic("inconsistent batch size")
+	}
+	m := NumMasks(e)
+	if len(rLowShareBatch) != b*m ||
+		len(rHighShareBatch) != b*m ||
+		len(rLowCommitmentBatch) != b*m ||
+		len(rHighCommitmentBatch) != b*m {
+		panic(fmt.Sprintf("incorrect number of double sharings: expected %v*%v = %v", b, m, b*m))
+	}
+	k := aCommitmentBatch[0].Len()
+	for i, com := range rLowCommitmentBatch {
+		if com.Len() != k {
+			panic(fmt.Sprintf("incorrect rLow k: expected %v, got %v", k, com.Len()))
+		}
+		if rHighCommitmentBatch[i].Len() != 2*k-1 {
+			panic(fmt.Sprintf("incorrect rHigh k: expected %v, got %v", 2*k-1, rHighCommitmentBatch[i].Len()))
+		}
+		if !com[0].Eq(&rHighCommitmentBatch[i][0]) {
+			panic(fmt.Sprintf("inconsistent double sharing at position %v", i))
+		}
+	}
+
+	copyShares := func(shares shamir.VerifiableShares) shamir.VerifiableShares {
+		sharesCopy := make(shamir.VerifiableShares, len(shares))
+		copy(sharesCopy, shares)
+		return sharesCopy
+	}
+	copyCommitments := func(coms []shamir.Commitment) []shamir.Commitment {
+		comsCopy := make([]shamir.Commitment, len(coms))
+		copy(comsCopy, coms)
+		return comsCopy
+	}
+	indicesCopy := make([]secp256k1.Fn, len(indices))
+	copy(indicesCopy, indices)
+
+	exponentiator := Exponentiator{
+		bits:                 exponentBits(e),
+		baseShareBatch:       copyShares(aShareBatch),
+		accShareBatch:        make(shamir.VerifiableShares, b),
+		baseCommitmentBatch:  copyCommitments(aCommitmentBatch),
+		accCommitmentBatch:   make([]shamir.Commitment, b),
+		rLowShareBatch:       copyShares(rLowShareBatch),
+		rHighShareBatch:      copyShares(rHighShareBatch),
+		rLowCommitmentBatch:  copyCommitments(rLowCommitmentBatch),
+		rHighCommitmentBatch: copyCommitments(rHighCommitmentBatch),
+		indices:              indicesCopy,
+		h:                    h,
+		rand:                 r,
+	}
+
+	// The exponent has at least two bits, so the first round always has a
+	// squaring step.
+	msg, ok := exponentiator.startRound()
+	if !ok {
+		panic("unreachable")
+	}
+
+	return exponentiator, msg
+}
+
+// HandleMessage applies a state transition upon receiving the given message
+// from another party. Messages for later rounds are buffered and handled once
+// the corresponding round starts, and messages for earlier rounds are
+// ignored.
+//
+// If the message completes the current round, the messages for the following
+// rounds are returned; these need to be broadcast to the other parties. More
+// than one message can be returned if buffered messages complete the
+// following rounds. Once the last round is complete, the output, i.e. the
+// shares and commitments that correspond to the powers of the input secrets,
+// is also returned. Otherwise, the output will be nil. If the message is
+// invalid in any way, an error will be returned along with nil values.
+//
+// If one of the buffered messages that are handled once the following rounds
+// start is invalid, it is dropped and the first such error is returned along
+// with the messages and output, which still need to be broadcast and used
+// respectively.
+func (exponentiator *Exponentiator) HandleMessage(msg Message) (
+	shamir.VerifiableShares, []shamir.Commitment, []Message, error,
+) {
+	if exponentiator.done() {
+		return nil, nil, nil, nil
+	}
+	if msg.Round >= uint32(len(exponentiator.bits)) {
+		return nil, nil, nil, ErrInvalidRound
+	}
+	if msg.Round < exponentiator.round {
+		return nil, nil, nil, nil
+	}
+	if msg.Round > exponentiator.round {
+		exponentiator.msgBuf = append(exponentiator.msgBuf, msg)
+		return nil, nil, nil, nil
+	}
+
+	complete, err := exponentiator.handleRoundMessage(msg.Messages)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !complete {
+		return nil, nil, nil, nil
+	}
+
+	var msgs []Message
+	var bufErr error
+	for !exponentiator.done() {
+		msg, ok := exponentiator.startRound()
+		if !ok {
+			continue
+		}
+		msgs = append(msgs, msg)
+		complete, err := exponentiator.handleBufferedMessages()
+		if bufErr == nil {
+			bufErr = err
+		}
+		if !complete {
+			return nil, nil, msgs, bufErr
+		}
+	}
+
+	shares := make(shamir.VerifiableShares, len(exponentiator.accShareBatch))
+	commitments := make([]shamir.Commitment, len(exponentiator.accCommitmentBatch))
+	copy(shares, exponentiator.accShareBatch)
+	copy(commitments, exponentiator.accCommitmentBatch)
+	return shares, commitments, msgs, bufErr
+}
+
+func (exponentiator *Exponentiator) done() bool {
+	return exponentiator.round >= uint32(len(exponentiator.bits))
+}
+
+// startRound sets up the multiplications for the current round and returns the
+// message for the round. If there are no multiplications to do in the round,
+// which can only happen for the last round, the protocol is completed and the
+// second return value will be false.
+func (exponentiator *Exponentiator) startRound() (Message, bool) {
+	r := exponentiator.round
+	last := r == uint32(len(exponentiator.bits)-1)
+
+	exponentiator.roundMul = false
+	exponentiator.roundSquare = !last
+	if exponentiator.bits[r] == 1 {
+		if exponentiator.accSet {
+			exponentiator.roundMul = true
+		} else {
+			// The accumulated result is currently 1, so the multiplication
+			// is trivial.
+			copy(exponentiator.accShareBatch, exponentiator.baseShareBatch)
+			copy(exponentiator.accCommitmentBatch, exponentiator.baseCommitmentBatch)
+			exponentiator.accSet = true
+		}
+	}
+
+	numOps := exponentiator.numOps()
+	if numOps == 0 {
+		exponentiator.round++
+		return Message{}, false
+	}
+
+	b := len(exponentiator.baseShareBatch)
+	m := len(exponentiator.rLowShareBatch) / b
+	l := b * numOps
+	aShareBatch := make(shamir.VerifiableShares, 0, l)
+	bShareBatch := make(shamir.VerifiableShares, 0, l)
+	rzgShareBatch := make(shamir.VerifiableShares, 0, l)
+	aCommitmentBatch := make([]shamir.Commitment, 0, l)
+	bCommitmentBatch := make([]shamir.Commitment, 0, l)
+	rzgCommitmentBatch := make([]shamir.Commitment, 0, l)
+	for i := 0; i < b; i++ {
+		for j := 0; j < numOps; j++ {
+			if j == 0 && exponentiator.roundMul {
+				aShareBatch = append(aShareBatch, exponentiator.accShareBatch[i])
+				aCommitmentBatch = append(aCommitmentBatch, exponentiator.accCommitmentBatch[i])
+			} else {
+				aShareBatch = append(aShareBatch, exponentiator.baseShareBatch[i])
+				aCommitmentBatch = append(aCommitmentBatch, exponentiator.baseCommitmentBatch[i])
+			}
+			bShareBatch = append(bShareBatch, exponentiator.baseShareBatch[i])
+			bCommitmentBatch = append(bCommitmentBatch, exponentiator.baseCommitmentBatch[i])
+			mask := i*m + int(exponentiator.maskOffset) + j
+			rzgShareBatch = append(rzgShareBatch, exponentiator.rHighShareBatch[mask])
+			rzgCommitmentBatch = append(rzgCommitmentBatch, exponentiator.rHighCommitmentBatch[mask])
+		}
+	}
+
+	rand := exponentiator.rand
+	if rand == nil {
+		rand = random.Reader
+	}
+	mulopener, msgs := mulopen.NewWithRand(
+		rand,
+		aShareBatch, bShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
+		exponentiator.indices, exponentiator.h,
+	)
+	exponentiator.mulopener = mulopener
+
+	return Message{Round: r, Messages: msgs}, true
+}
+
+// handleRoundMessage handles the given multiply and open messages for the
+// current round. The return value will be true if the round was completed.
+func (exponentiator *Exponentiator) handleRoundMessage(msgs []mulopen.Message) (bool, error) {
+	output, err := exponentiator.mulopener.HandleShareBatch(msgs)
+	if err != nil {
+		return false, err
+	}
+	if output == nil {
+		return false, nil
+	}
+	exponentiator.finishRound(output)
+	return true, nil
+}
+
+// handleBufferedMessages handles the buffered messages for the current round.
+// Invalid messages are dropped, as are any messages for the current round
+// that remain after the round is completed. The first return value will be
+// true if the round was completed, and the second is the error for the first
+// invalid message, if there was one.
+func (exponentiator *Exponentiator) handleBufferedMessages() (bool, error) {
+	r := exponentiator.round
+	buf := exponentiator.msgBuf
+	exponentiator.msgBuf = make([]Message, 0, len(buf))
+	complete := false
+	var firstErr error
+	for _, msg := range buf {
+		if msg.Round > r {
+			exponentiator.msgBuf = append(exponentiator.msgBuf, msg)
+			continue
+		}
+		if complete || msg.Round < r {
+			continue
+		}
+		var err error
+		complete, err = exponentiator.handleRoundMessage(msg.Messages)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return complete, firstErr
+}
+
+// finishRound computes the shares and commitments of the products from the
+// opened masked products, and updates the base and accumulated result.
+func (exponentiator *Exponentiator) finishRound(output []secp256k1.Fn) {
+	numOps := exponentiator.numOps()
+	b := len(exponentiator.baseShareBatch)
+	m := len(exponentiator.rLowShareBatch) / b
+	for i := 0; i < b; i++ {
+		for j := 0; j < numOps; j++ {
+			p := i*numOps + j
+			mask := i*m + int(exponentiator.maskOffset) + j
+			share, com := unmask(
+				&output[p],
+				&exponentiator.rLowShareBatch[mask], exponentiator.rLowCommitmentBatch[mask],
+			)
+			if j == 0 && exponentiator.roundMul {
+				exponentiator.accShareBatch[i] = share
+				exponentiator.accCommitmentBatch[i] = com
+			} else {
+				exponentiator.baseShareBatch[i] = share
+				exponentiator.baseCommitmentBatch[i] = com
+			}
+		}
+	}
+	exponentiator.maskOffset += uint32(numOps)
+	exponentiator.round++
+}
+
+func (exponentiator *Exponentiator) numOps() int {
+	numOps := 0
+	if exponentiator.roundMul {
+		numOps++
+	}
+	if exponentiator.roundSquare {
+		numOps++
+	}
+	return numOps
+}
+
+// unmask computes the share and commitment of the product from the opened
+// masked product c and the share and commitment for the threshold k sharing of
+// the mask. Since c is public, it is committed to with a decommitment of zero,
+// so that all parties compute the same commitment.
+func unmask(
+	c *secp256k1.Fn,
+	rLowShare *shamir.VerifiableShare, rLowCommitment shamir.Commitment,
+) (shamir.VerifiableShare, shamir.Commitment) {
+	var share shamir.VerifiableShare
+	share.Share.Index = rLowShare.Share.Index
+	share.Share.Value.Negate(&rLowShare.Share.Value)
+	share.Share.Value.Add(&share.Share.Value, c)
+	share.Decommitment.Negate(&rLowShare.Decommitment)
+
+	var minusOne secp256k1.Fn
+	minusOne.SetU16(1)
+	minusOne.Negate(&minusOne)
+	com := shamir.NewCommitmentWithCapacity(rLowCommitment.Len())
+	com.Scale(rLowCommitment, &minusOne)
+
+	var cCom secp256k1.Point
+	cCom.BaseExp(c)
+	com[0].Add(&com[0], &cCom)
+
+	return share, com
+}
+
+// exponentBits returns the bits of the given exponent, starting with the least
+// significant bit.
+func exponentBits(e secp256k1.Fn) []byte {
+	var bs [32]byte
+	e.PutB32(bs[:])
+	bits := make([]byte, 0, 256)
+	for i := len(bs) - 1; i >= 0; i-- {
+		for j := uint(0); j < 8; j++ {
+			bits = append(bits, (bs[i]>>j)&1)
+		}
+	}
+	for len(bits) > 0 && bits[len(bits)-1] == 0 {
+		bits = bits[:len(bits)-1]
+	}
+	if len(bits) < 2 {
+		panic("exponent should be at least 2")
+	}
+	return bits
+}
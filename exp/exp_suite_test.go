@@ -0,0 +1,13 @@
+package exp_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestExp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Exp Suite")
+}
@@ -0,0 +1,279 @@
+package exp_test
+
+import (
+	"fmt"
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/exp"
+
+	"github.com/renproject/mpc/exp/exputil"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+)
+
+var _ = Describe("Exponentiation", func() {
+	// Pow computes a^e for a public a.
+	Pow := func(a secp256k1.Fn, e uint16) secp256k1.Fn {
+		res := secp256k1.NewFnFromU16(1)
+		for i := uint16(0); i < e; i++ {
+			res.Mul(&res, &a)
+		}
+		return res
+	}
+
+	Context("number of masks", func() {
+		Specify("the number of masks should be the number of multiplications", func() {
+			cases := []struct {
+				e   uint16
+				num int
+			}{
+				{2, 1}, {3, 2}, {4, 2}, {5, 3}, {7, 4}, {8, 3}, {255, 14},
+			}
+			for _, c := range cases {
+				Expect(NumMasks(secp256k1.NewFnFromU16(c.e))).To(Equal(c.num))
+			}
+		})
+
+		Specify("exponents less than 2 should panic", func() {
+			Expect(func() { NumMasks(secp256k1.NewFnFromU16(0)) }).To(Panic())
+			Expect(func() { NumMasks(secp256k1.NewFnFromU16(1)) }).To(Panic())
+		})
+	})
+
+	Context("double sharings", func() {
+		n := 10
+		k := 3
+		b := 4
+
+		Specify("raised sharings should be sharings of the same secrets with threshold 2k-1", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+			rngShares, rngCommitments, secrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+
+			highShares := make([]shamir.VerifiableShares, n)
+			var highCommitments []shamir.Commitment
+			for i := range indices {
+				highShares[i], highCommitments = RaiseThreshold(
+					rngShares[i], rzgShares[i], rngCommitments, rzgCommitments,
+				)
+			}
+
+			for j := 0; j < b; j++ {
+				Expect(highCommitments[j].Len()).To(Equal(2*k - 1))
+				Expect(highCommitments[j][0].Eq(&rngCommitments[j][0])).To(BeTrue())
+				vshares := make(shamir.VerifiableShares, n)
+				shares := make(shamir.Shares, n)
+				for i := range indices {
+					Expect(shamir.IsValid(h, &highCommitments[j], &highShares[i][j])).To(BeTrue())
+					vshares[i] = highShares[i][j]
+					shares[i] = highShares[i][j].Share
+				}
+				Expect(shamirutil.VsharesAreConsistent(vshares, 2*k-1)).To(BeTrue())
+				secret := shamir.Open(shares)
+				Expect(secret.Eq(&secrets[j])).To(BeTrue())
+			}
+		})
+
+		Specify("inconsistent double sharings should panic", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+			exponent := secp256k1.NewFnFromU16(3)
+			m := NumMasks(exponent)
+			aShares, aCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, 1, h)
+
+			// Independent sharings of the same secret have different
+			// constant terms in their commitments.
+			lowShares := make(shamir.VerifiableShares, m)
+			highShares := make(shamir.VerifiableShares, m)
+			lowCommitments := make([]shamir.Commitment, m)
+			highCommitments := make([]shamir.Commitment, m)
+			for j := 0; j < m; j++ {
+				secret := secp256k1.RandomFn()
+				low, lowCom := rkpgutil.RXGOutput(indices, k, h, secret)
+				high, highCom := rkpgutil.RXGOutput(indices, 2*k-1, h, secret)
+				lowShares[j], highShares[j] = low[0], high[0]
+				lowCommitments[j], highCommitments[j] = lowCom, highCom
+			}
+
+			Expect(func() {
+				New(
+					exponent, aShares[0], aCommitments,
+					lowShares, highShares, lowCommitments, highCommitments,
+					indices, h,
+				)
+			}).To(Panic())
+		})
+	})
+
+	Context("handling messages", func() {
+		n := 10
+		k := 3
+
+		Specify("errors for invalid buffered messages should be returned", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+			exponent := secp256k1.NewFnFromU16(3)
+			m := NumMasks(exponent)
+			aShares, aCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, 1, h)
+			rLowShares, rHighShares, rLowCommitments, rHighCommitments :=
+				exputil.DoubleSharingBatch(indices, k, m, h)
+
+			exponentiators := make([]Exponentiator, n)
+			messages := make([]Message, n)
+			for i := range exponentiators {
+				exponentiators[i], messages[i] = New(
+					exponent, aShares[i], aCommitments,
+					rLowShares[i], rHighShares[i], rLowCommitments, rHighCommitments,
+					indices, h,
+				)
+			}
+
+			// A message for the second round that contains the messages for
+			// the first round is buffered, and is invalid once it is handled.
+			invalid := Message{Round: 1, Messages: messages[1].Messages}
+			_, _, msgs, err := exponentiators[0].HandleMessage(invalid)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msgs).To(BeNil())
+
+			for i := 1; i < n; i++ {
+				_, _, msgs, err = exponentiators[0].HandleMessage(messages[i])
+				if msgs != nil {
+					break
+				}
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(msgs).To(HaveLen(1))
+			Expect(msgs[0].Round).To(Equal(uint32(1)))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("seeded randomness", func() {
+		n := 10
+		k := 3
+
+		Specify("messages for every round created from the same seed should be the same", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+			exponent := secp256k1.NewFnFromU16(3)
+			m := NumMasks(exponent)
+			aShares, aCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, 1, h)
+			rLowShares, rHighShares, rLowCommitments, rHighCommitments :=
+				exputil.DoubleSharingBatch(indices, k, m, h)
+
+			messages := make([]Message, n)
+			for i := 1; i < n; i++ {
+				_, messages[i] = New(
+					exponent, aShares[i], aCommitments,
+					rLowShares[i], rHighShares[i], rLowCommitments, rHighCommitments,
+					indices, h,
+				)
+			}
+
+			// The messages of the first player for both rounds of the
+			// protocol, which needs to keep the source of randomness for the
+			// second round.
+			messagesWithSeed := func(seed int64) []Message {
+				exponentiator, msg := NewWithRand(
+					rand.New(rand.NewSource(seed)),
+					exponent, aShares[0], aCommitments,
+					rLowShares[0], rHighShares[0], rLowCommitments, rHighCommitments,
+					indices, h,
+				)
+				msgs := []Message{msg}
+				for i := 1; i < n; i++ {
+					_, _, roundMsgs, err := exponentiator.HandleMessage(messages[i])
+					Expect(err).ToNot(HaveOccurred())
+					msgs = append(msgs, roundMsgs...)
+				}
+				Expect(msgs).To(HaveLen(2))
+				return msgs
+			}
+
+			seed := rand.Int63()
+			msgs := messagesWithSeed(seed)
+			Expect(messagesWithSeed(seed)).To(Equal(msgs))
+			other := messagesWithSeed(seed + 1)
+			Expect(other[0]).ToNot(Equal(msgs[0]))
+			Expect(other[1]).ToNot(Equal(msgs[1]))
+		})
+	})
+
+	Context("network", func() {
+		n := 10
+		k := 3
+		b := 2
+		t := k - 1
+
+		for _, e := range []uint16{2, 3, 8, uint16(rand.Intn(250) + 4)} {
+			e := e
+
+			Specify(fmt.Sprintf("all honest nodes should compute shares of the %vth power of the secrets", e), func() {
+				indices := shamirutil.RandomIndices(n)
+				h := secp256k1.RandomPoint()
+				exponent := secp256k1.NewFnFromU16(e)
+				m := NumMasks(exponent)
+
+				aShares, aCommitments, aSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+				rLowShares, rHighShares, rLowCommitments, rHighCommitments :=
+					exputil.DoubleSharingBatch(indices, k, b*m, h)
+
+				ids := make([]mpcutil.ID, n)
+				for i := range ids {
+					ids[i] = mpcutil.ID(i + 1)
+				}
+				machines := make([]mpcutil.Machine, n)
+				honestMachines := make([]*exputil.Machine, 0, n-t)
+				for i, id := range ids {
+					if i < t {
+						m := mpcutil.OfflineMachine(id)
+						machines[i] = &m
+						continue
+					}
+					machine := exputil.NewMachine(
+						exponent, aShares[i], aCommitments,
+						rLowShares[i], rHighShares[i],
+						rLowCommitments, rHighCommitments,
+						ids, id, indices, h,
+					)
+					machines[i] = &machine
+					honestMachines = append(honestMachines, &machine)
+				}
+
+				shuffleMsgs, _ := mpcutil.MessageShufflerDropper(ids, 0)
+				network := mpcutil.NewNetwork(machines, shuffleMsgs)
+				network.SetCaptureHist(true)
+				err := network.Run()
+				Expect(err).ToNot(HaveOccurred())
+
+				for i := 0; i < b; i++ {
+					pow := Pow(aSecrets[i], e)
+
+					shares := make(shamir.Shares, 0, n-t)
+					vshares := make(shamir.VerifiableShares, 0, n-t)
+					commitment := honestMachines[0].OutputCommitments[i]
+					for _, machine := range honestMachines {
+						Expect(machine.OutputCommitments[i].Eq(commitment)).To(BeTrue())
+						vshares = append(vshares, machine.OutputShares[i])
+						shares = append(shares, machine.OutputShares[i].Share)
+					}
+
+					Expect(shamirutil.VsharesAreConsistent(vshares, k-1)).To(BeFalse())
+					Expect(shamirutil.VsharesAreConsistent(vshares, k)).To(BeTrue())
+					for _, vshare := range vshares {
+						Expect(shamir.IsValid(h, &commitment, &vshare)).To(BeTrue())
+					}
+
+					secret := shamir.Open(shares)
+					Expect(secret.Eq(&pow)).To(BeTrue())
+				}
+			})
+		}
+	})
+})
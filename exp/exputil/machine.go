@@ -0,0 +1,178 @@
+package exputil
+
+import (
+	"io"
+
+	"github.com/renproject/mpc/exp"
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+// Machine represents a player that honestly carries out the exponentiation
+// protocol.
+type Machine struct {
+	OwnID mpcutil.ID
+	IDs   []mpcutil.ID
+	exp.Exponentiator
+	InitMsgs          []Message
+	OutputShares      shamir.VerifiableShares
+	OutputCommitments []shamir.Commitment
+}
+
+// NewMachine constructs a new honest machine for an exponentiation network
+// test. It will have the given inputs and ID.
+func NewMachine(
+	e secp256k1.Fn,
+	aShareBatch shamir.VerifiableShares, aCommitmentBatch []shamir.Commitment,
+	rLowShareBatch, rHighShareBatch shamir.VerifiableShares,
+	rLowCommitmentBatch, rHighCommitmentBatch []shamir.Commitment,
+	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
+) Machine {
+	return NewMachineWithRand(
+		random.Reader,
+		e, aShareBatch, aCommitmentBatch,
+		rLowShareBatch, rHighShareBatch,
+		rLowCommitmentBatch, rHighCommitmentBatch,
+		ids, ownID, indices, h,
+	)
+}
+
+// NewMachineWithRand is the same as NewMachine, except that the randomness for
+// the Exponentiator is read from the given source.
+func NewMachineWithRand(
+	r io.Reader,
+	e secp256k1.Fn,
+	aShareBatch shamir.VerifiableShares, aCommitmentBatch []shamir.Commitment,
+	rLowShareBatch, rHighShareBatch shamir.VerifiableShares,
+	rLowCommitmentBatch, rHighCommitmentBatch []shamir.Commitment,
+	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
+) Machine {
+	exponentiator, msg := exp.NewWithRand(
+		r,
+		e, aShareBatch, aCommitmentBatch,
+		rLowShareBatch, rHighShareBatch,
+		rLowCommitmentBatch, rHighCommitmentBatch,
+		indices, h,
+	)
+	m := Machine{
+		OwnID:         ownID,
+		IDs:           ids,
+		Exponentiator: exponentiator,
+	}
+	m.InitMsgs = m.broadcast(msg)
+	return m
+}
+
+// ID implements the Machine interface.
+func (m Machine) ID() mpcutil.ID { return m.OwnID }
+
+// InitialMessages implements the Machine interface.
+func (m Machine) InitialMessages() []mpcutil.Message {
+	msgs := make([]mpcutil.Message, len(m.InitMsgs))
+	for i := range m.InitMsgs {
+		msgs[i] = &m.InitMsgs[i]
+	}
+	return msgs
+}
+
+// Handle implements the Machine interface.
+func (m *Machine) Handle(msg mpcutil.Message) []mpcutil.Message {
+	outputShares, outputCommitments, expMsgs, _ := m.Exponentiator.HandleMessage(msg.(*Message).Message)
+	if outputShares != nil && outputCommitments != nil {
+		m.OutputShares = outputShares
+		m.OutputCommitments = outputCommitments
+	}
+	var msgs []mpcutil.Message
+	for _, expMsg := range expMsgs {
+		for _, msg := range m.broadcast(expMsg) {
+			msg := msg
+			msgs = append(msgs, &msg)
+		}
+	}
+	return msgs
+}
+
+// Done implements the mpcutil.Terminator interface.
+func (m Machine) Done() bool { return len(m.OutputCommitments) != 0 }
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of shares of the exponentiated values.
+func (m Machine) Result() interface{} { return m.OutputShares }
+
+func (m Machine) broadcast(msg exp.Message) []Message {
+	msgs := make([]Message, 0, len(m.IDs)-1)
+	for _, id := range m.IDs {
+		if id == m.OwnID {
+			continue
+		}
+		msgs = append(msgs, Message{
+			FromID:  m.OwnID,
+			ToID:    id,
+			Message: msg,
+		})
+	}
+	return msgs
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (m Machine) SizeHint() int {
+	return m.OwnID.SizeHint() +
+		surge.SizeHint(m.IDs) +
+		m.Exponentiator.SizeHint() +
+		surge.SizeHint(m.InitMsgs) +
+		surge.SizeHint(m.OutputShares) +
+		surge.SizeHint(m.OutputCommitments)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (m Machine) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.OwnID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.IDs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.Exponentiator.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.InitMsgs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.OutputShares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(m.OutputCommitments, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (m *Machine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.OwnID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.IDs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.Exponentiator.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.InitMsgs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.OutputShares, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&m.OutputCommitments, buf, rem)
+}
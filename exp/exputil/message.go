@@ -0,0 +1,52 @@
+package exputil
+
+import (
+	"github.com/renproject/mpc/exp"
+	"github.com/renproject/mpc/mpcutil"
+)
+
+// Message is the message type that players send to eachother during an
+// instance of exponentiation.
+type Message struct {
+	FromID, ToID mpcutil.ID
+	Message      exp.Message
+}
+
+// From implements the mpcutil.Message interface.
+func (msg Message) From() mpcutil.ID { return msg.FromID }
+
+// To implements the mpcutil.Message interface.
+func (msg Message) To() mpcutil.ID { return msg.ToID }
+
+// SizeHint implements the surge.SizeHinter interface.
+func (msg Message) SizeHint() int {
+	return msg.FromID.SizeHint() +
+		msg.ToID.SizeHint() +
+		msg.Message.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (msg Message) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.FromID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.ToID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return msg.Message.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (msg *Message) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.FromID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.ToID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return msg.Message.Unmarshal(buf, rem)
+}
@@ -0,0 +1,33 @@
+package exputil
+
+import (
+	"github.com/renproject/mpc/exp"
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// DoubleSharingBatch returns a batch of b random double sharings, i.e. pairs
+// of sharings of the same random secret with thresholds k and 2k-1, created
+// from random RNG and RZG outputs using exp.RaiseThreshold. In the returned
+// shares, shares[i] are the shares for player i and has length equal to the
+// batch size.
+func DoubleSharingBatch(
+	indices []secp256k1.Fn,
+	k, b int,
+	h secp256k1.Point,
+) (
+	lowShares, highShares []shamir.VerifiableShares,
+	lowCommitments, highCommitments []shamir.Commitment,
+) {
+	lowShares, lowCommitments, _ = rkpgutil.RNGOutputBatch(indices, k, b, h)
+	zeroShares, zeroCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+	highShares = make([]shamir.VerifiableShares, len(indices))
+	for i := range indices {
+		highShares[i], highCommitments = exp.RaiseThreshold(
+			lowShares[i], zeroShares[i],
+			lowCommitments, zeroCommitments,
+		)
+	}
+	return lowShares, highShares, lowCommitments, highCommitments
+}
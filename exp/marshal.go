@@ -0,0 +1,273 @@
+package exp
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/renproject/mpc/mulopen"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+	"github.com/renproject/surge"
+)
+
+// SizeHint implements the surge.SizeHinter interface.
+func (exponentiator Exponentiator) SizeHint() int {
+	return exponentiator.mulopener.SizeHint() +
+		surge.SizeHint(exponentiator.msgBuf) +
+		surge.SizeHint(exponentiator.bits) +
+		surge.SizeHint(exponentiator.round) +
+		surge.SizeHint(exponentiator.maskOffset) +
+		surge.SizeHint(exponentiator.accSet) +
+		surge.SizeHint(exponentiator.roundMul) +
+		surge.SizeHint(exponentiator.roundSquare) +
+		surge.SizeHint(exponentiator.baseShareBatch) +
+		surge.SizeHint(exponentiator.accShareBatch) +
+		surge.SizeHint(exponentiator.baseCommitmentBatch) +
+		surge.SizeHint(exponentiator.accCommitmentBatch) +
+		surge.SizeHint(exponentiator.rLowShareBatch) +
+		surge.SizeHint(exponentiator.rHighShareBatch) +
+		surge.SizeHint(exponentiator.rLowCommitmentBatch) +
+		surge.SizeHint(exponentiator.rHighCommitmentBatch) +
+		surge.SizeHint(exponentiator.indices) +
+		exponentiator.h.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (exponentiator Exponentiator) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := exponentiator.mulopener.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.msgBuf, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.bits, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(exponentiator.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalU32(exponentiator.maskOffset, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(exponentiator.accSet, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(exponentiator.roundMul, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(exponentiator.roundSquare, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.baseShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.accShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.baseCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.accCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.rLowShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.rHighShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.rLowCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.rHighCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(exponentiator.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return exponentiator.h.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (exponentiator *Exponentiator) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := exponentiator.mulopener.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.msgBuf, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.bits, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&exponentiator.round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalU32(&exponentiator.maskOffset, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&exponentiator.accSet, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&exponentiator.roundMul, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&exponentiator.roundSquare, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.baseShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.accShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.baseCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.accCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.rLowShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.rHighShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.rLowCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.rHighCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&exponentiator.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return exponentiator.h.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (exponentiator Exponentiator) Generate(rand *rand.Rand, size int) reflect.Value {
+	size /= 4
+	b := rand.Intn(2) + 1
+	m := rand.Intn(2) + 1
+	mulopener := mulopen.MulOpener{}.Generate(rand, size).Interface().(mulopen.MulOpener)
+	msgBuf := make([]Message, rand.Intn(2))
+	for i := range msgBuf {
+		msgBuf[i] = Message{}.Generate(rand, size).Interface().(Message)
+	}
+	bits := make([]byte, rand.Intn(size)+2)
+	for i := range bits {
+		bits[i] = byte(rand.Intn(2))
+	}
+	bits[len(bits)-1] = 1
+	randomShareBatch := func(l int) shamir.VerifiableShares {
+		shareBatch := make(shamir.VerifiableShares, l)
+		for i := range shareBatch {
+			shareBatch[i] = shamir.VerifiableShare{
+				Share: shamir.Share{
+					Index: secp256k1.RandomFn(),
+					Value: secp256k1.RandomFn(),
+				},
+				Decommitment: secp256k1.RandomFn(),
+			}
+		}
+		return shareBatch
+	}
+	randomCommitmentBatch := func(l int) []shamir.Commitment {
+		commitmentBatch := make([]shamir.Commitment, l)
+		for i := range commitmentBatch {
+			commitmentBatch[i] = shamir.Commitment{}.Generate(rand, 3).Interface().(shamir.Commitment)
+		}
+		return commitmentBatch
+	}
+	exp := Exponentiator{
+		mulopener:            mulopener,
+		msgBuf:               msgBuf,
+		bits:                 bits,
+		round:                uint32(rand.Intn(len(bits))),
+		maskOffset:           uint32(rand.Intn(m)),
+		accSet:               rand.Int()&1 == 1,
+		roundMul:             rand.Int()&1 == 1,
+		roundSquare:          rand.Int()&1 == 1,
+		baseShareBatch:       randomShareBatch(b),
+		accShareBatch:        randomShareBatch(b),
+		baseCommitmentBatch:  randomCommitmentBatch(b),
+		accCommitmentBatch:   randomCommitmentBatch(b),
+		rLowShareBatch:       randomShareBatch(b * m),
+		rHighShareBatch:      randomShareBatch(b * m),
+		rLowCommitmentBatch:  randomCommitmentBatch(b * m),
+		rHighCommitmentBatch: randomCommitmentBatch(b * m),
+		indices:              shamirutil.RandomIndices(rand.Intn(3) + 1),
+		h:                    secp256k1.RandomPoint(),
+	}
+	return reflect.ValueOf(exp)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (msg Message) SizeHint() int {
+	return surge.SizeHint(msg.Round) + surge.SizeHint(msg.Messages)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (msg Message) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.MarshalU32(msg.Round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(msg.Messages, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (msg *Message) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := surge.UnmarshalU32(&msg.Round, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&msg.Messages, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (msg Message) Generate(rand *rand.Rand, size int) reflect.Value {
+	msgs := make([]mulopen.Message, rand.Intn(2)+1)
+	for i := range msgs {
+		msgs[i] = mulopen.Message{}.Generate(rand, size).Interface().(mulopen.Message)
+	}
+	return reflect.ValueOf(Message{
+		Round:    rand.Uint32(),
+		Messages: msgs,
+	})
+}
@@ -0,0 +1,11 @@
+package exp
+
+import "github.com/renproject/mpc/mulopen"
+
+// The Message type that is sent between parties during an invocation of the
+// exponentiation protocol. It contains the multiply and open messages for the
+// given round.
+type Message struct {
+	Round    uint32
+	Messages []mulopen.Message
+}
@@ -0,0 +1,20 @@
+package inv
+
+import "fmt"
+
+// ZeroProductError is returned when the opened product of the input and the
+// random multiplier is zero for some of the elements in the batch. The
+// inverse can not be computed for these elements, and they need to be retried
+// with fresh random inputs (see Inverter.Retry). The output for the remaining
+// elements in the batch is retained, and will be returned once all of the
+// retried elements have been successfully inverted.
+type ZeroProductError struct {
+	// Indices contains the positions in the original batch of the elements
+	// for which the opened product was zero.
+	Indices []int
+}
+
+// Error implements the error interface.
+func (err ZeroProductError) Error() string {
+	return fmt.Sprintf("zero product for batch elements %v", err.Indices)
+}
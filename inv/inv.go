@@ -1,17 +1,42 @@
 package inv
 
 import (
+	"io"
+
 	"github.com/renproject/mpc/mulopen"
 	"github.com/renproject/mpc/params"
+	"github.com/renproject/mpc/random"
 	"github.com/renproject/secp256k1"
 	"github.com/renproject/shamir"
 )
 
 // An Inverter is a state machine that implements the inversion protocol.
+//
+// The inversion protocol opens the product of the input and a random
+// multiplier, and so it will fail for a given batch element if this product
+// is zero. This happens when the input is zero, in which case there is no
+// inverse, or, with negligible probability, when the random multiplier is
+// zero. When this happens the elements with a zero product are reported using
+// a ZeroProductError, and they can be rerun with fresh random multipliers
+// using Retry while the output for the rest of the batch is kept. If the
+// product is zero again after a retry, then with overwhelming probability the
+// input is zero.
 type Inverter struct {
-	mulopener        mulopen.MulOpener
-	rShareBatch      shamir.VerifiableShares
-	rCommitmentBatch []shamir.Commitment
+	mulopener mulopen.MulOpener
+
+	// pending contains the positions in the batch of the elements that are
+	// currently being inverted by the mulopener.
+	pending []uint32
+
+	aShareBatch        shamir.VerifiableShares
+	aCommitmentBatch   []shamir.Commitment
+	rShareBatch        shamir.VerifiableShares
+	rCommitmentBatch   []shamir.Commitment
+	invShareBatch      shamir.VerifiableShares
+	invCommitmentBatch []shamir.Commitment
+
+	indices []secp256k1.Fn
+	h       secp256k1.Point
 }
 
 // New returns a new Inverter state machine along with the initial message that
@@ -21,23 +46,61 @@ func New(
 	aShareBatch, rShareBatch, rzgShareBatch shamir.VerifiableShares,
 	aCommitmentBatch, rCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
 	indices []secp256k1.Fn, h secp256k1.Point,
+) (Inverter, []mulopen.Message) {
+	return NewWithRand(
+		random.Reader,
+		aShareBatch, rShareBatch, rzgShareBatch,
+		aCommitmentBatch, rCommitmentBatch, rzgCommitmentBatch,
+		indices, h,
+	)
+}
+
+// NewWithRand is the same as New, except that the randomness for the initial
+// message is read from the given source.
+//
+// Panics: This function will panic in the same cases as New, or if the source
+// of randomness returns an error.
+func NewWithRand(
+	r io.Reader,
+	aShareBatch, rShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, rCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
 ) (Inverter, []mulopen.Message) {
 	if !params.ValidPedersenParameter(h) {
 		panic("insecure choice of pedersen parameter")
 	}
+	b := len(aShareBatch)
+	aShareBatchCopy := make(shamir.VerifiableShares, b)
+	aCommitmentBatchCopy := make([]shamir.Commitment, len(aCommitmentBatch))
 	rShareBatchCopy := make(shamir.VerifiableShares, len(rShareBatch))
 	rCommitmentBatchCopy := make([]shamir.Commitment, len(rCommitmentBatch))
+	copy(aShareBatchCopy, aShareBatch)
+	copy(aCommitmentBatchCopy, aCommitmentBatch)
 	copy(rShareBatchCopy, rShareBatch)
 	copy(rCommitmentBatchCopy, rCommitmentBatch)
-	mulopener, messages := mulopen.New(
+	indicesCopy := make([]secp256k1.Fn, len(indices))
+	copy(indicesCopy, indices)
+	mulopener, messages := mulopen.NewWithRand(
+		r,
 		aShareBatch, rShareBatch, rzgShareBatch,
 		aCommitmentBatch, rCommitmentBatch, rzgCommitmentBatch,
 		indices, h,
 	)
+	pending := make([]uint32, b)
+	for i := range pending {
+		pending[i] = uint32(i)
+	}
 	inverter := Inverter{
-		mulopener:        mulopener,
-		rShareBatch:      rShareBatchCopy,
-		rCommitmentBatch: rCommitmentBatchCopy,
+		mulopener:          mulopener,
+		pending:            pending,
+		aShareBatch:        aShareBatchCopy,
+		aCommitmentBatch:   aCommitmentBatchCopy,
+		rShareBatch:        rShareBatchCopy,
+		rCommitmentBatch:   rCommitmentBatchCopy,
+		invShareBatch:      make(shamir.VerifiableShares, b),
+		invCommitmentBatch: make([]shamir.Commitment, b),
+		indices:            indicesCopy,
+		h:                  h,
 	}
 	return inverter, messages
 }
@@ -50,6 +113,12 @@ func New(
 // computed and returned. If not enough messages have been received, the return
 // value will be nil. If the message batch is invalid in any way, an error will
 // be returned along with a nil value.
+//
+// If the opened product is zero for any of the elements in the batch, a
+// ZeroProductError that identifies these elements is returned along with a nil
+// value. The output for the other elements is kept, and the full output will
+// be returned once the failed elements have been successfully retried (see
+// Retry).
 func (inverter *Inverter) HandleMulOpenMessageBatch(messageBatch []mulopen.Message) (
 	shamir.VerifiableShares, []shamir.Commitment, error,
 ) {
@@ -57,17 +126,94 @@ func (inverter *Inverter) HandleMulOpenMessageBatch(messageBatch []mulopen.Messa
 	if err != nil {
 		return nil, nil, err
 	}
-	if output != nil {
-		var inv secp256k1.Fn
-		invShares := make(shamir.VerifiableShares, len(inverter.rShareBatch))
-		invCommitments := make([]shamir.Commitment, len(inverter.rCommitmentBatch))
-		for i := range output {
-			invCommitments[i] = shamir.NewCommitmentWithCapacity(inverter.rCommitmentBatch[0].Len())
-			inv.Inverse(&output[i])
-			invShares[i].Scale(&inverter.rShareBatch[i], &inv)
-			invCommitments[i].Scale(inverter.rCommitmentBatch[i], &inv)
+	if output == nil {
+		return nil, nil, nil
+	}
+
+	var inv secp256k1.Fn
+	var failed []uint32
+	for j, i := range inverter.pending {
+		if output[j].IsZero() {
+			failed = append(failed, i)
+			continue
 		}
-		return invShares, invCommitments, nil
+		inv.Inverse(&output[j])
+		inverter.invCommitmentBatch[i] = shamir.NewCommitmentWithCapacity(inverter.rCommitmentBatch[i].Len())
+		inverter.invShareBatch[i].Scale(&inverter.rShareBatch[i], &inv)
+		inverter.invCommitmentBatch[i].Scale(inverter.rCommitmentBatch[i], &inv)
 	}
-	return nil, nil, nil
+	inverter.pending = failed
+	if len(failed) != 0 {
+		indices := make([]int, len(failed))
+		for j, i := range failed {
+			indices[j] = int(i)
+		}
+		return nil, nil, ZeroProductError{Indices: indices}
+	}
+
+	invShares := make(shamir.VerifiableShares, len(inverter.invShareBatch))
+	invCommitments := make([]shamir.Commitment, len(inverter.invCommitmentBatch))
+	copy(invShares, inverter.invShareBatch)
+	copy(invCommitments, inverter.invCommitmentBatch)
+	return invShares, invCommitments, nil
+}
+
+// Retry restarts the inversion protocol for the elements of the batch that
+// were reported in the last ZeroProductError, using the given fresh random
+// multipliers and RZG sharings. The given batches should contain one element
+// for each of the failed elements, in the same order as they appear in the
+// error. The returned messages are the initial messages for the new multiply
+// and open step, and should be broadcast to the other parties; subsequent
+// messages should be handled using HandleMulOpenMessageBatch as before.
+//
+// Panics: This function will panic if there are no failed elements to retry,
+// or if the given batches do not have the same length as the number of failed
+// elements.
+func (inverter *Inverter) Retry(
+	rShareBatch, rzgShareBatch shamir.VerifiableShares,
+	rCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+) []mulopen.Message {
+	return inverter.RetryWithRand(
+		random.Reader,
+		rShareBatch, rzgShareBatch,
+		rCommitmentBatch, rzgCommitmentBatch,
+	)
+}
+
+// RetryWithRand is the same as Retry, except that the randomness for the new
+// initial messages is read from the given source.
+//
+// Panics: This function will panic in the same cases as Retry, or if the
+// source of randomness returns an error.
+func (inverter *Inverter) RetryWithRand(
+	r io.Reader,
+	rShareBatch, rzgShareBatch shamir.VerifiableShares,
+	rCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+) []mulopen.Message {
+	b := len(inverter.pending)
+	if b == 0 {
+		panic("no failed elements to retry")
+	}
+	if len(rShareBatch) != b || len(rzgShareBatch) != b ||
+		len(rCommitmentBatch) != b || len(rzgCommitmentBatch) != b {
+		panic("batch size does not match the number of failed elements")
+	}
+
+	aShareBatch := make(shamir.VerifiableShares, b)
+	aCommitmentBatch := make([]shamir.Commitment, b)
+	for j, i := range inverter.pending {
+		aShareBatch[j] = inverter.aShareBatch[i]
+		aCommitmentBatch[j] = inverter.aCommitmentBatch[i]
+		inverter.rShareBatch[i] = rShareBatch[j]
+		inverter.rCommitmentBatch[i] = rCommitmentBatch[j]
+	}
+
+	mulopener, messages := mulopen.NewWithRand(
+		r,
+		aShareBatch, rShareBatch, rzgShareBatch,
+		aCommitmentBatch, rCommitmentBatch, rzgCommitmentBatch,
+		inverter.indices, inverter.h,
+	)
+	inverter.mulopener = mulopener
+	return messages
 }
@@ -6,8 +6,11 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	. "github.com/renproject/mpc/inv"
+
 	"github.com/renproject/mpc/inv/invutil"
 	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/mulopen"
 	"github.com/renproject/mpc/rkpg/rkpgutil"
 	"github.com/renproject/secp256k1"
 	"github.com/renproject/shamir"
@@ -15,6 +18,191 @@ import (
 )
 
 var _ = Describe("inverter", func() {
+	Context("zero products", func() {
+		n := 10
+		k := 3
+		b := 5
+
+		// HandleAll has each inverter handle the messages from all of the
+		// other inverters, and returns the results of the last call that did
+		// not return nil values.
+		HandleAll := func(inverters []Inverter, messages [][]mulopen.Message) (
+			[]shamir.VerifiableShares, [][]shamir.Commitment, []error,
+		) {
+			shares := make([]shamir.VerifiableShares, len(inverters))
+			commitments := make([][]shamir.Commitment, len(inverters))
+			errs := make([]error, len(inverters))
+			for i := range inverters {
+				for j := range messages {
+					if i == j {
+						continue
+					}
+					s, c, err := inverters[i].HandleMulOpenMessageBatch(messages[j])
+					if s != nil || err != nil {
+						shares[i], commitments[i], errs[i] = s, c, err
+					}
+				}
+			}
+			return shares, commitments, errs
+		}
+
+		Specify("elements with a zero product should be reported and can be retried", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+
+			aShares, aCommitments, aSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+
+			// Use a zero random multiplier for some of the elements.
+			rShares := make([]shamir.VerifiableShares, n)
+			for i := range rShares {
+				rShares[i] = make(shamir.VerifiableShares, b)
+			}
+			rCommitments := make([]shamir.Commitment, b)
+			failed := []int{}
+			for i := 0; i < b; i++ {
+				var r secp256k1.Fn
+				if rand.Int()&1 == 0 {
+					r.SetU16(0)
+					failed = append(failed, i)
+				} else {
+					r = secp256k1.RandomFn()
+				}
+				var sharing shamir.VerifiableShares
+				sharing, rCommitments[i] = rkpgutil.RXGOutput(indices, k, h, r)
+				for j := range sharing {
+					rShares[j][i] = sharing[j]
+				}
+			}
+
+			inverters := make([]Inverter, n)
+			messages := make([][]mulopen.Message, n)
+			for i := range inverters {
+				inverters[i], messages[i] = New(
+					aShares[i], rShares[i], rzgShares[i],
+					aCommitments, rCommitments, rzgCommitments,
+					indices, h,
+				)
+			}
+			shares, commitments, errs := HandleAll(inverters, messages)
+
+			if len(failed) != 0 {
+				for i := range inverters {
+					Expect(shares[i]).To(BeNil())
+					Expect(errs[i]).To(Equal(ZeroProductError{Indices: failed}))
+				}
+
+				l := len(failed)
+				rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, l, h)
+				rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, l, h)
+				for i := range inverters {
+					messages[i] = inverters[i].Retry(
+						rShares[i], rzgShares[i],
+						rCommitments, rzgCommitments,
+					)
+				}
+				shares, commitments, errs = HandleAll(inverters, messages)
+			}
+
+			for i := range inverters {
+				Expect(errs[i]).ToNot(HaveOccurred())
+				Expect(shares[i]).To(HaveLen(b))
+			}
+			for j := 0; j < b; j++ {
+				var inv secp256k1.Fn
+				inv.Inverse(&aSecrets[j])
+				openShares := make(shamir.Shares, n)
+				for i := range inverters {
+					Expect(commitments[i][j].Eq(commitments[0][j])).To(BeTrue())
+					Expect(shamir.IsValid(h, &commitments[i][j], &shares[i][j])).To(BeTrue())
+					openShares[i] = shares[i][j].Share
+				}
+				secret := shamir.Open(openShares)
+				Expect(secret.Eq(&inv)).To(BeTrue())
+			}
+		})
+
+		Specify("a zero input should be reported again after a retry", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+
+			aShares, aCommitments := rkpgutil.RZGOutputBatch(indices, k, 1, h)
+			rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, 1, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, 1, h)
+
+			inverters := make([]Inverter, n)
+			messages := make([][]mulopen.Message, n)
+			for i := range inverters {
+				inverters[i], messages[i] = New(
+					aShares[i], rShares[i], rzgShares[i],
+					aCommitments, rCommitments, rzgCommitments,
+					indices, h,
+				)
+			}
+			_, _, errs := HandleAll(inverters, messages)
+			for i := range inverters {
+				Expect(errs[i]).To(Equal(ZeroProductError{Indices: []int{0}}))
+			}
+
+			rShares, rCommitments, _ = rkpgutil.RNGOutputBatch(indices, k, 1, h)
+			rzgShares, rzgCommitments = rkpgutil.RZGOutputBatch(indices, 2*k-1, 1, h)
+			for i := range inverters {
+				messages[i] = inverters[i].Retry(
+					rShares[i], rzgShares[i],
+					rCommitments, rzgCommitments,
+				)
+			}
+			_, _, errs = HandleAll(inverters, messages)
+			for i := range inverters {
+				Expect(errs[i]).To(Equal(ZeroProductError{Indices: []int{0}}))
+			}
+		})
+
+		Specify("messages created from the same seed should be the same", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+
+			aShares, aCommitments := rkpgutil.RZGOutputBatch(indices, k, 1, h)
+			rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, 1, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, 1, h)
+
+			inverters := make([]Inverter, n)
+			messages := make([][]mulopen.Message, n)
+			newWithSeed := func(i int, seed int64) []mulopen.Message {
+				inverters[i], messages[i] = NewWithRand(
+					rand.New(rand.NewSource(seed)),
+					aShares[i], rShares[i], rzgShares[i],
+					aCommitments, rCommitments, rzgCommitments,
+					indices, h,
+				)
+				return messages[i]
+			}
+			seed := rand.Int63()
+			for i := range inverters {
+				newWithSeed(i, seed+int64(i))
+			}
+			Expect(newWithSeed(0, seed)).To(Equal(messages[0]))
+			Expect(newWithSeed(0, seed+int64(n))).ToNot(Equal(newWithSeed(0, seed)))
+
+			// The input is zero, so every element needs to be retried.
+			_, _, errs := HandleAll(inverters, messages)
+			Expect(errs[0]).To(Equal(ZeroProductError{Indices: []int{0}}))
+
+			rShares, rCommitments, _ = rkpgutil.RNGOutputBatch(indices, k, 1, h)
+			rzgShares, rzgCommitments = rkpgutil.RZGOutputBatch(indices, 2*k-1, 1, h)
+			retryWithSeed := func(seed int64) []mulopen.Message {
+				return inverters[0].RetryWithRand(
+					rand.New(rand.NewSource(seed)),
+					rShares[0], rzgShares[0],
+					rCommitments, rzgCommitments,
+				)
+			}
+			retryMessages := retryWithSeed(seed)
+			Expect(retryWithSeed(seed)).To(Equal(retryMessages))
+			Expect(retryWithSeed(seed + 1)).ToNot(Equal(retryMessages))
+		})
+	})
+
 	Context("network", func() {
 		n := 15
 		k := 4
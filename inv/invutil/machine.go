@@ -1,8 +1,11 @@
 package invutil
 
 import (
+	"io"
+
 	"github.com/renproject/mpc/inv"
 	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/random"
 	"github.com/renproject/secp256k1"
 	"github.com/renproject/shamir"
 	"github.com/renproject/surge"
@@ -25,7 +28,24 @@ func NewMachine(
 	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
 	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
 ) Machine {
-	inverter, msgs := inv.New(
+	return NewMachineWithRand(
+		random.Reader,
+		aShareBatch, bShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
+		ids, ownID, indices, h,
+	)
+}
+
+// NewMachineWithRand is the same as NewMachine, except that the randomness for
+// the Inverter is read from the given source.
+func NewMachineWithRand(
+	r io.Reader,
+	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
+) Machine {
+	inverter, msgs := inv.NewWithRand(
+		r,
 		aShareBatch, bShareBatch, rzgShareBatch,
 		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
 		indices, h,
@@ -70,6 +90,13 @@ func (m *Machine) Handle(msg mpcutil.Message) []mpcutil.Message {
 	return nil
 }
 
+// Done implements the mpcutil.Terminator interface.
+func (m Machine) Done() bool { return len(m.OutputCommitments) != 0 }
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of shares of the inverses.
+func (m Machine) Result() interface{} { return m.OutputShares }
+
 // SizeHint implements the surge.SizeHinter interface.
 func (m Machine) SizeHint() int {
 	return m.OwnID.SizeHint() +
@@ -13,8 +13,15 @@ import (
 // SizeHint implements the surge.SizeHinter interface.
 func (inverter Inverter) SizeHint() int {
 	return inverter.mulopener.SizeHint() +
+		surge.SizeHint(inverter.pending) +
+		surge.SizeHint(inverter.aShareBatch) +
+		surge.SizeHint(inverter.aCommitmentBatch) +
 		surge.SizeHint(inverter.rShareBatch) +
-		surge.SizeHint(inverter.rCommitmentBatch)
+		surge.SizeHint(inverter.rCommitmentBatch) +
+		surge.SizeHint(inverter.invShareBatch) +
+		surge.SizeHint(inverter.invCommitmentBatch) +
+		surge.SizeHint(inverter.indices) +
+		inverter.h.SizeHint()
 }
 
 // Marshal implements the surge.Marshaler interface.
@@ -23,11 +30,39 @@ func (inverter Inverter) Marshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, err
 	}
+	buf, rem, err = surge.Marshal(inverter.pending, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(inverter.aShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(inverter.aCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
 	buf, rem, err = surge.Marshal(inverter.rShareBatch, buf, rem)
 	if err != nil {
 		return buf, rem, err
 	}
-	return surge.Marshal(inverter.rCommitmentBatch, buf, rem)
+	buf, rem, err = surge.Marshal(inverter.rCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(inverter.invShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(inverter.invCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(inverter.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return inverter.h.Marshal(buf, rem)
 }
 
 // Unmarshal implements the surge.Unmarshaler interface.
@@ -36,11 +71,39 @@ func (inverter *Inverter) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, err
 	}
+	buf, rem, err = surge.Unmarshal(&inverter.pending, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&inverter.aShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&inverter.aCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
 	buf, rem, err = surge.Unmarshal(&inverter.rShareBatch, buf, rem)
 	if err != nil {
 		return buf, rem, err
 	}
-	return surge.Unmarshal(&inverter.rCommitmentBatch, buf, rem)
+	buf, rem, err = surge.Unmarshal(&inverter.rCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&inverter.invShareBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&inverter.invCommitmentBatch, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&inverter.indices, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return inverter.h.Unmarshal(buf, rem)
 }
 
 // Generate implements the quick.Generator interface.
@@ -48,24 +111,45 @@ func (inverter Inverter) Generate(rand *rand.Rand, size int) reflect.Value {
 	size /= 4
 	b := rand.Intn(size/2) + 1
 	mulopener := mulopen.MulOpener{}.Generate(rand, size).Interface().(mulopen.MulOpener)
-	rShareBatch := make(shamir.VerifiableShares, b)
-	for i := 0; i < b; i++ {
-		rShareBatch[i] = shamir.VerifiableShare{
-			Share: shamir.Share{
-				Index: secp256k1.RandomFn(),
-				Value: secp256k1.RandomFn(),
-			},
-			Decommitment: secp256k1.RandomFn(),
+	pending := make([]uint32, rand.Intn(b+1))
+	for i := range pending {
+		pending[i] = uint32(rand.Intn(b))
+	}
+	randomShareBatch := func() shamir.VerifiableShares {
+		shareBatch := make(shamir.VerifiableShares, b)
+		for i := 0; i < b; i++ {
+			shareBatch[i] = shamir.VerifiableShare{
+				Share: shamir.Share{
+					Index: secp256k1.RandomFn(),
+					Value: secp256k1.RandomFn(),
+				},
+				Decommitment: secp256k1.RandomFn(),
+			}
+		}
+		return shareBatch
+	}
+	randomCommitmentBatch := func() []shamir.Commitment {
+		commitmentBatch := make([]shamir.Commitment, b)
+		for i := 0; i < b; i++ {
+			commitmentBatch[i] = shamir.Commitment{}.Generate(rand, size/2).Interface().(shamir.Commitment)
 		}
+		return commitmentBatch
 	}
-	rCommitmentBatch := make([]shamir.Commitment, b)
-	for i := 0; i < b; i++ {
-		rCommitmentBatch[i] = shamir.Commitment{}.Generate(rand, size/2).Interface().(shamir.Commitment)
+	indices := make([]secp256k1.Fn, rand.Intn(size/2)+1)
+	for i := range indices {
+		indices[i] = secp256k1.RandomFn()
 	}
 	inv := Inverter{
 		mulopener,
-		rShareBatch,
-		rCommitmentBatch,
+		pending,
+		randomShareBatch(),
+		randomCommitmentBatch(),
+		randomShareBatch(),
+		randomCommitmentBatch(),
+		randomShareBatch(),
+		randomCommitmentBatch(),
+		indices,
+		secp256k1.RandomPoint(),
 	}
 	return reflect.ValueOf(inv)
 }
@@ -0,0 +1,178 @@
+package mpcutil
+
+import "fmt"
+
+// A Strategy determines the behaviour of the machines that have been
+// corrupted by an Adversary.
+type Strategy interface {
+	// Rush is called once per round, before any of the messages for that
+	// round are delivered, with the messages for the round that are from
+	// honest machines to corrupted machines (the view of the adversary) and
+	// the messages that the corrupted machines would send if they were
+	// honest. The returned messages will be sent by the corrupted machines
+	// instead. Since the messages are chosen after seeing the honest messages
+	// for the same round, the adversary is rushing. The round for the initial
+	// messages is 0.
+	Rush(round int, view, outgoing []Message) []Message
+}
+
+// StrategyFunc is an adapter that allows an ordinary function to be used as a
+// Strategy.
+type StrategyFunc func(round int, view, outgoing []Message) []Message
+
+// Rush implements the Strategy interface.
+func (f StrategyFunc) Rush(round int, view, outgoing []Message) []Message {
+	return f(round, view, outgoing)
+}
+
+// SilentStrategy is a Strategy for which the corrupted machines do not send
+// any messages, i.e. they crash as soon as they are corrupted.
+var SilentStrategy Strategy = StrategyFunc(func(int, []Message, []Message) []Message {
+	return nil
+})
+
+// PassiveStrategy is a Strategy for which the corrupted machines follow the
+// protocol honestly. This is useful to check that a protocol is not affected
+// by adaptive corruptions alone, and to inspect the view of the adversary.
+var PassiveStrategy Strategy = StrategyFunc(func(_ int, _, outgoing []Message) []Message {
+	return outgoing
+})
+
+// A CorruptionPolicy is used by an Adversary to choose machines to corrupt
+// adaptively during a run. It is called once per round, before the messages
+// for the round are given to the Strategy, with all of the messages for the
+// round (including those between honest machines) and the set of machines
+// that are already corrupted. The machines with the returned IDs will be
+// corrupted, up to the corruption threshold of the adversary. A machine that
+// is corrupted in a given round will have its messages for that round
+// replaced by the Strategy.
+type CorruptionPolicy func(round int, msgs []Message, corrupted map[ID]bool) []ID
+
+// An Adversary controls the machines that it has corrupted during a Network
+// run. Up to t machines can be corrupted, either before the run (static
+// corruption) or during the run (adaptive corruption), and the messages that
+// are sent by corrupted machines are determined by a Strategy instead of by
+// the machines themselves. Messages that are sent to corrupted machines are
+// still delivered to them, so that a Strategy can use the messages that an
+// honest machine would send as a basis for the messages that it sends.
+//
+// This allows the same adversarial behaviour to be tested against every
+// protocol, instead of needing a malicious Machine implementation for each
+// protocol.
+type Adversary struct {
+	t         int
+	strategy  Strategy
+	corrupted map[ID]bool
+	schedule  map[int][]ID
+	policy    CorruptionPolicy
+	view      []Message
+}
+
+// NewAdversary creates a new Adversary that can corrupt at most t machines and
+// that uses the given Strategy for the corrupted machines.
+//
+// Panics: This function will panic if t is negative.
+func NewAdversary(t int, strategy Strategy) Adversary {
+	if t < 0 {
+		panic(fmt.Sprintf("t must not be negative: got %v", t))
+	}
+	return Adversary{
+		t:         t,
+		strategy:  strategy,
+		corrupted: make(map[ID]bool, t),
+		schedule:  make(map[int][]ID),
+		policy:    nil,
+		view:      nil,
+	}
+}
+
+// Corrupt corrupts the machine with the given ID. It returns false if the
+// machine could not be corrupted because t machines have already been
+// corrupted, and true otherwise.
+func (adv *Adversary) Corrupt(id ID) bool {
+	if adv.corrupted[id] {
+		return true
+	}
+	if len(adv.corrupted) >= adv.t {
+		return false
+	}
+	adv.corrupted[id] = true
+	return true
+}
+
+// CorruptAt schedules the machines with the given IDs to be corrupted at the
+// start of the given round.
+func (adv *Adversary) CorruptAt(round int, ids ...ID) {
+	adv.schedule[round] = append(adv.schedule[round], ids...)
+}
+
+// SetCorruptionPolicy sets the policy that is used to choose machines to
+// corrupt adaptively during a run.
+func (adv *Adversary) SetCorruptionPolicy(policy CorruptionPolicy) {
+	adv.policy = policy
+}
+
+// IsCorrupted returns true if the machine with the given ID has been
+// corrupted, and false otherwise.
+func (adv Adversary) IsCorrupted(id ID) bool {
+	return adv.corrupted[id]
+}
+
+// Corrupted returns the set of machines that have been corrupted.
+func (adv Adversary) Corrupted() map[ID]bool {
+	corrupted := make(map[ID]bool, len(adv.corrupted))
+	for id := range adv.corrupted {
+		corrupted[id] = true
+	}
+	return corrupted
+}
+
+// View returns all of the messages that have been sent from honest machines to
+// corrupted machines so far, in the order that they were seen by the
+// adversary.
+func (adv Adversary) View() []Message {
+	return adv.view
+}
+
+// Rush applies the adversary to the messages for the given round. First any
+// corruptions for the round are performed, and then the messages from the
+// corrupted machines are replaced by the messages chosen by the Strategy.
+// Nil messages are ignored.
+func (adv *Adversary) Rush(round int, msgs []Message) []Message {
+	for _, id := range adv.schedule[round] {
+		adv.Corrupt(id)
+	}
+	if adv.policy != nil {
+		for _, id := range adv.policy(round, msgs, adv.Corrupted()) {
+			adv.Corrupt(id)
+		}
+	}
+
+	processed := make([]Message, 0, len(msgs))
+	var view, outgoing []Message
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		if adv.corrupted[msg.From()] {
+			outgoing = append(outgoing, msg)
+			continue
+		}
+		if adv.corrupted[msg.To()] {
+			view = append(view, msg)
+		}
+		processed = append(processed, msg)
+	}
+	adv.view = append(adv.view, view...)
+
+	for _, msg := range adv.strategy.Rush(round, view, outgoing) {
+		if msg == nil {
+			continue
+		}
+		if !adv.corrupted[msg.From()] {
+			panic(fmt.Sprintf("strategy sent a message from honest machine %v", msg.From()))
+		}
+		processed = append(processed, msg)
+	}
+	return processed
+}
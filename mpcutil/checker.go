@@ -0,0 +1,399 @@
+package mpcutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/renproject/surge"
+)
+
+// DefaultMaxStates is the maximum number of distinct states that a Checker
+// will explore by default.
+const DefaultMaxStates = 1000000
+
+// ErrStateLimit is returned by Checker.Check when the maximum number of states
+// has been explored without finding a violation, and so not every run has been
+// checked.
+var ErrStateLimit = errors.New("state limit reached before all states were explored")
+
+// An Invariant is a property of the final states of the honest machines in a
+// run, which are those that have not crashed. It returns a non-nil error
+// describing how the property is violated, if it is.
+type Invariant func(honest []Machine) error
+
+// A Checker performs bounded model checking for a small network of Machines.
+// Instead of simulating a single run like a Network or Scheduler, it explores
+// every order in which the messages can be delivered, together with every way
+// in which up to t of the machines can crash. A crashed machine stops handling
+// messages, and all of the messages to or from it that have not yet been
+// delivered are dropped. A machine can crash at any point during a run,
+// including before any of its initial messages are delivered, which is the
+// same as being offline for the whole run.
+//
+// A run ends when there are no more messages to deliver, at which point the
+// invariants are checked for the honest machines. The states of the machines
+// are forked by marshalling and unmarshalling them, and states that are
+// reached by different interleavings (for example, by delivering two messages
+// to different machines in either order) are only explored once. Machines are
+// assumed to be deterministic, so the result of a machine handling a given
+// message in a given state is also only computed once, however many global
+// states it occurs in. This makes exhaustive exploration feasible for networks
+// with n = 4 and small batch sizes.
+type Checker struct {
+	machines  []Machine
+	t         int
+	maxStates int
+}
+
+// NewChecker creates a new Checker for the given machines in their initial
+// states, where up to t of the machines can crash. The machines must be
+// pointers, as they would be for a Network.
+//
+// Panics: This function will panic if t is negative, or if two machines have
+// the same ID.
+func NewChecker(machines []Machine, t int) Checker {
+	if t < 0 {
+		panic(fmt.Sprintf("t must not be negative: got %v", t))
+	}
+	seen := make(map[ID]bool, len(machines))
+	for _, machine := range machines {
+		if seen[machine.ID()] {
+			panic(fmt.Sprintf("two machines can't have the same ID: found duplicate ID %v", machine.ID()))
+		}
+		seen[machine.ID()] = true
+	}
+	return Checker{
+		machines:  machines,
+		t:         t,
+		maxStates: DefaultMaxStates,
+	}
+}
+
+// SetMaxStates sets the maximum number of distinct states that will be
+// explored.
+//
+// Panics: This function will panic if the maximum is less than 1.
+func (c *Checker) SetMaxStates(max int) {
+	if max < 1 {
+		panic(fmt.Sprintf("max states must be at least 1: got %v", max))
+	}
+	c.maxStates = max
+}
+
+// A Violation describes a run in which an invariant was violated or a machine
+// panicked. The messages that were delivered during the run are given in the
+// order that they were delivered, and the crashed machines are those that
+// crashed at some point during the run.
+type Violation struct {
+	Err       error
+	Delivered []Message
+	Crashed   []ID
+
+	initialStates []byte
+}
+
+// Error implements the error interface.
+func (v *Violation) Error() string {
+	return fmt.Sprintf(
+		"violation after delivering %v messages with crashed machines %v: %v",
+		len(v.Delivered), v.Crashed, v.Err,
+	)
+}
+
+// Dump saves the initial states of the machines and the delivered messages to
+// the file with the given name, so that the run can be inspected by a Debugger
+// or minimized by Minimize.
+func (v *Violation) Dump(filename string) {
+	dump(filename, v.initialStates, v.Delivered)
+}
+
+// Check explores the runs of the network, checking the given invariants at the
+// end of each run. The exploration stops as soon as an invariant is violated
+// or a machine panics, in which case the returned error will be a *Violation.
+// If the maximum number of states is reached first, ErrStateLimit is returned.
+// The number of distinct states that were explored is returned in all cases.
+func (c Checker) Check(invariants ...Invariant) (int, error) {
+	initialStates, err := surge.ToBinary(c.machines)
+	if err != nil {
+		panic(fmt.Sprintf("could not marshal machines: %v", err))
+	}
+
+	e := explorer{
+		checker:       c,
+		invariants:    invariants,
+		indexOfID:     make(map[ID]int, len(c.machines)),
+		initialStates: initialStates,
+		visited:       make(map[[32]byte]struct{}),
+		transitions:   make(map[transitionKey]transition),
+	}
+
+	s := checkerState{
+		states:  make([]localState, len(c.machines)),
+		crashed: make([]bool, len(c.machines)),
+	}
+	for i, machine := range c.machines {
+		e.indexOfID[machine.ID()] = i
+		s.states[i] = newLocalState(machine)
+	}
+	for _, machine := range c.machines {
+		for _, msg := range machine.InitialMessages() {
+			if msg != nil {
+				s.pending = append(s.pending, newPendingMessage(msg))
+			}
+		}
+	}
+
+	err = e.explore(s)
+	return len(e.visited), err
+}
+
+// A checkerState is the state of the network at a point during a run. The
+// states of the machines are kept in marshalled form, along with their
+// digests, so that states can be forked and hashed cheaply.
+type checkerState struct {
+	states    []localState
+	crashed   []bool
+	numCrash  int
+	pending   []pendingMessage
+	delivered []Message
+	crashes   []ID
+}
+
+type localState struct {
+	buf    []byte
+	digest [32]byte
+}
+
+func newLocalState(machine Machine) localState {
+	buf, err := surge.ToBinary(machine)
+	if err != nil {
+		panic(fmt.Sprintf("could not marshal machine: %v", err))
+	}
+	return localState{buf, sha256.Sum256(buf)}
+}
+
+type pendingMessage struct {
+	msg    Message
+	digest [32]byte
+}
+
+func newPendingMessage(msg Message) pendingMessage {
+	buf, err := surge.ToBinary(msg)
+	if err != nil {
+		panic(fmt.Sprintf("could not marshal message: %v", err))
+	}
+	return pendingMessage{msg, sha256.Sum256(buf)}
+}
+
+// A transition is the result of a machine in a given state handling a given
+// message.
+type transition struct {
+	state    localState
+	outgoing []pendingMessage
+	failure  error
+}
+
+type transitionKey struct {
+	state, msg [32]byte
+}
+
+type explorer struct {
+	checker       Checker
+	invariants    []Invariant
+	indexOfID     map[ID]int
+	initialStates []byte
+	visited       map[[32]byte]struct{}
+	transitions   map[transitionKey]transition
+}
+
+func (e *explorer) explore(s checkerState) error {
+	h := s.hash()
+	if _, ok := e.visited[h]; ok {
+		return nil
+	}
+	if len(e.visited) >= e.checker.maxStates {
+		return ErrStateLimit
+	}
+	e.visited[h] = struct{}{}
+
+	if len(s.pending) == 0 {
+		honest := make([]Machine, 0, len(s.states))
+		for i, state := range s.states {
+			if !s.crashed[i] {
+				honest = append(honest, e.machine(i, state))
+			}
+		}
+		for _, invariant := range e.invariants {
+			if err := invariant(honest); err != nil {
+				return e.violation(s, err)
+			}
+		}
+		return nil
+	}
+
+	// Deliver each of the distinct pending messages.
+	for i, p := range s.pending {
+		if isDuplicate(s.pending[:i], p) {
+			continue
+		}
+		next, err := e.deliver(s, i)
+		if err != nil {
+			return err
+		}
+		if err := e.explore(next); err != nil {
+			return err
+		}
+	}
+
+	// Crash each of the honest machines, if there are crashes left.
+	if s.numCrash < e.checker.t {
+		for i := range s.states {
+			if s.crashed[i] {
+				continue
+			}
+			if err := e.explore(s.crash(i, e.checker.machines[i].ID())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deliver returns the state after the pending message with the given index is
+// handled by its recipient. A panic by the recipient is returned as a
+// violation.
+func (e *explorer) deliver(s checkerState, i int) (checkerState, error) {
+	p := s.pending[i]
+
+	next := s
+	next.pending = make([]pendingMessage, 0, len(s.pending))
+	next.pending = append(next.pending, s.pending[:i]...)
+	next.pending = append(next.pending, s.pending[i+1:]...)
+	next.delivered = append(append([]Message{}, s.delivered...), p.msg)
+
+	j, ok := e.indexOfID[p.msg.To()]
+	if !ok {
+		return next, nil
+	}
+
+	key := transitionKey{s.states[j].digest, p.digest}
+	tr, ok := e.transitions[key]
+	if !ok {
+		tr = e.handle(j, s.states[j], p.msg)
+		e.transitions[key] = tr
+	}
+	if tr.failure != nil {
+		return next, e.violation(next, tr.failure)
+	}
+
+	next.states = append([]localState{}, s.states...)
+	next.states[j] = tr.state
+	for _, out := range tr.outgoing {
+		if k, ok := e.indexOfID[out.msg.To()]; ok && next.crashed[k] {
+			continue
+		}
+		next.pending = append(next.pending, out)
+	}
+
+	return next, nil
+}
+
+// handle computes the transition for the machine with the given index in the
+// given state handling the given message.
+func (e *explorer) handle(i int, state localState, msg Message) (tr transition) {
+	machine := e.machine(i, state)
+
+	defer func() {
+		if r := recover(); r != nil {
+			tr = transition{failure: fmt.Errorf("panic: %v", r)}
+		}
+	}()
+
+	for _, out := range machine.Handle(msg) {
+		if out != nil {
+			tr.outgoing = append(tr.outgoing, newPendingMessage(out))
+		}
+	}
+	tr.state = newLocalState(machine)
+	return tr
+}
+
+// machine returns a new machine of the same type as the machine with the given
+// index, in the given state.
+func (e *explorer) machine(i int, state localState) Machine {
+	machine := reflect.New(reflect.TypeOf(e.checker.machines[i]).Elem()).Interface().(Machine)
+	if err := surge.FromBinary(machine, state.buf); err != nil {
+		panic(fmt.Sprintf("could not unmarshal machine: %v", err))
+	}
+	return machine
+}
+
+func (e *explorer) violation(s checkerState, err error) *Violation {
+	return &Violation{
+		Err:           err,
+		Delivered:     s.delivered,
+		Crashed:       s.crashes,
+		initialStates: e.initialStates,
+	}
+}
+
+// crash returns the state after the machine with the given index and ID
+// crashes.
+func (s checkerState) crash(i int, id ID) checkerState {
+	next := s
+	next.crashed = append([]bool{}, s.crashed...)
+	next.crashed[i] = true
+	next.numCrash++
+	next.crashes = append(append([]ID{}, s.crashes...), id)
+	next.pending = make([]pendingMessage, 0, len(s.pending))
+	for _, p := range s.pending {
+		if p.msg.From() != id && p.msg.To() != id {
+			next.pending = append(next.pending, p)
+		}
+	}
+	return next
+}
+
+// hash returns a hash of the state that does not depend on the order of the
+// pending messages or on how the state was reached. The state of a crashed
+// machine does not affect the rest of the run, and so it is not included.
+func (s checkerState) hash() [32]byte {
+	digests := make([][32]byte, len(s.pending))
+	for i, p := range s.pending {
+		digests[i] = p.digest
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return bytes.Compare(digests[i][:], digests[j][:]) < 0
+	})
+
+	hasher := sha256.New()
+	for i, state := range s.states {
+		if s.crashed[i] {
+			hasher.Write([]byte{0})
+			continue
+		}
+		hasher.Write([]byte{1})
+		hasher.Write(state.digest[:])
+	}
+	for _, digest := range digests {
+		hasher.Write(digest[:])
+	}
+
+	var h [32]byte
+	copy(h[:], hasher.Sum(nil))
+	return h
+}
+
+func isDuplicate(pending []pendingMessage, p pendingMessage) bool {
+	for _, other := range pending {
+		if other.digest == p.digest {
+			return true
+		}
+	}
+	return false
+}
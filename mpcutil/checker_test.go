@@ -0,0 +1,84 @@
+package mpcutil_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/mpcutil"
+)
+
+var _ = Describe("Checker", func() {
+	n := 3
+
+	newMachines := func(faulty bool) []Machine {
+		ids := make([]ID, n)
+		for i := range ids {
+			ids[i] = ID(i + 1)
+		}
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &pingMachine{
+				id:     ids[i],
+				ids:    ids,
+				faulty: faulty && i == 0,
+			}
+		}
+		return machines
+	}
+
+	It("should explore every run when there are no violations", func() {
+		runs := 0
+		checker := NewChecker(newMachines(false), 1)
+		states, err := checker.Check(func(honest []Machine) error {
+			runs++
+			Expect(len(honest)).To(BeNumerically(">=", n-1))
+			return nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(states).To(BeNumerically(">", runs))
+		Expect(runs).To(BeNumerically(">", 1))
+	})
+
+	It("should return an error when the state limit is reached", func() {
+		checker := NewChecker(newMachines(false), 1)
+		checker.SetMaxStates(10)
+		states, err := checker.Check()
+		Expect(err).To(Equal(ErrStateLimit))
+		Expect(states).To(Equal(10))
+	})
+
+	It("should find a run in which a machine panics", func() {
+		checker := NewChecker(newMachines(true), 0)
+		_, err := checker.Check()
+		violation, ok := err.(*Violation)
+		Expect(ok).To(BeTrue())
+		Expect(violation.Err).To(MatchError("panic: invalid state"))
+		Expect(violation.Crashed).To(BeEmpty())
+
+		// The run can be minimized to the messages that cause the panic.
+		dir, err := ioutil.TempDir("", "mpcutil")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "violation.dump")
+		output := filepath.Join(dir, "minimized.dump")
+		violation.Dump(filename)
+		m, err := Minimize(filename, output, pingMessage{}, pingMachine{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(2))
+	})
+
+	Context("panics", func() {
+		Specify("negative t", func() {
+			Expect(func() { NewChecker(newMachines(false), -1) }).To(Panic())
+		})
+
+		Specify("duplicate IDs", func() {
+			machines := newMachines(false)
+			machines[1] = machines[0]
+			Expect(func() { NewChecker(machines, 1) }).To(Panic())
+		})
+	})
+})
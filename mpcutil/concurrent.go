@@ -0,0 +1,369 @@
+package mpcutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A ConcurrentNetwork is used to simulate a network of distributed Machines,
+// like a Network, except that each machine runs in its own goroutine and
+// receives messages over a channel. There are no lock-step rounds: a message
+// is handled as soon as its receiver is ready, and so the order in which
+// messages are delivered is determined by the Go scheduler. This exercises
+// the machines under real concurrency, for example with the race detector,
+// and gives wall clock performance numbers on machines with multiple cores.
+//
+// The rounds in the statistics of a ConcurrentNetwork are logical rounds: the
+// initial messages are sent in round 0, and the messages that are sent by a
+// machine when handling a message from round r are sent in round r+1. These
+// are the same rounds in which the messages would be delivered by a Network.
+type ConcurrentNetwork struct {
+	machines    []Machine
+	processMsgs func([]Message)
+	indexOfID   map[ID]int
+	bufferSize  int
+
+	captureStats bool
+	stats        statsRecorder
+}
+
+// NewConcurrentNetwork creates a new ConcurrentNetwork object from the given
+// machines and message processing function. The message processing function
+// is applied to the messages that are sent by a machine when it handles a
+// message, and to the initial messages of each machine, before they are sent.
+// Messages that it sets to nil are dropped, and so the message processing
+// functions for a Network, such as the one returned by
+// MessageShufflerDropper, can also be used for a ConcurrentNetwork. The calls
+// to the message processing function are never concurrent with each other.
+//
+// Panics: This function will panic if two machines have the same ID.
+func NewConcurrentNetwork(machines []Machine, processMsgs func([]Message)) ConcurrentNetwork {
+	indexOfID := make(map[ID]int, len(machines))
+	for i, machine := range machines {
+		if _, ok := indexOfID[machine.ID()]; ok {
+			panic(fmt.Sprintf("two machines can't have the same ID: found duplicate ID %v", machine.ID()))
+		}
+		indexOfID[machine.ID()] = i
+	}
+
+	return ConcurrentNetwork{
+		machines:    machines,
+		processMsgs: processMsgs,
+		indexOfID:   indexOfID,
+		bufferSize:  0,
+
+		captureStats: false,
+	}
+}
+
+// SetBufferSize sets the size of the buffer for the channel over which each
+// machine receives messages. If the size is 0, which is the default, the
+// buffers are unbounded. Otherwise, a machine that sends a message to another
+// machine whose buffer is full will block until there is space in the buffer.
+// This can deadlock if two machines are blocked sending messages to each
+// other, and so runs with bounded buffers should use a context with a
+// deadline. Messages that a machine sends to itself, such as timer ticks,
+// never block, and can exceed the size of the buffer.
+//
+// Panics: This function will panic if the size is negative.
+func (net *ConcurrentNetwork) SetBufferSize(size int) {
+	if size < 0 {
+		panic(fmt.Sprintf("buffer size must not be negative: got %v", size))
+	}
+	net.bufferSize = size
+}
+
+// SetCaptureStats sets whether the network will record the number of messages
+// and bytes sent and received by each machine in each round, and the time
+// spent by each machine handling messages. The recorded statistics can be
+// retrieved using Report after a run.
+func (net *ConcurrentNetwork) SetCaptureStats(b bool) {
+	net.captureStats = b
+}
+
+// Run drives an execution of the network of machines to completion. The run
+// will continue until there are no more messages to deliver, or until all of
+// the machines have finished, if all of the machines implement Terminator. If
+// a machine panics when handling a message, the run is stopped and an error
+// describing the panic is returned. If the given context is done before the
+// run is complete, the run is stopped and the error from the context is
+// returned. In all other cases, a nil error is returned. All of the goroutines
+// started by the run have exited by the time that Run returns.
+func (net *ConcurrentNetwork) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r := concurrentRun{
+		net:      net,
+		ctx:      runCtx,
+		cancel:   cancel,
+		parties:  make([]party, len(net.machines)),
+		inFlight: int64(len(net.machines)),
+	}
+	capacity := net.bufferSize
+	if capacity == 0 {
+		capacity = len(net.machines)
+	}
+	for i, machine := range net.machines {
+		r.parties[i] = party{
+			machine: machine,
+			inbox:   make(chan envelope, capacity),
+		}
+		if _, ok := machine.(Terminator); ok {
+			r.numTerminators++
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range r.parties {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.runParty(i)
+		}(i)
+	}
+	wg.Wait()
+	r.senders.Wait()
+
+	if net.captureStats {
+		net.stats.reset()
+		for i := range r.parties {
+			for round, counts := range r.parties[i].stats {
+				for len(net.stats.rounds) <= round {
+					net.stats.nextRound(len(r.parties))
+				}
+				net.stats.rounds[round][i] = counts
+			}
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+	if atomic.LoadInt32(&r.complete) == 0 {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Unfinished returns the IDs of the machines that implement Terminator and
+// had not finished by the end of the run.
+func (net ConcurrentNetwork) Unfinished() []ID {
+	return unfinished(net.machines)
+}
+
+// Outputs returns the outputs of the machines that implement Outputter,
+// indexed by ID. Machines that also implement Terminator are only included if
+// they have finished.
+func (net ConcurrentNetwork) Outputs() map[ID]interface{} {
+	return outputs(net.machines)
+}
+
+// Report returns the statistics that were recorded during the last run. The
+// report will be empty unless the statistics were captured, which can be set
+// using SetCaptureStats.
+func (net ConcurrentNetwork) Report() Report {
+	return net.stats.report(net.machines)
+}
+
+// An envelope is a message together with the logical round in which it was
+// sent.
+type envelope struct {
+	msg   Message
+	round int
+}
+
+// A party is a machine together with the channel over which it receives
+// messages and the statistics that it has recorded. The statistics are only
+// accessed by the goroutine of the party.
+type party struct {
+	machine Machine
+	inbox   chan envelope
+	stats   []Counts
+	done    bool
+}
+
+func (p *party) counts(round int) *Counts {
+	for len(p.stats) <= round {
+		p.stats = append(p.stats, Counts{})
+	}
+	return &p.stats[round]
+}
+
+// A concurrentRun is the state of a single run of a ConcurrentNetwork that is
+// shared by the goroutines of the parties.
+type concurrentRun struct {
+	// inFlight is the number of messages that have been sent but not yet
+	// handled, plus the number of parties that have not yet sent their
+	// initial messages. It is only decremented after the messages sent in
+	// response have been counted, so it is only 0 when the run is complete.
+	// It is the first field so that it is 64-bit aligned for atomic access.
+	inFlight int64
+
+	net     *ConcurrentNetwork
+	ctx     context.Context
+	cancel  func()
+	parties []party
+	senders sync.WaitGroup
+
+	numTerminators int
+	finished       int32
+	complete       int32
+
+	processMu sync.Mutex
+	errOnce   sync.Once
+	err       error
+}
+
+func (r *concurrentRun) runParty(i int) {
+	p := &r.parties[i]
+	if r.ctx.Err() != nil {
+		return
+	}
+
+	r.send(p, p.machine.InitialMessages(), 0)
+	r.checkDone(p)
+	r.release()
+
+	for {
+		// A select chooses randomly between the cases that are ready, so the
+		// context is checked first to make sure that no more messages are
+		// handled once the run has been stopped.
+		if r.ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-r.ctx.Done():
+			return
+		case e := <-p.inbox:
+			out, ok := r.handle(p, e)
+			if !ok {
+				return
+			}
+			r.send(p, out, e.round+1)
+			r.checkDone(p)
+			r.release()
+		}
+	}
+}
+
+// handle delivers the message in the given envelope to the machine of the
+// given party. If the machine panics, the run is stopped and false is
+// returned.
+func (r *concurrentRun) handle(p *party, e envelope) (out []Message, ok bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.errOnce.Do(func() {
+				r.err = fmt.Errorf("machine %v panicked: %v", p.machine.ID(), rec)
+			})
+			r.cancel()
+			out, ok = nil, false
+		}
+	}()
+
+	if !r.net.captureStats {
+		return p.machine.Handle(e.msg), true
+	}
+
+	counts := p.counts(e.round)
+	counts.MessagesReceived++
+	counts.BytesReceived += e.msg.SizeHint()
+	start := time.Now()
+	out = p.machine.Handle(e.msg)
+	counts.HandleTime += time.Since(start)
+	return out, true
+}
+
+// send sends the given messages from the given party in the given round.
+func (r *concurrentRun) send(p *party, msgs []Message, round int) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	if r.net.captureStats {
+		counts := p.counts(round)
+		for _, msg := range msgs {
+			if msg != nil {
+				counts.MessagesSent++
+				counts.BytesSent += msg.SizeHint()
+			}
+		}
+	}
+
+	// The message processing function can modify the messages, so it is
+	// given a copy instead of the slice returned by the machine.
+	msgs = append([]Message(nil), msgs...)
+	r.processMu.Lock()
+	r.net.processMsgs(msgs)
+	r.processMu.Unlock()
+
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		j, ok := r.net.indexOfID[msg.To()]
+		if !ok {
+			continue
+		}
+		atomic.AddInt64(&r.inFlight, 1)
+		inbox, e := r.parties[j].inbox, envelope{msg, round}
+
+		if r.net.bufferSize != 0 && &r.parties[j] != p {
+			select {
+			case inbox <- e:
+			case <-r.ctx.Done():
+				return
+			}
+			continue
+		}
+
+		// The buffers are unbounded, or the message is from the party to
+		// itself, in which case blocking would deadlock since the party is
+		// the only one that can empty its buffer. Either way, if the buffer
+		// of the receiver is full the message is sent from another goroutine
+		// instead of blocking.
+		select {
+		case inbox <- e:
+		default:
+			r.senders.Add(1)
+			go func() {
+				defer r.senders.Done()
+				select {
+				case inbox <- e:
+				case <-r.ctx.Done():
+				}
+			}()
+		}
+	}
+}
+
+// checkDone stops the run if the machine of the given party has finished and
+// it is the last of the machines to finish.
+func (r *concurrentRun) checkDone(p *party) {
+	if p.done || r.numTerminators != len(r.parties) {
+		return
+	}
+	if !p.machine.(Terminator).Done() {
+		return
+	}
+	p.done = true
+	if atomic.AddInt32(&r.finished, 1) == int32(r.numTerminators) {
+		r.stop()
+	}
+}
+
+// release marks a message as handled, or the initial messages of a party as
+// sent, and stops the run if there are no more messages to deliver.
+func (r *concurrentRun) release() {
+	if atomic.AddInt64(&r.inFlight, -1) == 0 {
+		r.stop()
+	}
+}
+
+func (r *concurrentRun) stop() {
+	atomic.StoreInt32(&r.complete, 1)
+	r.cancel()
+}
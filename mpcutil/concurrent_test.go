@@ -0,0 +1,144 @@
+package mpcutil_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/mpcutil"
+)
+
+var _ = Describe("ConcurrentNetwork", func() {
+	n := 5
+
+	ids := make([]ID, n)
+	for i := range ids {
+		ids[i] = ID(i + 1)
+	}
+
+	newPingMachines := func() []Machine {
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &pingMachine{id: ids[i], ids: ids}
+		}
+		return machines
+	}
+
+	It("should deliver every message and record the same statistics as a Network", func() {
+		network := NewNetwork(newPingMachines(), func([]Message) {})
+		network.SetCaptureStats(true)
+		Expect(network.Run()).To(Succeed())
+
+		concurrent := NewConcurrentNetwork(newPingMachines(), func([]Message) {})
+		concurrent.SetCaptureStats(true)
+		Expect(concurrent.Run(context.Background())).To(Succeed())
+
+		expected, report := network.Report(), concurrent.Report()
+		Expect(report.Rounds).To(Equal(expected.Rounds))
+		Expect(report.Stats).To(HaveLen(len(expected.Stats)))
+		for i, stats := range report.Stats {
+			Expect(stats.Round).To(Equal(expected.Stats[i].Round))
+			Expect(stats.ID).To(Equal(expected.Stats[i].ID))
+			stats.HandleTime = expected.Stats[i].HandleTime
+			Expect(stats.Counts).To(Equal(expected.Stats[i].Counts))
+		}
+	})
+
+	It("should drop the messages set to nil by the message processing function", func() {
+		shuffleMsgs, isOffline := MessageShufflerDropper(ids, 1)
+		concurrent := NewConcurrentNetwork(newPingMachines(), shuffleMsgs)
+		concurrent.SetCaptureStats(true)
+		Expect(concurrent.Run(context.Background())).To(Succeed())
+
+		report := concurrent.Report()
+		for _, id := range ids {
+			if isOffline[id] {
+				Expect(report.Machine(id).MessagesReceived).To(Equal(0))
+			} else {
+				Expect(report.Machine(id).MessagesReceived).To(Equal((n - 2) * (maxCount + 1)))
+			}
+		}
+	})
+
+	It("should deliver every message with bounded buffers", func() {
+		// Each pair of machines has two exchanges of messages, and each
+		// exchange has one message in flight at a time, so the buffers never
+		// need to hold more than 2(n-1) messages.
+		concurrent := NewConcurrentNetwork(newPingMachines(), func([]Message) {})
+		concurrent.SetBufferSize(2 * (n - 1))
+		concurrent.SetCaptureStats(true)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		Expect(concurrent.Run(ctx)).To(Succeed())
+		Expect(concurrent.Report().Total().MessagesReceived).To(Equal(n * (n - 1) * (maxCount + 1)))
+	})
+
+	It("should not block on messages that a machine sends to itself with bounded buffers", func() {
+		// Each machine only exchanges messages with itself, and sends more
+		// of them than fit in its buffer before it handles any messages,
+		// which would deadlock if sending them blocked.
+		self := 3
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &selfPingMachine{
+				pingMachine: pingMachine{id: ids[i], ids: []ID{ids[i]}},
+				self:        self,
+			}
+		}
+		concurrent := NewConcurrentNetwork(machines, func([]Message) {})
+		concurrent.SetBufferSize(1)
+		concurrent.SetCaptureStats(true)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		Expect(concurrent.Run(ctx)).To(Succeed())
+		Expect(concurrent.Report().Total().MessagesReceived).To(Equal(n * self * (maxCount + 1)))
+	})
+
+	It("should stop once all of the machines have finished", func() {
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &countingMachine{
+				pingMachine: pingMachine{id: ids[i], ids: ids},
+				target:      n - 1,
+			}
+		}
+		concurrent := NewConcurrentNetwork(machines, func([]Message) {})
+		concurrent.SetCaptureStats(true)
+		Expect(concurrent.Run(context.Background())).To(Succeed())
+
+		Expect(concurrent.Unfinished()).To(BeEmpty())
+		outputs := concurrent.Outputs()
+		Expect(outputs).To(HaveLen(n))
+		for _, id := range ids {
+			Expect(outputs[id]).To(BeNumerically(">=", n-1))
+		}
+
+		// The run stops before all of the messages of a complete run have
+		// been delivered.
+		Expect(concurrent.Report().Total().MessagesReceived).To(BeNumerically("<", n*(n-1)*(maxCount+1)))
+	})
+
+	It("should return an error when a machine panics", func() {
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &panickingMachine{pingMachine{id: ids[i], ids: ids}}
+		}
+		concurrent := NewConcurrentNetwork(machines, func([]Message) {})
+		err := concurrent.Run(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid state"))
+	})
+
+	It("should stop when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		concurrent := NewConcurrentNetwork(newPingMachines(), func([]Message) {})
+		Expect(concurrent.Run(ctx)).To(Equal(context.Canceled))
+	})
+
+	It("should panic when the buffer size is negative", func() {
+		concurrent := NewConcurrentNetwork(newPingMachines(), func([]Message) {})
+		Expect(func() { concurrent.SetBufferSize(-1) }).To(Panic())
+	})
+})
@@ -1,46 +1,101 @@
 package mpcutil
 
 import (
-	"os"
+	"fmt"
+	"io/ioutil"
 	"reflect"
+	"strings"
 
 	"github.com/renproject/surge"
 )
 
+// DefaultSnapshotInterval is the number of messages between snapshots of the
+// machine states that a Debugger takes by default.
+const DefaultSnapshotInterval = 100
+
 // A Debugger provides functionality for loading debug states, and performing
 // debugging operations on the given debug state (which consists of a message
 // history and initial states for the machines).
+//
+// As messages are handled, the Debugger periodically takes snapshots of the
+// states of all of the machines. This allows the Debugger to move backwards
+// through the message history (see StepBack and Goto) by restoring the
+// nearest snapshot before the target position and handling messages from
+// there, instead of needing to handle all of the messages from the start.
 type Debugger struct {
-	messages []Message
-	machines []Machine
+	messages  []Message
+	machines  []Machine
+	indexOfID map[ID]int
 
 	pos     int
 	machbps []machineBreakPoint
 	msgbps  []messageBreakPoint
+
+	snapshotInterval int
+	snapshots        []snapshot
+}
+
+// A snapshot is the marshalled states of all of the machines at a given
+// position in the message history.
+type snapshot struct {
+	pos    int
+	states [][]byte
+}
+
+// A StateDiff is a difference between the states of a machine at two
+// positions in the message history. The path identifies the part of the state
+// that is different, for example "opener.shareBufs[0]", and the from and to
+// strings are the formatted values at the two positions.
+type StateDiff struct {
+	Path     string
+	From, To string
 }
 
 // NewDebugger creates a new Debugger from the file with the given filename.
 // The messageType and machineType arguments are used to know how to correctly
 // unmarshal the file.
 func NewDebugger(filename string, messageType, machineType interface{}) Debugger {
-	file, err := os.Open(filename)
+	initialStates, messages := loadDump(filename, messageType, machineType)
+	machines := unmarshalMachines(initialStates, machineType)
+
+	indexOfID := make(map[ID]int, len(machines))
+	for i, machine := range machines {
+		indexOfID[machine.ID()] = i
+	}
+
+	dbg := Debugger{
+		messages:  messages,
+		machines:  machines,
+		indexOfID: indexOfID,
+
+		pos:     0,
+		machbps: nil,
+		msgbps:  nil,
+
+		snapshotInterval: DefaultSnapshotInterval,
+		snapshots:        nil,
+	}
+	dbg.takeSnapshot()
+
+	return dbg
+}
+
+// loadDump reads the debug file with the given name, and returns the part of
+// the file that contains the initial states of the machines and the
+// unmarshalled message history.
+func loadDump(filename string, messageType, machineType interface{}) ([]byte, []Message) {
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		panic(err)
 	}
 
-	// Unmarshal machines.
-	buf := make([]byte, surge.MaxBytes)
-	_, err = file.Read(buf)
+	// Skip over the machines to find the start of the message history.
 	sl := reflect.New(reflect.SliceOf(reflect.TypeOf(machineType)))
-	buf, _, err = surge.Unmarshal(sl.Interface(), buf, surge.MaxBytes)
+	buf, _, err := surge.Unmarshal(sl.Interface(), data, surge.MaxBytes)
 	if err != nil {
 		panic(err)
 	}
-
-	var machines []Machine
-	for i := 0; i < reflect.Indirect(sl).Len(); i++ {
-		machines = append(machines, reflect.Indirect(sl).Index(i).Addr().Interface().(Machine))
-	}
+	initialStates := data[:len(data)-len(buf)]
 
 	// Unmarshal messages.
 	sl = reflect.New(reflect.SliceOf(reflect.TypeOf(messageType)))
@@ -54,19 +109,61 @@ func NewDebugger(filename string, messageType, machineType interface{}) Debugger
 		messages = append(messages, reflect.Indirect(sl).Index(i).Addr().Interface().(Message))
 	}
 
-	pos := 0
-	var machbps []machineBreakPoint
-	var msgbps []messageBreakPoint
+	return initialStates, messages
+}
+
+// unmarshalMachines returns new machines in the states given by the
+// marshalled initial states.
+func unmarshalMachines(initialStates []byte, machineType interface{}) []Machine {
+	sl := reflect.New(reflect.SliceOf(reflect.TypeOf(machineType)))
+	_, _, err := surge.Unmarshal(sl.Interface(), initialStates, surge.MaxBytes)
+	if err != nil {
+		panic(err)
+	}
 
-	return Debugger{messages, machines, pos, machbps, msgbps}
+	var machines []Machine
+	for i := 0; i < reflect.Indirect(sl).Len(); i++ {
+		machines = append(machines, reflect.Indirect(sl).Index(i).Addr().Interface().(Machine))
+	}
+	return machines
+}
+
+// SetSnapshotInterval sets the number of messages that are handled between
+// snapshots of the machine states. A smaller interval makes moving backwards
+// faster at the cost of more memory. Snapshots that have already been taken
+// are kept.
+//
+// Panics: This function will panic if the interval is less than 1.
+func (dbg *Debugger) SetSnapshotInterval(interval int) {
+	if interval < 1 {
+		panic(fmt.Sprintf("snapshot interval must be at least 1: got %v", interval))
+	}
+	dbg.snapshotInterval = interval
+}
+
+// Pos returns the current position in the message history, which is the
+// number of messages that have been handled.
+func (dbg Debugger) Pos() int {
+	return dbg.pos
+}
+
+// Len returns the number of messages in the message history.
+func (dbg Debugger) Len() int {
+	return len(dbg.messages)
 }
 
 // Step processes the next message in the message history. It returns true if
 // there are more messages in the hostory, and false otherwise
 func (dbg *Debugger) Step() bool {
+	if dbg.pos == len(dbg.messages) {
+		return false
+	}
 	msg := dbg.messages[dbg.pos]
-	_ = dbg.machines[msg.To()].Handle(msg)
+	_ = dbg.machines[dbg.indexOfID[msg.To()]].Handle(msg)
 	dbg.pos++
+	if dbg.pos%dbg.snapshotInterval == 0 {
+		dbg.takeSnapshot()
+	}
 
 	if dbg.pos == len(dbg.messages) {
 		return false
@@ -74,6 +171,245 @@ func (dbg *Debugger) Step() bool {
 	return true
 }
 
+// StepBack moves to the position before the current position, so that the
+// state of the machines is as it was before the last message was handled. It
+// returns false if the current position is already the start of the message
+// history, and true otherwise.
+func (dbg *Debugger) StepBack() bool {
+	if dbg.pos == 0 {
+		return false
+	}
+	return dbg.Goto(dbg.pos - 1)
+}
+
+// Goto moves to the given position in the message history, so that the state
+// of the machines is as it was after the given number of messages were
+// handled. The nearest snapshot at or before the position is restored and
+// then the remaining messages are handled; breakpoints are not triggered
+// while doing so. It returns false, without changing the position, if the
+// given position is not in the range [0, Len()], and true otherwise.
+func (dbg *Debugger) Goto(pos int) bool {
+	if pos < 0 || pos > len(dbg.messages) {
+		return false
+	}
+	if pos < dbg.pos || dbg.nearestSnapshot(pos).pos > dbg.pos {
+		dbg.restoreSnapshot(dbg.nearestSnapshot(pos))
+	}
+	for dbg.pos < pos {
+		dbg.Step()
+	}
+	return true
+}
+
+// MachineAt returns a copy of the machine with the given ID in its state at the
+// given position in the message history. The current position is unchanged.
+// The return value will be nil if there is no machine with the given ID or the
+// position is not in the range [0, Len()].
+func (dbg *Debugger) MachineAt(id ID, pos int) Machine {
+	i, ok := dbg.indexOfID[id]
+	if !ok || pos < 0 || pos > len(dbg.messages) {
+		return nil
+	}
+	current := dbg.pos
+	dbg.Goto(pos)
+	machine := copyMachine(dbg.machines[i])
+	dbg.Goto(current)
+	return machine
+}
+
+// Diff returns the differences between the states of the machine with the
+// given ID at the two given positions in the message history. The current
+// position is unchanged. Types from outside of this module, such as field
+// elements and curve points, are compared as a whole.
+//
+// Panics: This function will panic if there is no machine with the given ID,
+// or if either position is not in the range [0, Len()].
+func (dbg *Debugger) Diff(id ID, from, to int) []StateDiff {
+	if _, ok := dbg.indexOfID[id]; !ok {
+		panic(fmt.Sprintf("no machine with ID %v", id))
+	}
+	if from < 0 || from > len(dbg.messages) || to < 0 || to > len(dbg.messages) {
+		panic(fmt.Sprintf("invalid positions: %v and %v", from, to))
+	}
+	fromMachine, toMachine := dbg.MachineAt(id, from), dbg.MachineAt(id, to)
+	var diffs []StateDiff
+	diffValues("", reflect.ValueOf(fromMachine), reflect.ValueOf(toMachine), &diffs)
+	return diffs
+}
+
+func (dbg *Debugger) takeSnapshot() {
+	if len(dbg.snapshots) > 0 && dbg.snapshots[len(dbg.snapshots)-1].pos >= dbg.pos {
+		return
+	}
+	states := make([][]byte, len(dbg.machines))
+	for i, machine := range dbg.machines {
+		buf, err := surge.ToBinary(machine)
+		if err != nil {
+			panic(fmt.Sprintf("could not marshal machine: %v", err))
+		}
+		states[i] = buf
+	}
+	dbg.snapshots = append(dbg.snapshots, snapshot{pos: dbg.pos, states: states})
+}
+
+// nearestSnapshot returns the snapshot with the largest position that is not
+// after the given position. Snapshots are always taken in increasing order of
+// position, and there is always a snapshot for position 0.
+func (dbg Debugger) nearestSnapshot(pos int) snapshot {
+	nearest := dbg.snapshots[0]
+	for _, snap := range dbg.snapshots {
+		if snap.pos > pos {
+			break
+		}
+		nearest = snap
+	}
+	return nearest
+}
+
+func (dbg *Debugger) restoreSnapshot(snap snapshot) {
+	for i, machine := range dbg.machines {
+		if err := surge.FromBinary(machine, snap.states[i]); err != nil {
+			panic(fmt.Sprintf("could not unmarshal machine: %v", err))
+		}
+	}
+	dbg.pos = snap.pos
+}
+
+// copyMachine returns a deep copy of the given machine, which is assumed to be
+// a pointer.
+func copyMachine(machine Machine) Machine {
+	buf, err := surge.ToBinary(machine)
+	if err != nil {
+		panic(fmt.Sprintf("could not marshal machine: %v", err))
+	}
+	cpy := reflect.New(reflect.TypeOf(machine).Elem()).Interface().(Machine)
+	if err := surge.FromBinary(cpy, buf); err != nil {
+		panic(fmt.Sprintf("could not unmarshal machine: %v", err))
+	}
+	return cpy
+}
+
+// modulePath is the import path of this module; values of types from other
+// modules are compared as a whole when computing state diffs.
+const modulePath = "github.com/renproject/mpc"
+
+// diffValues appends the differences between the two given values, which must
+// have the same type, to the given list.
+func diffValues(path string, a, b reflect.Value, diffs *[]StateDiff) {
+	if a.IsValid() != b.IsValid() {
+		*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+		return
+	}
+	if !a.IsValid() {
+		return
+	}
+	if a.Type() != b.Type() {
+		*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+			}
+			return
+		}
+		diffValues(path, a.Elem(), b.Elem(), diffs)
+	case reflect.Struct:
+		if !strings.HasPrefix(a.Type().PkgPath(), modulePath) {
+			var inner []StateDiff
+			for i := 0; i < a.NumField(); i++ {
+				diffValues("", a.Field(i), b.Field(i), &inner)
+			}
+			if len(inner) > 0 {
+				*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+			}
+			return
+		}
+		for i := 0; i < a.NumField(); i++ {
+			name := a.Type().Field(i).Name
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValues(name, a.Field(i), b.Field(i), diffs)
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			*diffs = append(*diffs, StateDiff{
+				path + ".len",
+				fmt.Sprint(a.Len()),
+				fmt.Sprint(b.Len()),
+			})
+		}
+		l := a.Len()
+		if b.Len() < l {
+			l = b.Len()
+		}
+		for i := 0; i < l; i++ {
+			diffValues(fmt.Sprintf("%v[%v]", path, i), a.Index(i), b.Index(i), diffs)
+		}
+	case reflect.Map:
+		for _, key := range a.MapKeys() {
+			diffValues(fmt.Sprintf("%v[%v]", path, formatValue(key)), a.MapIndex(key), b.MapIndex(key), diffs)
+		}
+		for _, key := range b.MapKeys() {
+			if !a.MapIndex(key).IsValid() {
+				diffValues(fmt.Sprintf("%v[%v]", path, formatValue(key)), a.MapIndex(key), b.MapIndex(key), diffs)
+			}
+		}
+	case reflect.Bool:
+		if a.Bool() != b.Bool() {
+			*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a.Int() != b.Int() {
+			*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a.Uint() != b.Uint() {
+			*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+		}
+	case reflect.Float32, reflect.Float64:
+		if a.Float() != b.Float() {
+			*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+		}
+	case reflect.String:
+		if a.String() != b.String() {
+			*diffs = append(*diffs, StateDiff{path, formatValue(a), formatValue(b)})
+		}
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<none>"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// IDs returns the IDs of all of the machines, in the order that they were
+// saved in the debug file.
+func (dbg Debugger) IDs() []ID {
+	ids := make([]ID, len(dbg.machines))
+	for i, machine := range dbg.machines {
+		ids[i] = machine.ID()
+	}
+	return ids
+}
+
+// Message returns the message at the given position in the message history,
+// which is the message that will be handled next when the current position is
+// the given position. The return value will be nil if the position is not in
+// the range [0, Len()).
+func (dbg Debugger) Message(pos int) Message {
+	if pos < 0 || pos >= len(dbg.messages) {
+		return nil
+	}
+	return dbg.messages[pos]
+}
+
 // MachineByID returns the machine for the given ID in its current state.
 func (dbg Debugger) MachineByID(id ID) Machine {
 	for _, machine := range dbg.machines {
@@ -117,10 +453,17 @@ func (dbg *Debugger) SetMessageBreakPoint(pred func(Message) bool) {
 	dbg.msgbps = append(dbg.msgbps, bp)
 }
 
+// ClearBreakPoints removes all of the registered breakpoints, including those
+// that have already been triggered.
+func (dbg *Debugger) ClearBreakPoints() {
+	dbg.machbps = nil
+	dbg.msgbps = nil
+}
+
 // Continue handles messages either until a breakpoint is triggered or there
 // are no more messages to handle.
 func (dbg *Debugger) Continue() {
-	for {
+	for dbg.pos < len(dbg.messages) {
 		if dbg.machBpTriggered() || dbg.msgBpTriggered(dbg.messages[dbg.pos]) {
 			break
 		}
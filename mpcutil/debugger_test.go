@@ -0,0 +1,115 @@
+package mpcutil_test
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/mpcutil"
+
+	"github.com/renproject/surge"
+)
+
+var _ = Describe("Debugger", func() {
+	n := 5
+
+	ids := make([]ID, n)
+	for i := range ids {
+		ids[i] = ID(i + 1)
+	}
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "debugger")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	// NewDumpedDebugger runs a network of ping machines and returns a
+	// Debugger for the dump of the run.
+	NewDumpedDebugger := func() Debugger {
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &pingMachine{id: ids[i], ids: ids}
+		}
+		shuffleMsgs, _ := MessageShufflerDropper(ids, 0)
+		network := NewNetwork(machines, shuffleMsgs)
+		network.SetCaptureHist(true)
+		Expect(network.Run()).To(Succeed())
+
+		filename := filepath.Join(dir, "test.dump")
+		network.Dump(filename)
+		return NewDebugger(filename, pingMessage{}, pingMachine{})
+	}
+
+	// States returns the marshalled states of all of the machines at the
+	// current position of the Debugger.
+	States := func(dbg *Debugger) [][]byte {
+		bufs := make([][]byte, n)
+		for i, id := range ids {
+			buf, err := surge.ToBinary(dbg.MachineByID(id))
+			Expect(err).ToNot(HaveOccurred())
+			bufs[i] = buf
+		}
+		return bufs
+	}
+
+	It("should be able to move backwards and forwards through a run", func() {
+		dbg := NewDumpedDebugger()
+		dbg.SetSnapshotInterval(rand.Intn(5) + 1)
+		Expect(dbg.Len()).To(Equal(n * (n - 1) * (maxCount + 1)))
+
+		// Record the states of all machines at every position.
+		history := make([][][]byte, 0, dbg.Len()+1)
+		history = append(history, States(&dbg))
+		for dbg.Pos() < dbg.Len() {
+			dbg.Step()
+			history = append(history, States(&dbg))
+		}
+
+		for i := 0; i < 20; i++ {
+			pos := rand.Intn(dbg.Len() + 1)
+			Expect(dbg.Goto(pos)).To(BeTrue())
+			Expect(dbg.Pos()).To(Equal(pos))
+			Expect(States(&dbg)).To(Equal(history[pos]))
+		}
+		Expect(dbg.Goto(-1)).To(BeFalse())
+		Expect(dbg.Goto(dbg.Len() + 1)).To(BeFalse())
+
+		Expect(dbg.Goto(dbg.Len())).To(BeTrue())
+		for pos := dbg.Len() - 1; pos >= 0; pos-- {
+			Expect(dbg.StepBack()).To(BeTrue())
+			Expect(States(&dbg)).To(Equal(history[pos]))
+		}
+		Expect(dbg.StepBack()).To(BeFalse())
+	})
+
+	It("should report the differences between the states of a machine", func() {
+		dbg := NewDumpedDebugger()
+
+		// Every machine other than machine 3 receives a message from machine
+		// 3 during the run, and the position is not changed by a diff.
+		pos := rand.Intn(dbg.Len() + 1)
+		Expect(dbg.Goto(pos)).To(BeTrue())
+		id := ids[rand.Intn(2)]
+		Expect(dbg.Diff(id, pos, pos)).To(BeEmpty())
+		Expect(dbg.Diff(id, 0, dbg.Len())).To(Equal([]StateDiff{
+			{Path: "seenThree", From: "false", To: "true"},
+		}))
+		Expect(dbg.Diff(ids[2], 0, dbg.Len())).To(BeEmpty())
+		Expect(dbg.Pos()).To(Equal(pos))
+	})
+
+	It("should panic when the snapshot interval is less than 1", func() {
+		dbg := NewDumpedDebugger()
+		Expect(func() { dbg.SetSnapshotInterval(0) }).To(Panic())
+	})
+})
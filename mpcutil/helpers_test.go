@@ -0,0 +1,167 @@
+package mpcutil_test
+
+import (
+	. "github.com/renproject/mpc/mpcutil"
+
+	"github.com/renproject/surge"
+)
+
+// maxCount is the count at which the machines stop replying to messages.
+const maxCount = 4
+
+// A pingMessage is sent back and forth between two machines, with the count
+// increasing by one each time, until the count reaches maxCount.
+type pingMessage struct {
+	from, to ID
+	count    uint32
+}
+
+func (msg pingMessage) From() ID { return msg.from }
+func (msg pingMessage) To() ID   { return msg.to }
+
+func (msg pingMessage) SizeHint() int {
+	return msg.from.SizeHint() + msg.to.SizeHint() + surge.SizeHint(msg.count)
+}
+
+func (msg pingMessage) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.from.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.to.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.MarshalU32(msg.count, buf, rem)
+}
+
+func (msg *pingMessage) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.from.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = msg.to.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.UnmarshalU32(&msg.count, buf, rem)
+}
+
+// A pingMachine replies to every message that it receives. If it is faulty, it
+// panics when it receives a message from machine 2 with the maximum count
+// after having received any message from machine 3.
+type pingMachine struct {
+	id        ID
+	ids       []ID
+	faulty    bool
+	seenThree bool
+}
+
+func (m pingMachine) ID() ID { return m.id }
+
+func (m pingMachine) InitialMessages() []Message {
+	var msgs []Message
+	for _, id := range m.ids {
+		if id != m.id {
+			msgs = append(msgs, &pingMessage{from: m.id, to: id, count: 0})
+		}
+	}
+	return msgs
+}
+
+func (m *pingMachine) Handle(msg Message) []Message {
+	ping := msg.(*pingMessage)
+	if ping.from == 3 {
+		m.seenThree = true
+	}
+	if m.faulty && m.seenThree && ping.from == 2 && ping.count == maxCount {
+		panic("invalid state")
+	}
+	if ping.count == maxCount {
+		return nil
+	}
+	return []Message{&pingMessage{from: m.id, to: ping.from, count: ping.count + 1}}
+}
+
+func (m pingMachine) SizeHint() int {
+	return m.id.SizeHint() +
+		surge.SizeHint(m.ids) +
+		surge.SizeHint(m.faulty) +
+		surge.SizeHint(m.seenThree)
+}
+
+func (m pingMachine) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.id.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.ids, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(m.faulty, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.MarshalBool(m.seenThree, buf, rem)
+}
+
+func (m *pingMachine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.id.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.ids, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&m.faulty, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.UnmarshalBool(&m.seenThree, buf, rem)
+}
+
+// A countingMachine is a pingMachine that finishes once it has handled a given
+// number of messages, and whose output is the number of messages that it has
+// handled.
+type countingMachine struct {
+	pingMachine
+	handled, target int
+}
+
+func (m *countingMachine) Handle(msg Message) []Message {
+	m.handled++
+	return m.pingMachine.Handle(msg)
+}
+
+func (m countingMachine) Done() bool          { return m.handled >= m.target }
+func (m countingMachine) Result() interface{} { return m.handled }
+
+// A panickingMachine is a pingMachine that panics when it handles a message
+// with the maximum count.
+type panickingMachine struct {
+	pingMachine
+}
+
+func (m *panickingMachine) Handle(msg Message) []Message {
+	if msg.(*pingMessage).count == maxCount {
+		panic("invalid state")
+	}
+	return m.pingMachine.Handle(msg)
+}
+
+// A selfPingMachine is a pingMachine that also starts the given number of
+// exchanges with itself, like a machine that sends itself timer ticks.
+type selfPingMachine struct {
+	pingMachine
+	self int
+}
+
+func (m selfPingMachine) InitialMessages() []Message {
+	msgs := m.pingMachine.InitialMessages()
+	for i := 0; i < m.self; i++ {
+		msgs = append(msgs, &pingMessage{from: m.id, to: m.id, count: 0})
+	}
+	return msgs
+}
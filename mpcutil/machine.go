@@ -20,6 +20,74 @@ type Machine interface {
 	Handle(Message) []Message
 }
 
+// A Terminator is a Machine that can report when it has finished executing its
+// protocol. Implementing this interface is optional, but it allows a Network
+// or Scheduler to stop a run as soon as all of the honest machines have
+// finished, and to report the machines that did not finish.
+type Terminator interface {
+	// Done returns true if the machine has finished, that is, it has
+	// computed its output and does not need to handle any more messages.
+	Done() bool
+}
+
+// An Outputter is a Machine that has an output once it has finished executing
+// its protocol. Implementing this interface is optional, but it allows the
+// outputs of all of the machines to be collected by a Network or Scheduler
+// without knowing the concrete types of the machines.
+type Outputter interface {
+	// Result returns the output of the machine. The type of the output is
+	// determined by the protocol. A machine that never has an output, such
+	// as a trusted party that only helps the other machines, should return
+	// nil, in which case it is not included in the collected outputs.
+	Result() interface{}
+}
+
+// allDone returns true if every machine that is not excluded implements
+// Terminator and has finished.
+func allDone(machines []Machine, exclude func(ID) bool) bool {
+	for _, machine := range machines {
+		if exclude(machine.ID()) {
+			continue
+		}
+		t, ok := machine.(Terminator)
+		if !ok || !t.Done() {
+			return false
+		}
+	}
+	return true
+}
+
+// unfinished returns the IDs of the machines that implement Terminator and
+// have not finished.
+func unfinished(machines []Machine) []ID {
+	var ids []ID
+	for _, machine := range machines {
+		if t, ok := machine.(Terminator); ok && !t.Done() {
+			ids = append(ids, machine.ID())
+		}
+	}
+	return ids
+}
+
+// outputs returns the non nil outputs of the machines that implement
+// Outputter, and that have finished if they implement Terminator.
+func outputs(machines []Machine) map[ID]interface{} {
+	outputs := make(map[ID]interface{}, len(machines))
+	for _, machine := range machines {
+		o, ok := machine.(Outputter)
+		if !ok {
+			continue
+		}
+		if t, ok := machine.(Terminator); ok && !t.Done() {
+			continue
+		}
+		if output := o.Result(); output != nil {
+			outputs[machine.ID()] = output
+		}
+	}
+	return outputs
+}
+
 // An OfflineMachine represents a player that is offline. It does not send any
 // messages.
 type OfflineMachine ID
@@ -0,0 +1,137 @@
+package mpcutil
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoPanic is returned by Minimize when handling the message history in a
+// debug file does not cause any of the machines to panic.
+var ErrNoPanic = errors.New("message history does not cause a panic")
+
+// Minimize finds a small subsequence of the message history in the debug file
+// with the given filename that still causes a machine to panic in the same way
+// as the full message history, and saves it along with the initial states of
+// the machines to the file with the given output filename. The output file can
+// be loaded by a Debugger in the same way as the original file. Two panics are
+// considered to be the same if their values have the same string
+// representation. The return value is the number of messages in the minimized
+// message history.
+//
+// The subsequence is found using the delta debugging algorithm of Zeller and
+// Hildebrandt: the message history is split into chunks, and each chunk and the
+// complement of each chunk is replayed from the initial states. If one of
+// these causes the same panic, it replaces the message history, and otherwise
+// the message history is split into smaller chunks. The result is 1-minimal,
+// that is, removing any single message from it will not cause the same panic.
+// Since every candidate is replayed from the initial states, this can take a
+// while for long message histories.
+//
+// Panics: This function will panic if the debug file cannot be loaded using
+// the given message and machine types.
+func Minimize(filename, output string, messageType, machineType interface{}) (int, error) {
+	initialStates, messages := loadDump(filename, messageType, machineType)
+
+	failure, ok := replay(initialStates, machineType, messages)
+	if !ok {
+		return 0, ErrNoPanic
+	}
+	fails := func(msgs []Message) bool {
+		f, ok := replay(initialStates, machineType, msgs)
+		return ok && f == failure
+	}
+
+	minimized := ddmin(messages, fails)
+	dump(output, initialStates, minimized)
+
+	return len(minimized), nil
+}
+
+// replay handles the given messages in order, using new machines in the given
+// initial states. If one of the machines panics, the string representation of
+// the panic and true are returned, and otherwise false is returned.
+func replay(initialStates []byte, machineType interface{}, msgs []Message) (failure string, panicked bool) {
+	machines := unmarshalMachines(initialStates, machineType)
+	indexOfID := make(map[ID]int, len(machines))
+	for i, machine := range machines {
+		indexOfID[machine.ID()] = i
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			failure, panicked = fmt.Sprint(r), true
+		}
+	}()
+
+	for _, msg := range msgs {
+		i, ok := indexOfID[msg.To()]
+		if !ok {
+			continue
+		}
+		_ = machines[i].Handle(msg)
+	}
+
+	return "", false
+}
+
+// ddmin returns a 1-minimal subsequence of the given messages for which fails
+// returns true. It is assumed that fails returns true for the given messages.
+func ddmin(msgs []Message, fails func([]Message) bool) []Message {
+	n := 2
+	for len(msgs) >= 2 {
+		chunks := split(msgs, n)
+		reduced := false
+
+		for _, chunk := range chunks {
+			if fails(chunk) {
+				msgs, n, reduced = chunk, 2, true
+				break
+			}
+		}
+
+		// When there are only two chunks, the complements are the same as the
+		// chunks, and so they have already been checked.
+		if !reduced && n > 2 {
+			for i := range chunks {
+				comp := complement(chunks, i)
+				if fails(comp) {
+					msgs, n, reduced = comp, n-1, true
+					break
+				}
+			}
+		}
+
+		if !reduced {
+			if n >= len(msgs) {
+				break
+			}
+			n *= 2
+			if n > len(msgs) {
+				n = len(msgs)
+			}
+		}
+	}
+	return msgs
+}
+
+// split splits the given messages into n contiguous chunks whose sizes differ
+// by at most one.
+func split(msgs []Message, n int) [][]Message {
+	chunks := make([][]Message, n)
+	for i := range chunks {
+		chunks[i] = msgs[i*len(msgs)/n : (i+1)*len(msgs)/n]
+	}
+	return chunks
+}
+
+// complement returns the messages in all of the given chunks except for the
+// chunk with the given index, in order.
+func complement(chunks [][]Message, i int) []Message {
+	var msgs []Message
+	for j, chunk := range chunks {
+		if j != i {
+			msgs = append(msgs, chunk...)
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,95 @@
+package mpcutil_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/mpcutil"
+)
+
+var _ = Describe("Minimize", func() {
+	n := 5
+
+	var dir, wd string
+
+	// The network saves the debug file to the working directory when a
+	// machine panics, so the specs are run in a temporary directory.
+	BeforeEach(func() {
+		var err error
+		wd, err = os.Getwd()
+		Expect(err).ToNot(HaveOccurred())
+		dir, err = ioutil.TempDir("", "mpcutil")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Chdir(dir)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(wd)).To(Succeed())
+		os.RemoveAll(dir)
+	})
+
+	// runNetwork runs a network of ping machines, where the machine with ID 1 is
+	// faulty if the argument is true, and returns the name of the debug file
+	// and the length of its message history.
+	runNetwork := func(faulty bool) (string, int) {
+		ids := make([]ID, n)
+		for i := range ids {
+			ids[i] = ID(i + 1)
+		}
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &pingMachine{
+				id:     ids[i],
+				ids:    ids,
+				faulty: faulty && i == 0,
+			}
+		}
+		shuffleMsgs, _ := MessageShufflerDropper(ids, 0)
+		network := NewNetwork(machines, shuffleMsgs)
+		network.SetCaptureHist(true)
+		filename := filepath.Join(dir, "panic.dump")
+		if faulty {
+			Expect(network.Run()).ToNot(Succeed())
+		} else {
+			Expect(network.Run()).To(Succeed())
+			network.Dump(filename)
+		}
+		dbg := NewDebugger(filename, pingMessage{}, pingMachine{})
+		return filename, dbg.Len()
+	}
+
+	It("should reduce the message history to the messages that cause the panic", func() {
+		filename, l := runNetwork(true)
+		output := filepath.Join(dir, "minimized.dump")
+		m, err := Minimize(filename, output, pingMessage{}, pingMachine{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(2))
+		Expect(m).To(BeNumerically("<", l))
+
+		// The only 1-minimal history is a message from machine 3 followed by
+		// the last message from machine 2.
+		dbg := NewDebugger(output, pingMessage{}, pingMachine{})
+		Expect(dbg.Len()).To(Equal(2))
+		first, second := dbg.Message(0).(*pingMessage), dbg.Message(1).(*pingMessage)
+		Expect(first.From()).To(Equal(ID(3)))
+		Expect(first.To()).To(Equal(ID(1)))
+		Expect(second.From()).To(Equal(ID(2)))
+		Expect(second.To()).To(Equal(ID(1)))
+		Expect(second.count).To(Equal(uint32(maxCount)))
+
+		Expect(dbg.Step()).To(BeTrue())
+		Expect(func() { dbg.Step() }).To(PanicWith("invalid state"))
+	})
+
+	It("should return an error when the message history does not cause a panic", func() {
+		filename, _ := runNetwork(false)
+		output := filepath.Join(dir, "minimized.dump")
+		_, err := Minimize(filename, output, pingMessage{}, pingMachine{})
+		Expect(err).To(Equal(ErrNoPanic))
+		_, err = os.Stat(output)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
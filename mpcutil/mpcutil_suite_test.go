@@ -0,0 +1,13 @@
+package mpcutil_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMpcutil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mpcutil Suite")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"time"
 
 	"github.com/renproject/surge"
 )
@@ -41,10 +42,14 @@ type Network struct {
 	machines               []Machine
 	processMsgs            func([]Message)
 	indexOfID              map[ID]int
+	adversary              *Adversary
 
 	captureHist   bool
 	msgHist       []Message
 	initialStates []byte
+
+	captureStats bool
+	stats        statsRecorder
 }
 
 // NewNetwork creates a new Network object from the given machines and message
@@ -78,6 +83,7 @@ func NewNetwork(machines []Machine, processMsgs func([]Message)) Network {
 
 		processMsgs: processMsgs,
 		indexOfID:   indexOfID,
+		adversary:   nil,
 
 		// TODO: Try to do something clever with the first allocation size?
 		captureHist:   false,
@@ -93,11 +99,31 @@ func (net *Network) SetCaptureHist(b bool) {
 	net.captureHist = b
 }
 
+// SetCaptureStats sets whether the network will record the number of messages
+// and bytes sent and received by each machine in each round, and the time
+// spent by each machine handling messages. The recorded statistics can be
+// retrieved using Report after a run.
+func (net *Network) SetCaptureStats(b bool) {
+	net.captureStats = b
+}
+
+// SetAdversary sets the adversary that controls the corrupted machines during
+// a run. The adversary is applied to the messages for each round before the
+// message processing function.
+func (net *Network) SetAdversary(adv *Adversary) {
+	net.adversary = adv
+}
+
 // Run drives an execution of the network of machines to completion. The run
-// will continue until there are no more messages to deliver. An error is
-// returned indicating the success of the run; if message history is being
-// captured, an error will be returned if any of the machines panic when
-// handling a message. In all other cases, a nil error is returned.
+// will continue until there are no more messages to deliver, or until the end
+// of a round in which all of the honest machines have finished, if all of the
+// honest machines implement Terminator. In the latter case, the messages that
+// were sent in that round are never delivered, and so they are not included
+// in the statistics. The honest machines are those that have not been
+// corrupted by the adversary, if there is one. An error is returned
+// indicating the success of the run; if message history is being captured,
+// an error will be returned if any of the machines panic when handling a
+// message. In all other cases, a nil error is returned.
 func (net *Network) Run() error {
 	// Fill the message buffer with the first messages.
 	net.msgBufCurr = net.msgBufCurr[:0]
@@ -107,6 +133,10 @@ func (net *Network) Run() error {
 			net.msgBufCurr = append(net.msgBufCurr, messages...)
 		}
 	}
+	round := 0
+	net.stats.reset()
+	net.applyAdversary(round)
+	net.recordSent()
 	net.processMsgs(net.msgBufCurr)
 
 	// Each loop is one round in the protocol.
@@ -136,9 +166,19 @@ func (net *Network) Run() error {
 			// All machines have finished sending messages.
 			break
 		}
+		if allDone(net.machines, net.isCorrupted) {
+			// All honest machines have finished, so the remaining messages
+			// can not change the outcome of the run. They are discarded
+			// without being delivered or recorded in the statistics.
+			net.msgBufNext = net.msgBufNext[:0]
+			break
+		}
 
 		// switch message buffers
 		net.msgBufCurr, net.msgBufNext = net.msgBufNext, net.msgBufCurr[:0]
+		round++
+		net.applyAdversary(round)
+		net.recordSent()
 
 		// Do any processing on the messages for the next round here, e.g.
 		// shuffling.
@@ -148,6 +188,72 @@ func (net *Network) Run() error {
 	return nil
 }
 
+// Unfinished returns the IDs of the machines that implement Terminator and
+// had not finished by the end of the run. Corrupted machines are not
+// included, since the run does not wait for them to finish.
+func (net Network) Unfinished() []ID {
+	var ids []ID
+	for _, id := range unfinished(net.machines) {
+		if !net.isCorrupted(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Outputs returns the outputs of the machines that implement Outputter,
+// indexed by ID. Machines that also implement Terminator are only included if
+// they have finished. The outputs of corrupted machines are not included.
+func (net Network) Outputs() map[ID]interface{} {
+	outputs := outputs(net.machines)
+	for id := range outputs {
+		if net.isCorrupted(id) {
+			delete(outputs, id)
+		}
+	}
+	return outputs
+}
+
+// Report returns the statistics that were recorded during the last run. The
+// report will be empty unless the statistics were captured, which can be set
+// using SetCaptureStats.
+func (net Network) Report() Report {
+	return net.stats.report(net.machines)
+}
+
+func (net Network) isCorrupted(id ID) bool {
+	return net.adversary != nil && net.adversary.IsCorrupted(id)
+}
+
+func (net *Network) applyAdversary(round int) {
+	if net.adversary == nil {
+		return
+	}
+	processed := net.adversary.Rush(round, net.msgBufCurr)
+	net.msgBufCurr = append(net.msgBufCurr[:0], processed...)
+}
+
+// recordSent records the messages that are due to be delivered in the current
+// round as having been sent, if statistics are being captured.
+func (net *Network) recordSent() {
+	if !net.captureStats {
+		return
+	}
+	net.stats.nextRound(len(net.machines))
+	for _, msg := range net.msgBufCurr {
+		if msg == nil {
+			continue
+		}
+		i, ok := net.indexOfID[msg.From()]
+		if !ok {
+			continue
+		}
+		counts := net.stats.counts(i)
+		counts.MessagesSent++
+		counts.BytesSent += msg.SizeHint()
+	}
+}
+
 func (net *Network) deliver(msg Message) (err error) {
 	err = nil
 
@@ -165,7 +271,16 @@ func (net *Network) deliver(msg Message) (err error) {
 		}()
 	}
 
-	res := net.machines[net.indexOfID[msg.To()]].Handle(msg)
+	i := net.indexOfID[msg.To()]
+	if net.captureStats {
+		counts := net.stats.counts(i)
+		counts.MessagesReceived++
+		counts.BytesReceived += msg.SizeHint()
+		start := time.Now()
+		defer func() { counts.HandleTime += time.Since(start) }()
+	}
+
+	res := net.machines[i].Handle(msg)
 	if res != nil {
 		net.msgBufNext = append(net.msgBufNext, res...)
 	}
@@ -177,6 +292,10 @@ func (net *Network) deliver(msg Message) (err error) {
 // file with the given name. This file can be loaded by a Debugger to start a
 // debugging session.
 func (net Network) Dump(filename string) {
+	dump(filename, net.initialStates, net.msgHist)
+}
+
+func dump(filename string, initialStates []byte, msgHist []Message) {
 	file, err := os.Create(filename)
 	if err != nil {
 		fmt.Printf("unable to create dump file: %v", err)
@@ -186,12 +305,12 @@ func (net Network) Dump(filename string) {
 	fmt.Printf("dumping debug state to file %s\n", filename)
 
 	// Write machine initial states.
-	_, err = file.Write(net.initialStates)
+	_, err = file.Write(initialStates)
 	if err != nil {
 		fmt.Printf("unable to write initial states to file: %v", err)
 	}
 
-	buf, err := surge.ToBinary(net.msgHist)
+	buf, err := surge.ToBinary(msgHist)
 	if err != nil {
 		fmt.Printf("unable to marshal message history: %v", err)
 	}
@@ -207,9 +326,16 @@ func (net Network) Dump(filename string) {
 // messages to or from these machines will be dropped. The message order will
 // also be shuffled each round.
 func MessageShufflerDropper(ids []ID, offline int) (func([]Message), map[ID]bool) {
+	return MessageShufflerDropperWithRand(rand.New(rand.NewSource(rand.Int63())), ids, offline)
+}
+
+// MessageShufflerDropperWithRand is the same as MessageShufflerDropper, except
+// that the offline machines and the order of the messages are chosen using the
+// given source of randomness, so that a run can be reproduced from its seed.
+func MessageShufflerDropperWithRand(r *rand.Rand, ids []ID, offline int) (func([]Message), map[ID]bool) {
 	shufIDs := make([]ID, len(ids))
 	copy(shufIDs, ids)
-	rand.Shuffle(len(shufIDs), func(i, j int) {
+	r.Shuffle(len(shufIDs), func(i, j int) {
 		shufIDs[i], shufIDs[j] = shufIDs[j], shufIDs[i]
 	})
 	isOffline := make(map[ID]bool)
@@ -221,7 +347,7 @@ func MessageShufflerDropper(ids []ID, offline int) (func([]Message), map[ID]bool
 	}
 
 	shuffleMsgs := func(msgs []Message) {
-		rand.Shuffle(len(msgs), func(i, j int) {
+		r.Shuffle(len(msgs), func(i, j int) {
 			msgs[i], msgs[j] = msgs[j], msgs[i]
 		})
 
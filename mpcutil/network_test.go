@@ -0,0 +1,139 @@
+package mpcutil_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/mpcutil"
+)
+
+var _ = Describe("Network", func() {
+	n := 3
+
+	newMachines := func(target int) []Machine {
+		ids := make([]ID, n)
+		for i := range ids {
+			ids[i] = ID(i + 1)
+		}
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &countingMachine{
+				pingMachine: pingMachine{id: ids[i], ids: ids},
+				target:      target,
+			}
+		}
+		return machines
+	}
+
+	It("should stop once all of the machines have finished", func() {
+		machines := newMachines(n - 1)
+		network := NewNetwork(machines, func([]Message) {})
+		network.SetCaptureStats(true)
+		Expect(network.Run()).To(Succeed())
+
+		// Each machine receives n-1 messages in each round, so the run ends
+		// after the first round even though replies are still pending.
+		Expect(network.Unfinished()).To(BeEmpty())
+		outputs := network.Outputs()
+		Expect(outputs).To(HaveLen(n))
+		for _, machine := range machines {
+			Expect(outputs[machine.ID()]).To(Equal(n - 1))
+		}
+
+		// The pending replies are not delivered, and so they are not
+		// recorded as sent.
+		report := network.Report()
+		Expect(report.Rounds).To(Equal(1))
+		Expect(report.Total().MessagesSent).To(Equal(n * (n - 1)))
+		Expect(report.Total().MessagesReceived).To(Equal(n * (n - 1)))
+	})
+
+	It("should report the machines that did not finish", func() {
+		// Each machine handles maxCount+1 messages from each of the other
+		// machines, which is less than the target.
+		machines := newMachines(n * maxCount)
+		network := NewNetwork(machines, func([]Message) {})
+		Expect(network.Run()).To(Succeed())
+
+		Expect(network.Unfinished()).To(ConsistOf(ID(1), ID(2), ID(3)))
+		Expect(network.Outputs()).To(BeEmpty())
+	})
+
+	It("should not report or wait for the corrupted machines", func() {
+		// The honest machines each handle maxCount+1 messages from each
+		// other, but machine 3 only handles the two initial messages, since
+		// it is silent and so the exchanges with it end immediately.
+		machines := newMachines(maxCount + 1)
+		adversary := NewAdversary(1, SilentStrategy)
+		Expect(adversary.Corrupt(ID(3))).To(BeTrue())
+		network := NewNetwork(machines, func([]Message) {})
+		network.SetAdversary(&adversary)
+		Expect(network.Run()).To(Succeed())
+
+		Expect(machines[2].(*countingMachine).handled).To(Equal(n - 1))
+		Expect(network.Unfinished()).To(BeEmpty())
+		outputs := network.Outputs()
+		Expect(outputs).To(HaveLen(n - 1))
+		Expect(outputs).ToNot(HaveKey(ID(3)))
+	})
+
+	It("should record the messages and bytes for each machine in each round", func() {
+		ids := make([]ID, n)
+		for i := range ids {
+			ids[i] = ID(i + 1)
+		}
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &pingMachine{id: ids[i], ids: ids}
+		}
+		network := NewNetwork(machines, func([]Message) {})
+		network.SetCaptureStats(true)
+		Expect(network.Run()).To(Succeed())
+
+		// In each round, every machine sends a message with the next count to
+		// each of the other machines, until the count reaches maxCount.
+		size := (&pingMessage{}).SizeHint()
+		report := network.Report()
+		Expect(report.Rounds).To(Equal(maxCount + 1))
+		Expect(report.Stats).To(HaveLen(n * (maxCount + 1)))
+		for i, stats := range report.Stats {
+			Expect(stats.Round).To(Equal(i / n))
+			Expect(stats.ID).To(Equal(ids[i%n]))
+			Expect(stats.MessagesSent).To(Equal(n - 1))
+			Expect(stats.BytesSent).To(Equal((n - 1) * size))
+			Expect(stats.MessagesReceived).To(Equal(n - 1))
+			Expect(stats.BytesReceived).To(Equal((n - 1) * size))
+		}
+		Expect(report.Machine(ids[0]).MessagesSent).To(Equal((n - 1) * (maxCount + 1)))
+		Expect(report.Round(0).MessagesReceived).To(Equal(n * (n - 1)))
+		total := report.Total()
+		Expect(total.BytesSent).To(Equal(n * (n - 1) * (maxCount + 1) * size))
+		Expect(total.HandleTime).To(BeNumerically(">", 0))
+
+		var buf bytes.Buffer
+		Expect(report.WriteCSV(&buf)).To(Succeed())
+		records, err := csv.NewReader(&buf).ReadAll()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(HaveLen(len(report.Stats) + 1))
+		sent, bytesSent := strconv.Itoa(n-1), strconv.Itoa((n-1)*size)
+		Expect(records[1][:6]).To(Equal([]string{"0", "1", sent, bytesSent, sent, bytesSent}))
+
+		buf.Reset()
+		Expect(report.WriteJSON(&buf)).To(Succeed())
+		var decoded Report
+		Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(report))
+	})
+
+	It("should not record anything when the statistics are not captured", func() {
+		machines := newMachines(0)
+		network := NewNetwork(machines, func([]Message) {})
+		Expect(network.Run()).To(Succeed())
+		Expect(network.Report().Rounds).To(Equal(0))
+		Expect(network.Report().Stats).To(BeEmpty())
+	})
+})
@@ -0,0 +1,357 @@
+package mpcutil
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/renproject/surge"
+)
+
+// A Latency determines how long it takes for a message to be delivered after
+// it has been sent. It is given the source of randomness for the Scheduler
+// that is using it, so that a run is completely determined by the seed of the
+// Scheduler, and the message that is being sent, so that the latency can
+// depend on the contents of the message.
+type Latency func(*rand.Rand, Message) time.Duration
+
+// ConstantLatency returns a Latency for which every message takes the given
+// time to be delivered.
+func ConstantLatency(d time.Duration) Latency {
+	return func(*rand.Rand, Message) time.Duration { return d }
+}
+
+// UniformLatency returns a Latency for which the time taken to deliver a
+// message is uniformly distributed in the range [min, max].
+//
+// Panics: This function will panic if min is negative or greater than max.
+func UniformLatency(min, max time.Duration) Latency {
+	if min < 0 || min > max {
+		panic(fmt.Sprintf("invalid latency range: [%v, %v]", min, max))
+	}
+	return func(r *rand.Rand, _ Message) time.Duration {
+		return min + time.Duration(r.Int63n(int64(max-min)+1))
+	}
+}
+
+// ExponentialLatency returns a Latency for which the time taken to deliver a
+// message is the given minimum plus an exponentially distributed delay with
+// the given mean. This models a link with a fixed propagation delay and a
+// queueing delay that is occasionally large.
+//
+// Panics: This function will panic if either argument is negative.
+func ExponentialLatency(min, mean time.Duration) Latency {
+	if min < 0 || mean < 0 {
+		panic(fmt.Sprintf("invalid latency parameters: min = %v, mean = %v", min, mean))
+	}
+	return func(r *rand.Rand, _ Message) time.Duration {
+		return min + time.Duration(r.ExpFloat64()*float64(mean))
+	}
+}
+
+// WithJitter returns a Latency that is the same as the given Latency, except
+// that a delay uniformly distributed in the range [0, jitter] is added to
+// every message.
+//
+// Panics: This function will panic if the jitter is negative.
+func WithJitter(latency Latency, jitter time.Duration) Latency {
+	if jitter < 0 {
+		panic(fmt.Sprintf("jitter must not be negative: got %v", jitter))
+	}
+	return func(r *rand.Rand, msg Message) time.Duration {
+		return latency(r, msg) + time.Duration(r.Int63n(int64(jitter)+1))
+	}
+}
+
+// A Scheduler is used to simulate a network of distributed Machines, like a
+// Network, but with asynchronous message delivery instead of lock-step
+// rounds. The Scheduler has a simulated clock, and every message that is sent
+// is scheduled to be delivered at a later time determined by the Latency for
+// the link between the sender and the receiver. Messages are delivered in the
+// order of their delivery times, and so a message can overtake messages that
+// were sent before it, and the messages that were sent in response to a
+// message can be delivered before other messages from the same "round".
+//
+// Machines can also be scheduled to be offline during intervals of simulated
+// time. A message whose delivery time is in such an interval for the receiver
+// is dropped, and so a machine can go offline (and come back online) at any
+// point during the execution of a protocol. All randomness used by the
+// Scheduler is derived from its seed, so a run can be reproduced exactly.
+type Scheduler struct {
+	machines  []Machine
+	indexOfID map[ID]int
+
+	rand        *rand.Rand
+	latency     Latency
+	linkLatency map[link]Latency
+	offline     map[ID][]interval
+
+	started bool
+	now     time.Duration
+	seq     uint64
+	queue   eventQueue
+	dropped int
+
+	captureHist   bool
+	msgHist       []Message
+	initialStates []byte
+}
+
+// maxDuration is the largest representable time, used to denote a time that
+// is never reached.
+const maxDuration = time.Duration(1<<63 - 1)
+
+type link struct {
+	from, to ID
+}
+
+type interval struct {
+	start, end time.Duration
+}
+
+// NewScheduler creates a new Scheduler for the given machines. The given
+// latency will be used for all links, except for those that have been given a
+// specific latency using SetLinkLatency, and the given seed is used for all
+// random choices made during a run.
+func NewScheduler(machines []Machine, latency Latency, seed int64) Scheduler {
+	indexOfID := make(map[ID]int)
+	for i, machine := range machines {
+		if _, ok := indexOfID[machine.ID()]; ok {
+			panic(fmt.Sprintf("two machines can't have the same ID: found duplicate ID %v", machine.ID()))
+		}
+		indexOfID[machine.ID()] = i
+	}
+
+	// Save initial machine state.
+	buf, err := surge.ToBinary(machines)
+	if err != nil {
+		panic(err)
+	}
+
+	return Scheduler{
+		machines:  machines,
+		indexOfID: indexOfID,
+
+		rand:        rand.New(rand.NewSource(seed)),
+		latency:     latency,
+		linkLatency: make(map[link]Latency),
+		offline:     make(map[ID][]interval),
+
+		started: false,
+		now:     0,
+		seq:     0,
+		queue:   eventQueue{},
+		dropped: 0,
+
+		captureHist:   false,
+		msgHist:       nil,
+		initialStates: buf,
+	}
+}
+
+// SetCaptureHist sets wether the scheduler will capture the message history
+// and create a debug file on a panic. The message history is the messages in
+// the order that they were delivered, and so the debug file can be loaded by
+// a Debugger in the same way as for a Network.
+func (s *Scheduler) SetCaptureHist(b bool) {
+	s.captureHist = b
+}
+
+// SetLinkLatency sets the latency for messages sent from the machine with the
+// first given ID to the machine with the second given ID.
+func (s *Scheduler) SetLinkLatency(from, to ID, latency Latency) {
+	s.linkLatency[link{from, to}] = latency
+}
+
+// SetOffline schedules the machine with the given ID to be offline during the
+// interval [start, end) of simulated time. An end time that is not after the
+// start time means that the machine never comes back online. The machine will
+// not receive any messages while it is offline; in particular, a machine that
+// is offline at time 0 will not send its initial messages.
+func (s *Scheduler) SetOffline(id ID, start, end time.Duration) {
+	if end <= start {
+		end = maxDuration
+	}
+	s.offline[id] = append(s.offline[id], interval{start, end})
+}
+
+// IsOffline returns true if the machine with the given ID is offline at the
+// given time, and false otherwise.
+func (s Scheduler) IsOffline(id ID, t time.Duration) bool {
+	for _, iv := range s.offline[id] {
+		if iv.start <= t && t < iv.end {
+			return true
+		}
+	}
+	return false
+}
+
+// Now returns the current simulated time, which is the delivery time of the
+// most recently delivered message.
+func (s Scheduler) Now() time.Duration {
+	return s.now
+}
+
+// Dropped returns the number of messages that have been dropped because the
+// receiver was offline.
+func (s Scheduler) Dropped() int {
+	return s.dropped
+}
+
+// Run drives an execution of the machines to completion. The run will continue
+// until there are no more messages to deliver, or until all of the machines
+// that are not offline for the rest of the run have finished, if they all
+// implement Terminator. The error return value is as for Network.Run.
+func (s *Scheduler) Run() error {
+	return s.RunUntil(maxDuration)
+}
+
+// RunUntil is the same as Run, except that it will stop once the next message
+// to be delivered has a delivery time after the given deadline. The remaining
+// messages are kept, and so the run can be continued with a later call to
+// RunUntil or Run.
+func (s *Scheduler) RunUntil(deadline time.Duration) error {
+	if !s.started {
+		s.started = true
+		for _, machine := range s.machines {
+			if s.IsOffline(machine.ID(), 0) {
+				continue
+			}
+			s.send(machine.InitialMessages())
+		}
+	}
+
+	for len(s.queue) > 0 && s.queue[0].at <= deadline {
+		if allDone(s.machines, s.neverOnline) {
+			break
+		}
+		ev := heap.Pop(&s.queue).(event)
+		s.now = ev.at
+		if s.IsOffline(ev.msg.To(), ev.at) {
+			s.dropped++
+			continue
+		}
+
+		// Add the about to be delivered message to the history.
+		if s.captureHist {
+			s.msgHist = append(s.msgHist, ev.msg)
+		}
+
+		err := s.deliver(ev.msg)
+		if err != nil && s.captureHist {
+			// If we get here then the machine we just tried to deliver the
+			// message to panicked.
+			s.Dump("panic.dump")
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unfinished returns the IDs of the machines that implement Terminator and
+// have not finished.
+func (s Scheduler) Unfinished() []ID {
+	return unfinished(s.machines)
+}
+
+// Outputs returns the outputs of the machines that implement Outputter,
+// indexed by ID. Machines that also implement Terminator are only included if
+// they have finished.
+func (s Scheduler) Outputs() map[ID]interface{} {
+	return outputs(s.machines)
+}
+
+// neverOnline returns true if the machine with the given ID is offline from
+// the current time onwards, in which case it can never finish.
+func (s Scheduler) neverOnline(id ID) bool {
+	for _, iv := range s.offline[id] {
+		if iv.start <= s.now && iv.end == maxDuration {
+			return true
+		}
+	}
+	return false
+}
+
+// send schedules the given messages to be delivered, ignoring nil messages.
+func (s *Scheduler) send(msgs []Message) {
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		latency, ok := s.linkLatency[link{msg.From(), msg.To()}]
+		if !ok {
+			latency = s.latency
+		}
+		delay := latency(s.rand, msg)
+		if delay < 0 {
+			delay = 0
+		}
+		heap.Push(&s.queue, event{at: s.now + delay, seq: s.seq, msg: msg})
+		s.seq++
+	}
+}
+
+func (s *Scheduler) deliver(msg Message) (err error) {
+	err = nil
+
+	if s.captureHist {
+		// Catch any panics and create debug file if they occur.
+		defer func() {
+			r := recover()
+			if r != nil {
+				if e, ok := r.(error); ok {
+					err = e
+				} else {
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}
+		}()
+	}
+
+	s.send(s.machines[s.indexOfID[msg.To()]].Handle(msg))
+
+	return
+}
+
+// Dump saves the initial state of the machines and the message history to the
+// file with the given name. This file can be loaded by a Debugger to start a
+// debugging session.
+func (s Scheduler) Dump(filename string) {
+	dump(filename, s.initialStates, s.msgHist)
+}
+
+// An event is the delivery of a message at a given time. The sequence number
+// is used to break ties between events with the same time so that the order
+// of delivery is deterministic.
+type event struct {
+	at  time.Duration
+	seq uint64
+	msg Message
+}
+
+// An eventQueue is a priority queue of events ordered by time, implementing
+// the heap.Interface interface.
+type eventQueue []event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,127 @@
+package mpcutil_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/mpcutil"
+)
+
+var _ = Describe("Scheduler", func() {
+	n := 3
+	d := time.Millisecond
+
+	ids := make([]ID, n)
+	for i := range ids {
+		ids[i] = ID(i + 1)
+	}
+
+	// newMachines returns machines that finish once they have handled the
+	// given number of messages.
+	newMachines := func(target int) []Machine {
+		machines := make([]Machine, n)
+		for i := range machines {
+			machines[i] = &countingMachine{
+				pingMachine: pingMachine{id: ids[i], ids: ids},
+				target:      target,
+			}
+		}
+		return machines
+	}
+
+	handled := func(machine Machine) int {
+		return machine.(*countingMachine).handled
+	}
+
+	// Each machine handles maxCount+1 messages from each of the other
+	// machines in a complete run.
+	total := (n - 1) * (maxCount + 1)
+
+	It("should deliver every message at the time given by the latency", func() {
+		machines := newMachines(total + 1)
+		scheduler := NewScheduler(machines, ConstantLatency(d), 0)
+		Expect(scheduler.Run()).To(Succeed())
+
+		for _, machine := range machines {
+			Expect(handled(machine)).To(Equal(total))
+		}
+		Expect(scheduler.Now()).To(Equal(time.Duration(maxCount+1) * d))
+		Expect(scheduler.Dropped()).To(Equal(0))
+	})
+
+	Context("deadlines", func() {
+		It("should only deliver the messages up to the deadline", func() {
+			machines := newMachines(total + 1)
+			scheduler := NewScheduler(machines, ConstantLatency(d), 0)
+
+			// The initial messages are sent, but none are delivered.
+			Expect(scheduler.RunUntil(d / 2)).To(Succeed())
+			Expect(scheduler.Now()).To(Equal(time.Duration(0)))
+			for _, machine := range machines {
+				Expect(handled(machine)).To(Equal(0))
+			}
+
+			// The messages with counts 0 and 1 are delivered at times d and
+			// 2d respectively.
+			Expect(scheduler.RunUntil(2 * d)).To(Succeed())
+			Expect(scheduler.Now()).To(Equal(2 * d))
+			for _, machine := range machines {
+				Expect(handled(machine)).To(Equal(2 * (n - 1)))
+			}
+
+			// Running until the same deadline again makes no progress.
+			Expect(scheduler.RunUntil(2 * d)).To(Succeed())
+			for _, machine := range machines {
+				Expect(handled(machine)).To(Equal(2 * (n - 1)))
+			}
+
+			// The remaining messages are kept for a later run.
+			Expect(scheduler.Run()).To(Succeed())
+			for _, machine := range machines {
+				Expect(handled(machine)).To(Equal(total))
+			}
+		})
+	})
+
+	Context("offline machines", func() {
+		It("should drop the messages that are delivered while the receiver is offline", func() {
+			machines := newMachines(total + 1)
+			scheduler := NewScheduler(machines, ConstantLatency(d), 0)
+
+			// The replies to the initial messages of machine 3 arrive at
+			// time 2d and are dropped, which ends those exchanges. The
+			// messages in the exchanges started by the other machines
+			// arrive at times d, 3d and 5d, and so are not affected.
+			scheduler.SetOffline(ids[2], 2*d, 3*d)
+			Expect(scheduler.IsOffline(ids[2], 2*d)).To(BeTrue())
+			Expect(scheduler.IsOffline(ids[2], 3*d)).To(BeFalse())
+			Expect(scheduler.Run()).To(Succeed())
+
+			Expect(scheduler.Dropped()).To(Equal(n - 1))
+			Expect(handled(machines[2])).To(Equal((n - 1) * (maxCount/2 + 1)))
+		})
+
+		It("should keep a machine offline forever when the end is not after the start", func() {
+			machines := newMachines(total - (maxCount + 1))
+			scheduler := NewScheduler(machines, ConstantLatency(d), 0)
+
+			scheduler.SetOffline(ids[2], 0, 0)
+			Expect(scheduler.IsOffline(ids[2], 0)).To(BeTrue())
+			Expect(scheduler.IsOffline(ids[2], time.Hour)).To(BeTrue())
+
+			scheduler.SetOffline(ids[1], 10*d, d)
+			Expect(scheduler.IsOffline(ids[1], 9*d)).To(BeFalse())
+			Expect(scheduler.IsOffline(ids[1], time.Hour)).To(BeTrue())
+
+			// Machine 3 does not send its initial messages, and the initial
+			// messages sent to it are dropped. The run stops once the other
+			// machines have finished, since machine 3 can never finish.
+			Expect(scheduler.Run()).To(Succeed())
+			Expect(scheduler.Dropped()).To(Equal(n - 1))
+			Expect(handled(machines[2])).To(Equal(0))
+			Expect(scheduler.Unfinished()).To(ConsistOf(ids[2]))
+			Expect(scheduler.Outputs()).To(HaveLen(n - 1))
+		})
+	})
+})
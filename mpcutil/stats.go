@@ -0,0 +1,166 @@
+package mpcutil
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Counts are the communication and computation costs for a machine, either
+// for a single round or accumulated over a number of rounds. The sizes of
+// messages are given by their SizeHint, which is the size of their marshalled
+// form. The handle time is the wall clock time spent in the Handle method of
+// the machine, which is a good approximation of the CPU time because the
+// Network delivers messages one at a time.
+type Counts struct {
+	MessagesSent     int           `json:"messagesSent"`
+	BytesSent        int           `json:"bytesSent"`
+	MessagesReceived int           `json:"messagesReceived"`
+	BytesReceived    int           `json:"bytesReceived"`
+	HandleTime       time.Duration `json:"handleTime"`
+}
+
+func (c *Counts) add(other Counts) {
+	c.MessagesSent += other.MessagesSent
+	c.BytesSent += other.BytesSent
+	c.MessagesReceived += other.MessagesReceived
+	c.BytesReceived += other.BytesReceived
+	c.HandleTime += other.HandleTime
+}
+
+// RoundStats are the Counts for the machine with the given ID in the given
+// round.
+type RoundStats struct {
+	Round int `json:"round"`
+	ID    ID  `json:"id"`
+	Counts
+}
+
+// A Report contains the statistics that were recorded during a run of a
+// Network. Rounds is the number of rounds in which messages were delivered
+// before the run ended. Stats contains an entry for every machine in every
+// round, ordered by round and then by the order of the machines in the
+// Network. A message is counted as sent in the round in which it is due to be
+// delivered, which for the initial messages is round 0, and so messages that
+// are dropped are counted as sent but not as received. Messages sent by the
+// adversary on behalf of corrupted machines are counted as sent by those
+// machines.
+type Report struct {
+	Rounds int          `json:"rounds"`
+	Stats  []RoundStats `json:"stats"`
+}
+
+// Machine returns the Counts for the machine with the given ID, accumulated
+// over all rounds.
+func (r Report) Machine(id ID) Counts {
+	var counts Counts
+	for _, stats := range r.Stats {
+		if stats.ID == id {
+			counts.add(stats.Counts)
+		}
+	}
+	return counts
+}
+
+// Round returns the Counts for the given round, accumulated over all
+// machines.
+func (r Report) Round(round int) Counts {
+	var counts Counts
+	for _, stats := range r.Stats {
+		if stats.Round == round {
+			counts.add(stats.Counts)
+		}
+	}
+	return counts
+}
+
+// Total returns the Counts accumulated over all machines and rounds.
+func (r Report) Total() Counts {
+	var counts Counts
+	for _, stats := range r.Stats {
+		counts.add(stats.Counts)
+	}
+	return counts
+}
+
+// WriteCSV writes the report to the given writer in CSV format. There is a
+// header line followed by one line for each entry in Stats. The handle time
+// is given in nanoseconds.
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"round",
+		"id",
+		"messages_sent",
+		"bytes_sent",
+		"messages_received",
+		"bytes_received",
+		"handle_time_ns",
+	})
+	if err != nil {
+		return err
+	}
+	for _, stats := range r.Stats {
+		err := cw.Write([]string{
+			strconv.Itoa(stats.Round),
+			strconv.Itoa(int(stats.ID)),
+			strconv.Itoa(stats.MessagesSent),
+			strconv.Itoa(stats.BytesSent),
+			strconv.Itoa(stats.MessagesReceived),
+			strconv.Itoa(stats.BytesReceived),
+			strconv.FormatInt(int64(stats.HandleTime), 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report to the given writer in JSON format. The handle
+// times are given in nanoseconds.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// statsRecorder records the Counts for each machine in each round of a run.
+type statsRecorder struct {
+	rounds [][]Counts
+}
+
+func (sr *statsRecorder) reset() {
+	sr.rounds = sr.rounds[:0]
+}
+
+// nextRound starts recording a new round for the given number of machines.
+func (sr *statsRecorder) nextRound(n int) {
+	sr.rounds = append(sr.rounds, make([]Counts, n))
+}
+
+// counts returns the Counts for the machine with the given index in the
+// current round.
+func (sr *statsRecorder) counts(i int) *Counts {
+	return &sr.rounds[len(sr.rounds)-1][i]
+}
+
+func (sr statsRecorder) report(machines []Machine) Report {
+	report := Report{
+		Rounds: len(sr.rounds),
+		Stats:  make([]RoundStats, 0, len(sr.rounds)*len(machines)),
+	}
+	for round, counts := range sr.rounds {
+		for i, machine := range machines {
+			report.Stats = append(report.Stats, RoundStats{
+				Round:  round,
+				ID:     machine.ID(),
+				Counts: counts[i],
+			})
+		}
+	}
+	return report
+}
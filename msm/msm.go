@@ -0,0 +1,83 @@
+// Package msm provides multi-scalar multiplication, which computes a sum of
+// scaled points using far fewer point operations than scaling each of the
+// points and adding the results. It is used by the batched verifiers in this
+// library, which check a random linear combination of many group equations at
+// once.
+package msm
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/renproject/secp256k1"
+)
+
+// MultiScalarMul computes the sum of the given points each scaled by the
+// corresponding scalar, using the bucket method of Pippenger. The points may
+// include the point at infinity.
+//
+// Panics: This function will panic if the number of points is not equal to
+// the number of scalars.
+func MultiScalarMul(points []secp256k1.Point, scalars []secp256k1.Fn) secp256k1.Point {
+	if len(points) != len(scalars) {
+		panic(fmt.Sprintf(
+			"inconsistent number of points and scalars: %v points and %v scalars",
+			len(points), len(scalars),
+		))
+	}
+
+	c := bits.Len(uint(len(points))) - 3
+	if c < 2 {
+		c = 2
+	}
+	if c > 16 {
+		c = 16
+	}
+
+	scalarBytes := make([][32]byte, len(scalars))
+	for i := range scalars {
+		scalars[i].PutB32(scalarBytes[i][:])
+	}
+
+	acc := secp256k1.NewPointInfinity()
+	buckets := make([]secp256k1.Point, (1<<c)-1)
+	for w := (256+c-1)/c - 1; w >= 0; w-- {
+		for d := 0; d < c; d++ {
+			tmp := acc
+			acc.Add(&tmp, &tmp)
+		}
+
+		for b := range buckets {
+			buckets[b] = secp256k1.NewPointInfinity()
+		}
+		for i := range points {
+			digit := windowDigit(&scalarBytes[i], w*c, c)
+			if digit != 0 {
+				buckets[digit-1].Add(&buckets[digit-1], &points[i])
+			}
+		}
+
+		// The sum of (b+1)*buckets[b] is computed as the sum of the running
+		// suffix sums of the buckets.
+		running := secp256k1.NewPointInfinity()
+		windowSum := secp256k1.NewPointInfinity()
+		for b := len(buckets) - 1; b >= 0; b-- {
+			running.Add(&running, &buckets[b])
+			windowSum.Add(&windowSum, &running)
+		}
+		acc.Add(&acc, &windowSum)
+	}
+	return acc
+}
+
+// windowDigit returns the integer represented by the c bits of the given big
+// endian scalar starting from the given bit position, where position 0 is the
+// least significant bit.
+func windowDigit(bs *[32]byte, pos, c int) int {
+	digit := 0
+	for t := 0; t < c && pos+t < 256; t++ {
+		p := pos + t
+		digit |= int((bs[31-p/8]>>(p%8))&1) << t
+	}
+	return digit
+}
@@ -0,0 +1,72 @@
+package msm_test
+
+import (
+	"math/rand"
+
+	"github.com/renproject/secp256k1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/msm"
+)
+
+var _ = Describe("Multi-scalar multiplication", func() {
+	trials := 10
+
+	// NaiveSum scales each of the points individually and adds the results.
+	// The points at infinity are skipped, since scaling them does not give
+	// the point at infinity.
+	NaiveSum := func(points []secp256k1.Point, scalars []secp256k1.Fn) secp256k1.Point {
+		sum := secp256k1.NewPointInfinity()
+		var term secp256k1.Point
+		for i := range points {
+			if points[i].IsInfinity() {
+				continue
+			}
+			term.Scale(&points[i], &scalars[i])
+			sum.Add(&sum, &term)
+		}
+		return sum
+	}
+
+	Specify("the result should be the same as scaling and adding each point", func() {
+		for i := 0; i < trials; i++ {
+			l := rand.Intn(100)
+			points := make([]secp256k1.Point, l)
+			scalars := make([]secp256k1.Fn, l)
+			for j := range points {
+				points[j] = secp256k1.RandomPoint()
+				scalars[j] = secp256k1.RandomFn()
+			}
+			sum := MultiScalarMul(points, scalars)
+			expected := NaiveSum(points, scalars)
+			Expect(sum.Eq(&expected)).To(BeTrue())
+		}
+	})
+
+	Specify("points at infinity, repeated points and zero scalars should be handled", func() {
+		p := secp256k1.RandomPoint()
+		points := []secp256k1.Point{p, secp256k1.NewPointInfinity(), p, secp256k1.RandomPoint()}
+		scalars := []secp256k1.Fn{secp256k1.RandomFn(), secp256k1.RandomFn(), secp256k1.RandomFn(), {}}
+		sum := MultiScalarMul(points, scalars)
+		expected := NaiveSum(points, scalars)
+		Expect(sum.Eq(&expected)).To(BeTrue())
+
+		// A point scaled by a scalar and its negation sum to infinity.
+		var neg secp256k1.Fn
+		neg.Negate(&scalars[0])
+		sum = MultiScalarMul([]secp256k1.Point{p, p}, []secp256k1.Fn{scalars[0], neg})
+		Expect(sum.IsInfinity()).To(BeTrue())
+	})
+
+	Specify("an empty sum should be the point at infinity", func() {
+		sum := MultiScalarMul(nil, nil)
+		Expect(sum.IsInfinity()).To(BeTrue())
+	})
+
+	Specify("inconsistent numbers of points and scalars should panic", func() {
+		Expect(func() {
+			MultiScalarMul([]secp256k1.Point{secp256k1.RandomPoint()}, nil)
+		}).To(Panic())
+	})
+})
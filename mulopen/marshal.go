@@ -14,6 +14,8 @@ import (
 // SizeHint implements the surge.SizeHinter interface.
 func (mulopener MulOpener) SizeHint() int {
 	return surge.SizeHint(mulopener.shareBufs) +
+		surge.SizeHint(mulopener.msgBufs) +
+		surge.SizeHint(mulopener.optimistic) +
 		surge.SizeHint(mulopener.batchSize) +
 		surge.SizeHint(mulopener.k) +
 		surge.SizeHint(mulopener.aCommitmentBatch) +
@@ -29,6 +31,14 @@ func (mulopener MulOpener) Marshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, err
 	}
+	buf, rem, err = surge.Marshal(mulopener.msgBufs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.MarshalBool(mulopener.optimistic, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
 	buf, rem, err = surge.MarshalU32(mulopener.batchSize, buf, rem)
 	if err != nil {
 		return buf, rem, err
@@ -62,6 +72,14 @@ func (mulopener *MulOpener) Unmarshal(buf []byte, rem int) ([]byte, int, error)
 	if err != nil {
 		return buf, rem, err
 	}
+	buf, rem, err = surge.Unmarshal(&mulopener.msgBufs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.UnmarshalBool(&mulopener.optimistic, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
 	buf, rem, err = surge.UnmarshalU32(&mulopener.batchSize, buf, rem)
 	if err != nil {
 		return buf, rem, err
@@ -90,7 +108,7 @@ func (mulopener *MulOpener) Unmarshal(buf []byte, rem int) ([]byte, int, error)
 }
 
 // Generate implements the quick.Generator interface.
-func (mulopener MulOpener) Generate(_ *rand.Rand, size int) reflect.Value {
+func (mulopener MulOpener) Generate(rand *rand.Rand, size int) reflect.Value {
 	size /= 5
 	n := rand.Intn(size/2) + 1
 	k := uint32(rand.Intn(size/2) + 2)
@@ -98,10 +116,13 @@ func (mulopener MulOpener) Generate(_ *rand.Rand, size int) reflect.Value {
 	if batchSize == 0 {
 		batchSize++
 	}
+	optimistic := rand.Int()&1 == 1
 	shareBufs := make([]shamir.Shares, batchSize)
+	msgBufs := make([][]Message, batchSize)
 	numReceived := rand.Intn(n)
 	for i := range shareBufs {
 		shareBufs[i] = shamir.Shares{}
+		msgBufs[i] = []Message{}
 		for j := 0; j < numReceived; j++ {
 			shareBufs[i] = append(shareBufs[i],
 				shamir.Share{
@@ -109,6 +130,11 @@ func (mulopener MulOpener) Generate(_ *rand.Rand, size int) reflect.Value {
 					Value: secp256k1.RandomFn(),
 				},
 			)
+			if optimistic {
+				msg := Message{}.Generate(rand, size).Interface().(Message)
+				msg.VShare.Share = shareBufs[i][j]
+				msgBufs[i] = append(msgBufs[i], msg)
+			}
 		}
 	}
 	aCommitmentBatch := make([]shamir.Commitment, batchSize)
@@ -125,6 +151,8 @@ func (mulopener MulOpener) Generate(_ *rand.Rand, size int) reflect.Value {
 	h := secp256k1.RandomPoint()
 	mo := MulOpener{
 		shareBufs,
+		msgBufs,
+		optimistic,
 		batchSize,
 		k,
 		aCommitmentBatch,
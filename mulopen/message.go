@@ -7,7 +7,8 @@ import (
 )
 
 // The Message type that is sent between parties during an invocation of
-// multiply and open.
+// multiply and open. The proof is created for the statement given by
+// ProofStatement.
 type Message struct {
 	VShare     shamir.VerifiableShare
 	Commitment secp256k1.Point
@@ -1,19 +1,33 @@
 package mulopen
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
 
+	"github.com/renproject/mpc/msm"
 	"github.com/renproject/mpc/mulopen/mulzkp"
+	"github.com/renproject/mpc/mulopen/mulzkp/zkp"
 	"github.com/renproject/mpc/params"
+	"github.com/renproject/mpc/random"
 	"github.com/renproject/secp256k1"
 	"github.com/renproject/shamir"
 )
 
 // A MulOpener is a state machine that implements the multiply and open
 // protocol.
+//
+// A MulOpener can also be constructed in optimistic mode (see NewOptimistic).
+// In this mode, received messages are buffered without checking their ZKPs or
+// shares. Once enough messages have been received, the reconstructed products
+// and the ZKPs are all checked at once. Only if this check fails are the
+// messages checked individually, in which case the invalid messages are
+// removed from the buffer.
 type MulOpener struct {
 	shareBufs []shamir.Shares
+	msgBufs   [][]Message
 
+	optimistic                                             bool
 	batchSize, k                                           uint32
 	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment
 
@@ -28,6 +42,82 @@ func New(
 	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
 	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
 	indices []secp256k1.Fn, h secp256k1.Point,
+) (MulOpener, []Message) {
+	return newMulOpener(
+		random.Reader,
+		aShareBatch, bShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
+		indices, h, false,
+	)
+}
+
+// NewWithRand is the same as New, except that the randomness for the initial
+// message is read from the given source.
+//
+// Panics: This function will panic in the same cases as New, or if the source
+// of randomness returns an error.
+func NewWithRand(
+	r io.Reader,
+	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (MulOpener, []Message) {
+	return newMulOpener(
+		r,
+		aShareBatch, bShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
+		indices, h, false,
+	)
+}
+
+// NewOptimistic is the same as New, except that the returned MulOpener will
+// be in optimistic mode. In this mode, the ZKPs and shares in received
+// messages are not checked when they are handled. Instead, once enough
+// messages have been received, the reconstructed product and its
+// decommitment are checked against the combination of the product
+// commitments and the RZG commitment, and the ZKPs for the messages that were
+// used are checked, all together using a single randomised check that is
+// computed with one multi-scalar multiplication. Only if this check fails are
+// the messages checked individually. This means that
+// an invalid message will not necessarily cause an error when it is handled,
+// but it will never cause the wrong product to be output.
+func NewOptimistic(
+	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (MulOpener, []Message) {
+	return newMulOpener(
+		random.Reader,
+		aShareBatch, bShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
+		indices, h, true,
+	)
+}
+
+// NewOptimisticWithRand is the same as NewOptimistic, except that the
+// randomness for the initial message is read from the given source.
+//
+// Panics: This function will panic in the same cases as NewOptimistic, or if
+// the source of randomness returns an error.
+func NewOptimisticWithRand(
+	r io.Reader,
+	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (MulOpener, []Message) {
+	return newMulOpener(
+		r,
+		aShareBatch, bShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
+		indices, h, true,
+	)
+}
+
+func newMulOpener(
+	r io.Reader,
+	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point, optimistic bool,
 ) (MulOpener, []Message) {
 	if !params.ValidPedersenParameter(h) {
 		panic("insecure choice of pedersen parameter")
@@ -77,11 +167,21 @@ func New(
 
 	shareBufs := make([]shamir.Shares, batchSize)
 	for i := range shareBufs {
-		shareBufs[i] = make(shamir.Shares, 0, k)
+		shareBufs[i] = make(shamir.Shares, 0, 2*k-1)
+	}
+	msgBufs := make([][]Message, batchSize)
+	for i := range msgBufs {
+		if optimistic {
+			msgBufs[i] = make([]Message, 0, 2*k-1)
+		} else {
+			msgBufs[i] = []Message{}
+		}
 	}
 
 	mulopener := MulOpener{
 		shareBufs:          shareBufs,
+		msgBufs:            msgBufs,
+		optimistic:         optimistic,
 		batchSize:          uint32(batchSize),
 		k:                  uint32(2*k - 1),
 		aCommitmentBatch:   aCommitmentBatch,
@@ -95,11 +195,11 @@ func New(
 	messageBatch := make([]Message, batchSize)
 	for i := 0; i < batchSize; i++ {
 		product.Mul(&aShareBatch[i].Share.Value, &bShareBatch[i].Share.Value)
-		tau := secp256k1.RandomFn()
-		aShareCommitment := pedersenCommit(&aShareBatch[i].Share.Value, &aShareBatch[i].Decommitment, &h)
+		tau := random.Fn(r)
 		bShareCommitment := pedersenCommit(&bShareBatch[i].Share.Value, &bShareBatch[i].Decommitment, &h)
 		productShareCommitment := pedersenCommit(&product, &tau, &h)
-		proof := mulzkp.CreateProof(&h, &aShareCommitment, &bShareCommitment, &productShareCommitment,
+		statement := ProofStatement(aCommitmentBatch[i], bCommitmentBatch[i], index)
+		proof := mulzkp.CreateProofForStatementWithRand(r, statement, &h, &bShareCommitment, &productShareCommitment,
 			aShareBatch[i].Share.Value, bShareBatch[i].Share.Value,
 			aShareBatch[i].Decommitment, bShareBatch[i].Decommitment, tau,
 		)
@@ -135,7 +235,10 @@ func New(
 // product of the two input secrets, is computed and returned. If not enough
 // shares have been received, the return value will be nil. If the message
 // batch id invalid in any way, an error will be returned along with a nil
-// value.
+// value. In optimistic mode, the messages are only checked once enough of
+// them have been received, and so an error for an invalid message batch might
+// only be returned when a later message batch is handled; in this case, the
+// error corresponds to the message batch that was handled last.
 func (mulopener *MulOpener) HandleShareBatch(messageBatch []Message) ([]secp256k1.Fn, error) {
 	if uint32(len(messageBatch)) != mulopener.batchSize {
 		return nil, ErrIncorrectBatchSize
@@ -164,25 +267,13 @@ func (mulopener *MulOpener) HandleShareBatch(messageBatch []Message) ([]secp256k
 		}
 	}
 
+	if mulopener.optimistic {
+		return mulopener.handleShareBatchOptimistic(messageBatch)
+	}
+
 	for i := uint32(0); i < mulopener.batchSize; i++ {
-		aShareCommitment := polyEvalPoint(mulopener.aCommitmentBatch[i], index)
-		bShareCommitment := polyEvalPoint(mulopener.bCommitmentBatch[i], index)
-		if !mulzkp.Verify(
-			&mulopener.h, &aShareCommitment, &bShareCommitment, &messageBatch[i].Commitment,
-			&messageBatch[i].Proof,
-		) {
-			return nil, ErrInvalidZKP
-		}
-		var shareCommitment secp256k1.Point
-		rzgShareCommitment := polyEvalPoint(mulopener.rzgCommitmentBatch[i], index)
-		shareCommitment.Add(&messageBatch[i].Commitment, &rzgShareCommitment)
-
-		com := pedersenCommit(
-			&messageBatch[i].VShare.Share.Value, &messageBatch[i].VShare.Decommitment,
-			&mulopener.h,
-		)
-		if !shareCommitment.Eq(&com) {
-			return nil, ErrInvalidShares
+		if err := mulopener.checkMessage(&messageBatch[i], i); err != nil {
+			return nil, err
 		}
 	}
 
@@ -203,6 +294,202 @@ func (mulopener *MulOpener) HandleShareBatch(messageBatch []Message) ([]secp256k
 	return nil, nil
 }
 
+func (mulopener *MulOpener) handleShareBatchOptimistic(messageBatch []Message) ([]secp256k1.Fn, error) {
+	for i := range mulopener.shareBufs {
+		mulopener.shareBufs[i] = append(mulopener.shareBufs[i], messageBatch[i].VShare.Share)
+		mulopener.msgBufs[i] = append(mulopener.msgBufs[i], messageBatch[i])
+	}
+	if uint32(len(mulopener.shareBufs[0])) != mulopener.k {
+		return nil, nil
+	}
+
+	numShares := len(mulopener.shareBufs[0])
+	lambdas := lagrangeCoefficients(mulopener.shareBufs[0])
+	secrets := make([]secp256k1.Fn, mulopener.batchSize)
+
+	// For each batch element, the reconstructed product and decommitment
+	// should be consistent with the Lagrange combination of the product
+	// commitments and the constant term of the RZG commitment, and the ZKPs
+	// should show that the product commitments are correct. All of these
+	// checks are combined into one random linear combination that is computed
+	// with a single multi-scalar multiplication. The points a and b of each
+	// ZKP are evaluations of the commitments for a and b, and so instead of
+	// computing them, their coefficients are folded into coefficients for the
+	// points of these commitments.
+	var points []secp256k1.Point
+	var scalars []secp256k1.Fn
+	var coeffs zkp.Coefficients
+	var gCoeff, hCoeff, decommitment, weight, tmp secp256k1.Fn
+	for i := uint32(0); i < mulopener.batchSize; i++ {
+		aCommitment, bCommitment := mulopener.aCommitmentBatch[i], mulopener.bCommitmentBatch[i]
+		aCoeffs := make([]secp256k1.Fn, len(aCommitment))
+		bCoeffs := make([]secp256k1.Fn, len(bCommitment))
+		digest := commitmentDigest(aCommitment, bCommitment)
+		weight = secp256k1.RandomFn()
+		secrets[i].SetU16(0)
+		decommitment.SetU16(0)
+		for j, msg := range mulopener.msgBufs[i] {
+			tmp.Mul(&lambdas[j], &msg.VShare.Share.Value)
+			secrets[i].Add(&secrets[i], &tmp)
+			tmp.Mul(&lambdas[j], &msg.VShare.Decommitment)
+			decommitment.Add(&decommitment, &tmp)
+
+			index := msg.VShare.Share.Index
+			statement := proofStatement(&digest, &index)
+			points, scalars, coeffs = mulzkp.BatchTerms(points, scalars, statement, &msg.Commitment, &msg.Proof)
+			tmp.Mul(&weight, &lambdas[j])
+			coeffs.C.Add(&coeffs.C, &tmp)
+			points = append(points, msg.Commitment)
+			scalars = append(scalars, coeffs.C)
+			gCoeff.Add(&gCoeff, &coeffs.G)
+			hCoeff.Add(&hCoeff, &coeffs.H)
+			addPowers(aCoeffs, &coeffs.A, &index)
+			addPowers(bCoeffs, &coeffs.B, &index)
+		}
+		points = append(points, aCommitment...)
+		scalars = append(scalars, aCoeffs...)
+		points = append(points, bCommitment...)
+		scalars = append(scalars, bCoeffs...)
+		points = append(points, mulopener.rzgCommitmentBatch[i][0])
+		scalars = append(scalars, weight)
+		tmp.Mul(&weight, &secrets[i])
+		tmp.Negate(&tmp)
+		gCoeff.Add(&gCoeff, &tmp)
+		tmp.Mul(&weight, &decommitment)
+		tmp.Negate(&tmp)
+		hCoeff.Add(&hCoeff, &tmp)
+	}
+	var g secp256k1.Point
+	one := secp256k1.NewFnFromU16(1)
+	g.BaseExp(&one)
+	points = append(points, g, mulopener.h)
+	scalars = append(scalars, gCoeff, hCoeff)
+	sum := msm.MultiScalarMul(points, scalars)
+	if sum.IsInfinity() {
+		return secrets, nil
+	}
+
+	// At least one of the buffered message batches was invalid, so we fall
+	// back to checking the messages individually. Any player that sent an
+	// invalid message for one of the batch elements has its entire message
+	// batch removed from the buffers.
+	errs := make([]error, numShares)
+	for i := range mulopener.msgBufs {
+		for j := range mulopener.msgBufs[i] {
+			if errs[j] != nil {
+				continue
+			}
+			errs[j] = mulopener.checkMessage(&mulopener.msgBufs[i][j], uint32(i))
+		}
+	}
+	for i := range mulopener.shareBufs {
+		shareBuf := mulopener.shareBufs[i][:0]
+		msgBuf := mulopener.msgBufs[i][:0]
+		for j := range mulopener.msgBufs[i] {
+			if errs[j] == nil {
+				shareBuf = append(shareBuf, mulopener.shareBufs[i][j])
+				msgBuf = append(msgBuf, mulopener.msgBufs[i][j])
+			}
+		}
+		mulopener.shareBufs[i] = shareBuf
+		mulopener.msgBufs[i] = msgBuf
+	}
+
+	// The message batch that was just handled was the last one in the buffer
+	// before the invalid batches were removed.
+	return nil, errs[numShares-1]
+}
+
+// checkMessage checks the ZKP and the share in the given message for the
+// batch element with the given index.
+func (mulopener *MulOpener) checkMessage(msg *Message, i uint32) error {
+	index := msg.VShare.Share.Index
+	aShareCommitment := polyEvalPoint(mulopener.aCommitmentBatch[i], index)
+	bShareCommitment := polyEvalPoint(mulopener.bCommitmentBatch[i], index)
+	statement := ProofStatement(mulopener.aCommitmentBatch[i], mulopener.bCommitmentBatch[i], index)
+	if !mulzkp.VerifyForStatement(
+		statement, &mulopener.h, &aShareCommitment, &bShareCommitment, &msg.Commitment,
+		&msg.Proof,
+	) {
+		return ErrInvalidZKP
+	}
+	var shareCommitment secp256k1.Point
+	rzgShareCommitment := polyEvalPoint(mulopener.rzgCommitmentBatch[i], index)
+	shareCommitment.Add(&msg.Commitment, &rzgShareCommitment)
+
+	com := pedersenCommit(&msg.VShare.Share.Value, &msg.VShare.Decommitment, &mulopener.h)
+	if !shareCommitment.Eq(&com) {
+		return ErrInvalidShares
+	}
+	return nil
+}
+
+// ProofStatement returns the statement for the ZKP in a message from the
+// player with the given index for a batch element with the given commitments
+// for a and b (see mulzkp.CreateProofForStatement). The statement determines
+// the commitments to the shares of a and b of the player, which are the points
+// a and b of the ZKP, so that a verifier does not need to compute them to
+// compute the challenge.
+func ProofStatement(aCommitment, bCommitment shamir.Commitment, index secp256k1.Fn) []byte {
+	digest := commitmentDigest(aCommitment, bCommitment)
+	return proofStatement(&digest, &index)
+}
+
+// commitmentDigest returns the hash of the given commitments for a and b.
+func commitmentDigest(aCommitment, bCommitment shamir.Commitment) [32]byte {
+	buf := make([]byte, aCommitment.SizeHint()+bCommitment.SizeHint())
+	tail, rem, err := aCommitment.Marshal(buf, len(buf))
+	if err != nil {
+		panic("unreachable")
+	}
+	if _, _, err = bCommitment.Marshal(tail, rem); err != nil {
+		panic("unreachable")
+	}
+	return sha256.Sum256(buf)
+}
+
+func proofStatement(digest *[32]byte, index *secp256k1.Fn) []byte {
+	statement := make([]byte, 64)
+	copy(statement, digest[:])
+	index.PutB32(statement[32:])
+	return statement
+}
+
+// addPowers adds c*x^l to the lth coefficient for each of the given
+// coefficients, which gives the coefficients for the points of a commitment
+// when c is the coefficient for its evaluation at x.
+func addPowers(coeffs []secp256k1.Fn, c, x *secp256k1.Fn) {
+	pow := *c
+	for l := range coeffs {
+		coeffs[l].Add(&coeffs[l], &pow)
+		pow.Mul(&pow, x)
+	}
+}
+
+// lagrangeCoefficients computes the coefficients that are used to interpolate
+// the constant term of a polynomial from its values at the indices of the
+// given shares.
+func lagrangeCoefficients(shares shamir.Shares) []secp256k1.Fn {
+	var num, denom, tmp secp256k1.Fn
+	lambdas := make([]secp256k1.Fn, len(shares))
+	for i := range shares {
+		num.SetU16(1)
+		denom.SetU16(1)
+		for j := range shares {
+			if i == j {
+				continue
+			}
+			tmp.Negate(&shares[i].Index)
+			tmp.Add(&tmp, &shares[j].Index)
+			denom.Mul(&denom, &tmp)
+			num.Mul(&num, &shares[j].Index)
+		}
+		denom.Inverse(&denom)
+		lambdas[i].Mul(&num, &denom)
+	}
+	return lambdas
+}
+
 // TODO: This should probably be a function inside the shamir package.
 func polyEvalPoint(commitment shamir.Commitment, index secp256k1.Fn) secp256k1.Point {
 	var acc secp256k1.Point
@@ -0,0 +1,64 @@
+package mulopen_test
+
+import (
+	"testing"
+
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir/shamirutil"
+
+	. "github.com/renproject/mpc/mulopen"
+)
+
+// benchmarkHandleShareBatch measures the time it takes for a MulOpener to
+// handle the messages from the other players up to and including the one
+// that allows it to reconstruct the products.
+func benchmarkHandleShareBatch(bench *testing.B, optimistic bool) {
+	n, k, b := 30, 10, 10
+	indices := shamirutil.RandomIndices(n)
+	h := secp256k1.RandomPoint()
+	aShares, aCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+	bShares, bCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+	rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+
+	newMulOpener := New
+	if optimistic {
+		newMulOpener = NewOptimistic
+	}
+	messages := make([][]Message, 2*k-1)
+	for i := range messages {
+		_, messages[i] = newMulOpener(
+			aShares[i], bShares[i], rzgShares[i],
+			aCommitments, bCommitments, rzgCommitments,
+			indices, h,
+		)
+	}
+
+	bench.ResetTimer()
+	for iter := 0; iter < bench.N; iter++ {
+		bench.StopTimer()
+		mulopener, _ := newMulOpener(
+			aShares[0], bShares[0], rzgShares[0],
+			aCommitments, bCommitments, rzgCommitments,
+			indices, h,
+		)
+		bench.StartTimer()
+		for _, messageBatch := range messages[1:] {
+			output, err := mulopener.HandleShareBatch(messageBatch)
+			if err != nil {
+				bench.Fatal(err)
+			}
+			if output != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkHandleShareBatch(bench *testing.B) {
+	benchmarkHandleShareBatch(bench, false)
+}
+
+func BenchmarkHandleShareBatchOptimistic(bench *testing.B) {
+	benchmarkHandleShareBatch(bench, true)
+}
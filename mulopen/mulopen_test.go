@@ -1,6 +1,7 @@
 package mulopen_test
 
 import (
+	"fmt"
 	"math/rand"
 
 	. "github.com/onsi/ginkgo"
@@ -56,10 +57,10 @@ var _ = Describe("MulOpener", func() {
 		for i := 0; i < b; i++ {
 			product.Mul(&aShareBatch[i].Share.Value, &bShareBatch[i].Share.Value)
 			tau := secp256k1.RandomFn()
-			aShareCommitment := PolyEvalPoint(aCommitmentBatch[i], index)
 			bShareCommitment := PolyEvalPoint(bCommitmentBatch[i], index)
 			productShareCommitment := PedersenCommit(&product, &tau, &h)
-			proof := mulzkp.CreateProof(&h, &aShareCommitment, &bShareCommitment, &productShareCommitment,
+			statement := ProofStatement(aCommitmentBatch[i], bCommitmentBatch[i], index)
+			proof := mulzkp.CreateProofForStatement(statement, &h, &bShareCommitment, &productShareCommitment,
 				aShareBatch[i].Share.Value, bShareBatch[i].Share.Value,
 				aShareBatch[i].Decommitment, bShareBatch[i].Decommitment, tau,
 			)
@@ -99,8 +100,9 @@ var _ = Describe("MulOpener", func() {
 				// The ZKP should be valid.
 				aShareCommitment := PolyEvalPoint(aCommitments[i], index)
 				bShareCommitment := PolyEvalPoint(bCommitments[i], index)
-				Expect(mulzkp.Verify(
-					&h, &aShareCommitment, &bShareCommitment, &message.Commitment, &message.Proof,
+				statement := ProofStatement(aCommitments[i], bCommitments[i], index)
+				Expect(mulzkp.VerifyForStatement(
+					statement, &h, &aShareCommitment, &bShareCommitment, &message.Commitment, &message.Proof,
 				)).To(BeTrue())
 
 				// The share should be valid with respect to the associated
@@ -114,6 +116,30 @@ var _ = Describe("MulOpener", func() {
 		})
 	})
 
+	Context("seeded randomness", func() {
+		Specify("initial messages created from the same seed should be the same", func() {
+			n, k, b, indices, h := RandomTestParams()
+			playerInd := rand.Intn(n)
+			aShares, aCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			bShares, bCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+
+			seed := rand.Int63()
+			newWithSeed := func(seed int64) []Message {
+				_, messages := NewWithRand(
+					rand.New(rand.NewSource(seed)),
+					aShares[playerInd], bShares[playerInd], rzgShares[playerInd],
+					aCommitments, bCommitments, rzgCommitments,
+					indices, h,
+				)
+				return messages
+			}
+			messages := newWithSeed(seed)
+			Expect(newWithSeed(seed)).To(Equal(messages))
+			Expect(newWithSeed(seed + 1)).ToNot(Equal(messages))
+		})
+	})
+
 	Context("handling messages", func() {
 		Context("valid messages", func() {
 			Specify("there should be no error and the return value should be nil unless it can reconstruct",
@@ -248,6 +274,111 @@ var _ = Describe("MulOpener", func() {
 		})
 	})
 
+	Context("optimistic mode", func() {
+		Specify("valid messages should reconstruct the correct product", func() {
+			n, k, b, indices, h := RandomTestParams()
+			playerInd := rand.Intn(n)
+			index := indices[playerInd]
+			aShares, aCommitments, aSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			bShares, bCommitments, bSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+
+			mulopener, _ := NewOptimistic(
+				aShares[playerInd], bShares[playerInd], rzgShares[playerInd],
+				aCommitments, bCommitments, rzgCommitments,
+				indices, h,
+			)
+
+			count := 1
+			for i, ind := range indices {
+				if ind.Eq(&index) {
+					continue
+				}
+				messageBatch := MessageBatchFromPlayer(
+					b, h, ind,
+					aShares[i], bShares[i], rzgShares[i],
+					aCommitments, bCommitments,
+				)
+
+				output, err := mulopener.HandleShareBatch(messageBatch)
+				count++
+				Expect(err).To(BeNil())
+				if count == 2*k-1 {
+					var product secp256k1.Fn
+					for i, secret := range output {
+						product.Mul(&aSecrets[i], &bSecrets[i])
+						Expect(secret.Eq(&product)).To(BeTrue())
+					}
+					break
+				} else {
+					Expect(output).To(BeNil())
+				}
+			}
+		})
+
+		Specify("invalid messages should be dropped and the correct product reconstructed", func() {
+			n, k, b, indices, h := RandomTestParams()
+			playerInd := rand.Intn(n)
+			index := indices[playerInd]
+			aShares, aCommitments, aSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			bShares, bCommitments, bSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+
+			mulopener, _ := NewOptimistic(
+				aShares[playerInd], bShares[playerInd], rzgShares[playerInd],
+				aCommitments, bCommitments, rzgCommitments,
+				indices, h,
+			)
+
+			// Up to k-1 of the other players send invalid messages. For some
+			// of these, the commitment to the product and the share are
+			// changed consistently, so that only the ZKP is invalid.
+			numInvalid := shamirutil.RandRange(1, k-1)
+			var output []secp256k1.Fn
+			count := 0
+			for i, ind := range indices {
+				if ind.Eq(&index) {
+					continue
+				}
+				messageBatch := MessageBatchFromPlayer(
+					b, h, ind,
+					aShares[i], bShares[i], rzgShares[i],
+					aCommitments, bCommitments,
+				)
+				invalid := count < numInvalid
+				if invalid {
+					j := rand.Intn(b)
+					if rand.Int()&1 == 0 {
+						messageBatch[j].VShare.Share.Value = secp256k1.RandomFn()
+					} else {
+						var gPow secp256k1.Point
+						delta := secp256k1.RandomFn()
+						gPow.BaseExp(&delta)
+						messageBatch[j].Commitment.Add(&messageBatch[j].Commitment, &gPow)
+						messageBatch[j].VShare.Share.Value.Add(&messageBatch[j].VShare.Share.Value, &delta)
+					}
+				}
+				count++
+
+				var err error
+				output, err = mulopener.HandleShareBatch(messageBatch)
+				if err != nil {
+					Expect(invalid).To(BeTrue())
+				}
+				if output != nil {
+					break
+				}
+			}
+
+			Expect(output).ToNot(BeNil())
+			var product secp256k1.Fn
+			for i, secret := range output {
+				product.Mul(&aSecrets[i], &bSecrets[i])
+				Expect(secret.Eq(&product)).To(BeTrue())
+			}
+		})
+	})
+
 	Context("panics", func() {
 		Specify("insecure pedersen parameter", func() {
 			n, k, b, indices, h := RandomTestParams()
@@ -372,44 +503,48 @@ var _ = Describe("MulOpener", func() {
 		k := 4
 		b := 3
 
-		Specify("all honest nodes should reconstruct the product of the secrets", func() {
-			indices := shamirutil.RandomIndices(n)
-			h := secp256k1.RandomPoint()
-			machines := make([]mpcutil.Machine, n)
+		for _, optimistic := range []bool{false, true} {
+			optimistic := optimistic
 
-			aShares, aCommitments, aSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
-			bShares, bCommitments, bSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
-			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+			Specify(fmt.Sprintf("all honest nodes should reconstruct the product of the secrets (optimistic: %v)", optimistic), func() {
+				indices := shamirutil.RandomIndices(n)
+				h := secp256k1.RandomPoint()
+				machines := make([]mpcutil.Machine, n)
 
-			ids := make([]mpcutil.ID, n)
-			for i := range ids {
-				ids[i] = mpcutil.ID(i + 1)
-			}
+				aShares, aCommitments, aSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+				bShares, bCommitments, bSecrets := rkpgutil.RNGOutputBatch(indices, k, b, h)
+				rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
 
-			for i, id := range ids {
-				machine := mulopenutil.NewMachine(
-					aShares[i], bShares[i], rzgShares[i],
-					aCommitments, bCommitments, rzgCommitments,
-					ids, id, indices, h,
-				)
-				machines[i] = &machine
-			}
+				ids := make([]mpcutil.ID, n)
+				for i := range ids {
+					ids[i] = mpcutil.ID(i + 1)
+				}
 
-			shuffleMsgs, _ := mpcutil.MessageShufflerDropper(ids, 0)
-			network := mpcutil.NewNetwork(machines, shuffleMsgs)
-			network.SetCaptureHist(true)
-			err := network.Run()
-			Expect(err).ToNot(HaveOccurred())
+				for i, id := range ids {
+					machine := mulopenutil.NewMachine(
+						aShares[i], bShares[i], rzgShares[i],
+						aCommitments, bCommitments, rzgCommitments,
+						ids, id, indices, h, optimistic,
+					)
+					machines[i] = &machine
+				}
 
-			for i := 0; i < b; i++ {
-				var product secp256k1.Fn
-				product.Mul(&aSecrets[i], &bSecrets[i])
+				shuffleMsgs, _ := mpcutil.MessageShufflerDropper(ids, 0)
+				network := mpcutil.NewNetwork(machines, shuffleMsgs)
+				network.SetCaptureHist(true)
+				err := network.Run()
+				Expect(err).ToNot(HaveOccurred())
+
+				for i := 0; i < b; i++ {
+					var product secp256k1.Fn
+					product.Mul(&aSecrets[i], &bSecrets[i])
 
-				for _, machine := range machines {
-					output := machine.(*mulopenutil.Machine).Output[i]
-					Expect(output.Eq(&product)).To(BeTrue())
+					for _, machine := range machines {
+						output := machine.(*mulopenutil.Machine).Output[i]
+						Expect(output.Eq(&product)).To(BeTrue())
+					}
 				}
-			}
-		})
+			})
+		}
 	})
 })
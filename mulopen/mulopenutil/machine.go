@@ -1,8 +1,11 @@
 package mulopenutil
 
 import (
+	"io"
+
 	"github.com/renproject/mpc/mpcutil"
 	"github.com/renproject/mpc/mulopen"
+	"github.com/renproject/mpc/random"
 	"github.com/renproject/secp256k1"
 	"github.com/renproject/shamir"
 	"github.com/renproject/surge"
@@ -18,13 +21,38 @@ type Machine struct {
 }
 
 // NewMachine constructs a new honest machine for a multiply and open network
-// test. It will have the given inputs and ID.
+// test. It will have the given inputs and ID. If optimistic is true, the
+// machine will use a MulOpener in optimistic mode.
 func NewMachine(
 	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
 	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
 	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
+	optimistic bool,
 ) Machine {
-	mulopener, msgs := mulopen.New(
+	return NewMachineWithRand(
+		random.Reader,
+		aShareBatch, bShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
+		ids, ownID, indices, h,
+		optimistic,
+	)
+}
+
+// NewMachineWithRand is the same as NewMachine, except that the randomness for
+// the MulOpener is read from the given source.
+func NewMachineWithRand(
+	r io.Reader,
+	aShareBatch, bShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
+	optimistic bool,
+) Machine {
+	newMulOpener := mulopen.NewWithRand
+	if optimistic {
+		newMulOpener = mulopen.NewOptimisticWithRand
+	}
+	mulopener, msgs := newMulOpener(
+		r,
 		aShareBatch, bShareBatch, rzgShareBatch,
 		aCommitmentBatch, bCommitmentBatch, rzgCommitmentBatch,
 		indices, h,
@@ -109,3 +137,10 @@ func (m *Machine) Handle(msg mpcutil.Message) []mpcutil.Message {
 	}
 	return nil
 }
+
+// Done implements the mpcutil.Terminator interface.
+func (m Machine) Done() bool { return len(m.Output) != 0 }
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of opened products.
+func (m Machine) Result() interface{} { return m.Output }
@@ -13,8 +13,10 @@ package mulzkp
 
 import (
 	"crypto/sha256"
+	"io"
 
 	"github.com/renproject/mpc/mulopen/mulzkp/zkp"
+	"github.com/renproject/mpc/random"
 	"github.com/renproject/secp256k1"
 )
 
@@ -24,7 +26,20 @@ import (
 //		a = (alpha)G + (rho)H, and
 //		b = (beta)G + (sigma)H.
 func CreateProof(h, a, b, c *secp256k1.Point, alpha, beta, rho, sigma, tau secp256k1.Fn) Proof {
-	msg, w := zkp.New(h, b, alpha, beta, rho, sigma, tau)
+	return CreateProofWithRand(random.Reader, h, a, b, c, alpha, beta, rho, sigma, tau)
+}
+
+// CreateProofWithRand is the same as CreateProof, except that the randomness
+// for the proof is read from the given source.
+//
+// Panics: This function will panic if the source of randomness returns an
+// error.
+func CreateProofWithRand(
+	r io.Reader,
+	h, a, b, c *secp256k1.Point,
+	alpha, beta, rho, sigma, tau secp256k1.Fn,
+) Proof {
+	msg, w := zkp.NewWithRand(r, h, b, alpha, beta, rho, sigma, tau)
 	e := computeChallenge(a, b, c, &msg)
 	res := zkp.ResponseForChallenge(&w, &e)
 
@@ -43,6 +58,78 @@ func Verify(h, a, b, c *secp256k1.Point, p *Proof) bool {
 	return zkp.Verify(h, a, b, c, &p.msg, &p.res, &e)
 }
 
+// CreateProofForStatement is the same as CreateProof, except that the
+// challenge for the proof is computed from the given statement instead of the
+// points a and b. This allows the proof to be checked as part of a larger
+// batch without computing a and b (see BatchTerms), for example when they are
+// evaluations of polynomial commitments. The statement must uniquely determine
+// a and b, otherwise the proof is not sound.
+func CreateProofForStatement(
+	statement []byte,
+	h, b, c *secp256k1.Point,
+	alpha, beta, rho, sigma, tau secp256k1.Fn,
+) Proof {
+	return CreateProofForStatementWithRand(random.Reader, statement, h, b, c, alpha, beta, rho, sigma, tau)
+}
+
+// CreateProofForStatementWithRand is the same as CreateProofForStatement,
+// except that the randomness for the proof is read from the given source.
+//
+// Panics: This function will panic if the source of randomness returns an
+// error.
+func CreateProofForStatementWithRand(
+	r io.Reader,
+	statement []byte,
+	h, b, c *secp256k1.Point,
+	alpha, beta, rho, sigma, tau secp256k1.Fn,
+) Proof {
+	msg, w := zkp.NewWithRand(r, h, b, alpha, beta, rho, sigma, tau)
+	e := computeStatementChallenge(statement, c, &msg)
+	res := zkp.ResponseForChallenge(&w, &e)
+
+	return Proof{msg, res}
+}
+
+// VerifyForStatement is the same as Verify for a proof that was created with
+// CreateProofForStatement for the given statement.
+func VerifyForStatement(statement []byte, h, a, b, c *secp256k1.Point, p *Proof) bool {
+	e := computeStatementChallenge(statement, c, &p.msg)
+	return zkp.Verify(h, a, b, c, &p.msg, &p.res, &e)
+}
+
+// BatchTerms appends to the given points and scalars the terms of a random
+// linear combination of the verification equations for the given proof, which
+// was created with CreateProofForStatement for the given statement. The
+// coefficients of the points a, b and c and the generators G and H are
+// returned instead of being appended (see zkp.BatchTerms).
+func BatchTerms(
+	points []secp256k1.Point, scalars []secp256k1.Fn,
+	statement []byte, c *secp256k1.Point, p *Proof,
+) ([]secp256k1.Point, []secp256k1.Fn, zkp.Coefficients) {
+	e := computeStatementChallenge(statement, c, &p.msg)
+	return zkp.BatchTerms(points, scalars, &p.msg, &p.res, &e)
+}
+
+func computeStatementChallenge(statement []byte, c *secp256k1.Point, msg *zkp.Message) secp256k1.Fn {
+	l := len(statement) + c.SizeHint() + msg.SizeHint()
+	buf := make([]byte, l)
+	copy(buf, statement)
+
+	tail, rem, err := c.Marshal(buf[len(statement):], l-len(statement))
+	if err != nil {
+		panic("unreachable")
+	}
+	_, _, err = msg.Marshal(tail, rem)
+	if err != nil {
+		panic("unreachable")
+	}
+	hash := sha256.Sum256(buf)
+
+	var e secp256k1.Fn
+	_ = e.SetB32(hash[:])
+	return e
+}
+
 func computeChallenge(a, b, c *secp256k1.Point, msg *zkp.Message) secp256k1.Fn {
 	l := a.SizeHint() + b.SizeHint() + c.SizeHint() + msg.SizeHint()
 	buf := make([]byte, l)
@@ -73,3 +160,26 @@ func computeChallenge(a, b, c *secp256k1.Point, msg *zkp.Message) secp256k1.Fn {
 	_ = e.SetB32(hash[:])
 	return e
 }
+
+// BatchVerify verifies all of the given proofs at once. The ith proof is
+// checked against the ith elements of a, b and c as in Verify. The return
+// value will be true if all of the proofs are valid, and false if at least one
+// of them is not. The check is randomised and is cheaper than calling Verify
+// for each proof, but it does not identify which proofs are invalid.
+//
+// Panics: This function will panic if the given slices do not all have the
+// same length.
+func BatchVerify(h *secp256k1.Point, a, b, c []secp256k1.Point, proofs []Proof) bool {
+	if len(proofs) != len(a) {
+		panic("inconsistent number of proofs")
+	}
+	msgs := make([]zkp.Message, len(proofs))
+	ress := make([]zkp.Response, len(proofs))
+	es := make([]secp256k1.Fn, len(proofs))
+	for i := range proofs {
+		msgs[i] = proofs[i].msg
+		ress[i] = proofs[i].res
+		es[i] = computeChallenge(&a[i], &b[i], &c[i], &proofs[i].msg)
+	}
+	return zkp.BatchVerify(h, a, b, c, msgs, ress, es)
+}
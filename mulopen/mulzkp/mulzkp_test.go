@@ -1,6 +1,8 @@
 package mulzkp_test
 
 import (
+	"math/rand"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/renproject/mpc/mulopen/mulzkp"
@@ -66,4 +68,110 @@ var _ = Describe("NIZK", func() {
 			}
 		})
 	})
+
+	Context("proofs for statements", func() {
+		It("should accept correct proofs for the same statement", func() {
+			for i := 0; i < trials; i++ {
+				alpha, beta, rho, sigma, tau, a, b, h := RandomTestParams()
+				c := RandomCorrectC(alpha, beta, tau, h)
+				statement := make([]byte, 64)
+				rand.Read(statement)
+
+				proof := CreateProofForStatement(statement, &h, &b, &c, alpha, beta, rho, sigma, tau)
+				Expect(VerifyForStatement(statement, &h, &a, &b, &c, &proof)).To(BeTrue())
+				statement[rand.Intn(len(statement))]++
+				Expect(VerifyForStatement(statement, &h, &a, &b, &c, &proof)).To(BeFalse())
+			}
+		})
+
+		It("should reject incorrect proofs", func() {
+			for i := 0; i < trials; i++ {
+				alpha, beta, rho, sigma, tau, a, b, h := RandomTestParams()
+				c := secp256k1.RandomPoint()
+				statement := make([]byte, 64)
+				rand.Read(statement)
+
+				proof := CreateProofForStatement(statement, &h, &b, &c, alpha, beta, rho, sigma, tau)
+				Expect(VerifyForStatement(statement, &h, &a, &b, &c, &proof)).To(BeFalse())
+			}
+		})
+
+		It("should give batch terms that sum to infinity only for correct proofs", func() {
+			for i := 0; i < trials/10; i++ {
+				alpha, beta, rho, sigma, tau, a, b, h := RandomTestParams()
+				c := RandomCorrectC(alpha, beta, tau, h)
+				statement := make([]byte, 64)
+				rand.Read(statement)
+				proof := CreateProofForStatement(statement, &h, &b, &c, alpha, beta, rho, sigma, tau)
+
+				// Sum returns the sum of the batch terms for the proof with c
+				// as the product commitment.
+				Sum := func(c secp256k1.Point) secp256k1.Point {
+					points, scalars, coeffs := BatchTerms(nil, nil, statement, &c, &proof)
+					var g, sum, term secp256k1.Point
+					one := secp256k1.NewFnFromU16(1)
+					g.BaseExp(&one)
+					points = append(points, a, b, c, g, h)
+					scalars = append(scalars, coeffs.A, coeffs.B, coeffs.C, coeffs.G, coeffs.H)
+					sum = secp256k1.NewPointInfinity()
+					for j := range points {
+						term.Scale(&points[j], &scalars[j])
+						sum.Add(&sum, &term)
+					}
+					return sum
+				}
+				sum := Sum(c)
+				Expect(sum.IsInfinity()).To(BeTrue())
+				sum = Sum(secp256k1.RandomPoint())
+				Expect(sum.IsInfinity()).To(BeFalse())
+			}
+		})
+	})
+
+	Context("batch verifying proofs", func() {
+		RandomProofs := func(l int) (
+			secp256k1.Point, []secp256k1.Point, []secp256k1.Point, []secp256k1.Point, []Proof,
+		) {
+			h := secp256k1.RandomPoint()
+			as := make([]secp256k1.Point, l)
+			bs := make([]secp256k1.Point, l)
+			cs := make([]secp256k1.Point, l)
+			proofs := make([]Proof, l)
+			for i := 0; i < l; i++ {
+				alpha := secp256k1.RandomFn()
+				beta := secp256k1.RandomFn()
+				rho := secp256k1.RandomFn()
+				sigma := secp256k1.RandomFn()
+				tau := secp256k1.RandomFn()
+
+				var hPow secp256k1.Point
+				hPow.Scale(&h, &rho)
+				as[i].BaseExp(&alpha)
+				as[i].Add(&as[i], &hPow)
+
+				hPow.Scale(&h, &sigma)
+				bs[i].BaseExp(&beta)
+				bs[i].Add(&bs[i], &hPow)
+
+				cs[i] = RandomCorrectC(alpha, beta, tau, h)
+				proofs[i] = CreateProof(&h, &as[i], &bs[i], &cs[i], alpha, beta, rho, sigma, tau)
+			}
+			return h, as, bs, cs, proofs
+		}
+
+		It("should accept batches of correct proofs", func() {
+			for i := 0; i < trials/10; i++ {
+				h, as, bs, cs, proofs := RandomProofs(rand.Intn(10) + 1)
+				Expect(BatchVerify(&h, as, bs, cs, proofs)).To(BeTrue())
+			}
+		})
+
+		It("should reject batches that contain an incorrect proof", func() {
+			for i := 0; i < trials/10; i++ {
+				h, as, bs, cs, proofs := RandomProofs(rand.Intn(10) + 1)
+				cs[rand.Intn(len(cs))] = secp256k1.RandomPoint()
+				Expect(BatchVerify(&h, as, bs, cs, proofs)).To(BeFalse())
+			}
+		})
+	})
 })
@@ -4,6 +4,7 @@ import (
 	"math/rand"
 	"reflect"
 
+	"github.com/renproject/mpc/random"
 	"github.com/renproject/secp256k1"
 )
 
@@ -64,13 +65,13 @@ func (res *Response) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 }
 
 // Generate implements the quick.Generator interface.
-func (res Response) Generate(_ *rand.Rand, _ int) reflect.Value {
+func (res Response) Generate(rand *rand.Rand, _ int) reflect.Value {
 	r := Response{
-		y:  secp256k1.RandomFn(),
-		w:  secp256k1.RandomFn(),
-		z:  secp256k1.RandomFn(),
-		w1: secp256k1.RandomFn(),
-		w2: secp256k1.RandomFn(),
+		y:  random.Fn(rand),
+		w:  random.Fn(rand),
+		z:  random.Fn(rand),
+		w1: random.Fn(rand),
+		w2: random.Fn(rand),
 	}
 	return reflect.ValueOf(r)
 }
@@ -10,18 +10,37 @@
 // https://doi.org/10.1145/277697.277716
 package zkp
 
-import "github.com/renproject/secp256k1"
+import (
+	"io"
+
+	"github.com/renproject/mpc/msm"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+)
 
 // New constructs a new message and witness for the ZKP for the given
 // parameters.
 func New(h, b *secp256k1.Point, alpha, beta, rho, sigma, tau secp256k1.Fn) (Message, Witness) {
+	return NewWithRand(random.Reader, h, b, alpha, beta, rho, sigma, tau)
+}
+
+// NewWithRand is the same as New, except that the randomness for the witness
+// is read from the given source.
+//
+// Panics: This function will panic if the source of randomness returns an
+// error.
+func NewWithRand(
+	r io.Reader,
+	h, b *secp256k1.Point,
+	alpha, beta, rho, sigma, tau secp256k1.Fn,
+) (Message, Witness) {
 	msg := Message{}
 	w := Witness{
-		d:  secp256k1.RandomFn(),
-		s:  secp256k1.RandomFn(),
-		x:  secp256k1.RandomFn(),
-		s1: secp256k1.RandomFn(),
-		s2: secp256k1.RandomFn(),
+		d:  random.Fn(r),
+		s:  random.Fn(r),
+		x:  random.Fn(r),
+		s1: random.Fn(r),
+		s2: random.Fn(r),
 
 		alpha: alpha,
 		beta:  beta,
@@ -113,3 +132,106 @@ func Verify(h, a, b, c *secp256k1.Point, msg *Message, res *Response, e *secp256
 
 	return true
 }
+
+// Coefficients are the coefficients of the points that are not part of a
+// proof in a random linear combination of its verification equations (see
+// BatchTerms).
+type Coefficients struct {
+	A, B, C, G, H secp256k1.Fn
+}
+
+// BatchTerms appends to the given points and scalars the terms of a random
+// linear combination of the verification equations for the given message,
+// challenge and response. The terms for the points a, b and c of the proof
+// and for the generators G and H are not appended, and their coefficients are
+// returned instead, so that callers can combine the terms of many proofs (and
+// other equations) that share these points. If the proof is valid, the sum of
+// the appended terms and the points a, b, c, G and H scaled by the returned
+// coefficients will be the point at infinity. Otherwise, it will not be the
+// point at infinity except with negligible probability.
+func BatchTerms(
+	points []secp256k1.Point, scalars []secp256k1.Fn,
+	msg *Message, res *Response, e *secp256k1.Fn,
+) ([]secp256k1.Point, []secp256k1.Fn, Coefficients) {
+	// For random weights r1, r2 and r3, the three verification equations are
+	// combined as
+	//	r1(eb + m - yG - wH) + r2(ea + m1 - zG - w1H) + r3(ec + m2 - zb - w2H).
+	r1 := secp256k1.RandomFn()
+	r2 := secp256k1.RandomFn()
+	r3 := secp256k1.RandomFn()
+	points = append(points, msg.m, msg.m1, msg.m2)
+	scalars = append(scalars, r1, r2, r3)
+
+	var coeffs Coefficients
+	var tmp secp256k1.Fn
+
+	// (r1e - r3z)b
+	coeffs.B.Mul(&r1, e)
+	tmp.Mul(&r3, &res.z)
+	tmp.Negate(&tmp)
+	coeffs.B.Add(&coeffs.B, &tmp)
+
+	// (r2e)a and (r3e)c
+	coeffs.A.Mul(&r2, e)
+	coeffs.C.Mul(&r3, e)
+
+	// -(r1y + r2z)G
+	coeffs.G.Mul(&r1, &res.y)
+	tmp.Mul(&r2, &res.z)
+	coeffs.G.Add(&coeffs.G, &tmp)
+	coeffs.G.Negate(&coeffs.G)
+
+	// -(r1w + r2w1 + r3w2)H
+	coeffs.H.Mul(&r1, &res.w)
+	tmp.Mul(&r2, &res.w1)
+	coeffs.H.Add(&coeffs.H, &tmp)
+	tmp.Mul(&r3, &res.w2)
+	coeffs.H.Add(&coeffs.H, &tmp)
+	coeffs.H.Negate(&coeffs.H)
+
+	return points, scalars, coeffs
+}
+
+// BatchVerify returns true if all of the given messages, challenges and
+// responses are valid for the ZKP, and false otherwise. The ith proof is
+// checked against the ith elements of each of the given slices. Instead of
+// checking the verification equations for each proof individually, a random
+// linear combination of all of the equations (see BatchTerms) is computed
+// using a single multi-scalar multiplication, which is much faster than the
+// scalar multiplications needed to check each proof. If the return value is
+// false, it is not known which of the proofs are invalid, and Verify should be
+// used to find them.
+//
+// Panics: This function will panic if the given slices do not all have the
+// same length.
+func BatchVerify(
+	h *secp256k1.Point,
+	as, bs, cs []secp256k1.Point,
+	msgs []Message, ress []Response, es []secp256k1.Fn,
+) bool {
+	l := len(as)
+	if len(bs) != l || len(cs) != l || len(msgs) != l || len(ress) != l || len(es) != l {
+		panic("inconsistent number of proofs")
+	}
+
+	points := make([]secp256k1.Point, 0, 6*l+2)
+	scalars := make([]secp256k1.Fn, 0, 6*l+2)
+	var gCoeff, hCoeff secp256k1.Fn
+	var coeffs Coefficients
+	for i := 0; i < l; i++ {
+		points, scalars, coeffs = BatchTerms(points, scalars, &msgs[i], &ress[i], &es[i])
+		points = append(points, as[i], bs[i], cs[i])
+		scalars = append(scalars, coeffs.A, coeffs.B, coeffs.C)
+		gCoeff.Add(&gCoeff, &coeffs.G)
+		hCoeff.Add(&hCoeff, &coeffs.H)
+	}
+
+	var g secp256k1.Point
+	one := secp256k1.NewFnFromU16(1)
+	g.BaseExp(&one)
+	points = append(points, g, *h)
+	scalars = append(scalars, gCoeff, hCoeff)
+
+	sum := msm.MultiScalarMul(points, scalars)
+	return sum.IsInfinity()
+}
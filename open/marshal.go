@@ -27,6 +27,9 @@ func (opener Opener) Generate(_ *rand.Rand, size int) reflect.Value {
 	}
 	indices := shamirutil.RandomIndices(rand.Intn(20))
 	h := secp256k1.RandomPoint()
+	if rand.Int()&1 == 1 {
+		return reflect.ValueOf(NewOptimistic(commitmentBatch, indices, h))
+	}
 	return reflect.ValueOf(New(commitmentBatch, indices, h))
 }
 
@@ -34,6 +37,7 @@ func (opener Opener) Generate(_ *rand.Rand, size int) reflect.Value {
 func (opener Opener) SizeHint() int {
 	return surge.SizeHint(opener.commitmentBatch) +
 		surge.SizeHint(opener.shareBufs) +
+		surge.SizeHint(opener.optimistic) +
 		opener.h.SizeHint() +
 		surge.SizeHint(opener.indices)
 }
@@ -44,6 +48,10 @@ func (opener Opener) Marshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, fmt.Errorf("marshaling share buffers: %v", err)
 	}
+	buf, rem, err = surge.MarshalBool(opener.optimistic, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("marshaling optimistic: %v", err)
+	}
 	buf, rem, err = surge.Marshal(opener.commitmentBatch, buf, rem)
 	if err != nil {
 		return buf, rem, fmt.Errorf("marshaling commitmentBatch: %v", err)
@@ -65,6 +73,10 @@ func (opener *Opener) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, fmt.Errorf("unmarshaling share buffers: %v", err)
 	}
+	buf, rem, err = surge.UnmarshalBool(&opener.optimistic, buf, rem)
+	if err != nil {
+		return buf, rem, fmt.Errorf("unmarshaling optimistic: %v", err)
+	}
 	buf, rem, err = surge.Unmarshal(&opener.commitmentBatch, buf, rem)
 	if err != nil {
 		return buf, rem, fmt.Errorf("unmarshaling commitment: %v", err)
@@ -27,11 +27,19 @@ import (
 //	- the number of players and their corresponding indices,
 //	- the reconstruction threshold (k),
 //	- and the Pedersen parameter (h).
+//
+// An Opener can also be constructed in optimistic mode (see NewOptimistic).
+// In this mode, shares are buffered without being checked against the
+// commitment, and once enough shares have been received only the
+// reconstructed secret and decommitment are checked against the constant term
+// of the commitment. The shares are only checked individually if this check
+// fails, in which case the invalid shares are removed from the buffer.
 type Opener struct {
 	// State
 	shareBufs []shamir.VerifiableShares
 
 	// Instance parameters
+	optimistic      bool
 	commitmentBatch []shamir.Commitment
 
 	// Global parameters
@@ -53,7 +61,8 @@ func (opener Opener) BatchSize() int {
 
 // I returns the current number of valid shares that the opener has received. It
 // assumes that all batches contain the same number of shares (this assumption
-// is enforced by all other methods).
+// is enforced by all other methods). In optimistic mode, this is the number of
+// shares that have been buffered but not necessarily checked.
 func (opener Opener) I() int {
 	return len(opener.shareBufs[0])
 }
@@ -69,6 +78,26 @@ func (opener Opener) I() int {
 //	- Not all commitments in the batch of commitments have the same
 //		reconstruction threshold (k).
 func New(commitmentBatch []shamir.Commitment, indices []secp256k1.Fn, h secp256k1.Point) Opener {
+	return newOpener(commitmentBatch, indices, h, false)
+}
+
+// NewOptimistic is the same as New, except that the returned Opener will be in
+// optimistic mode. In this mode, the shares in a received share batch are not
+// checked against the commitments when they are handled. Instead, once enough
+// shares have been received, the reconstructed secrets and decommitments are
+// checked against the constant terms of the commitments, and only if this
+// check fails are the shares checked individually. This means that an invalid
+// share batch will not necessarily cause an error when it is handled, but it
+// will never cause the wrong secret to be output.
+//
+// Panics: This function will panic in the same cases as New.
+func NewOptimistic(commitmentBatch []shamir.Commitment, indices []secp256k1.Fn, h secp256k1.Point) Opener {
+	return newOpener(commitmentBatch, indices, h, true)
+}
+
+func newOpener(
+	commitmentBatch []shamir.Commitment, indices []secp256k1.Fn, h secp256k1.Point, optimistic bool,
+) Opener {
 	if !params.ValidPedersenParameter(h) {
 		panic("insecure choice of pedersen parameter")
 	}
@@ -102,6 +131,7 @@ func New(commitmentBatch []shamir.Commitment, indices []secp256k1.Fn, h secp256k
 
 	return Opener{
 		shareBufs:       shareBufs,
+		optimistic:      optimistic,
 		commitmentBatch: comBatchCopy,
 		indices:         indicesCopy,
 		h:               h,
@@ -153,10 +183,13 @@ func (opener *Opener) HandleShareBatch(shareBatch shamir.VerifiableShares) (
 	}
 
 	// No shares should be invalid. If even a single share is invalid, we mark
-	// the entire batch of shares to be invalid.
-	for i, share := range shareBatch {
-		if !shamir.IsValid(opener.h, &opener.commitmentBatch[i], &share) {
-			return nil, nil, ErrInvalidShares
+	// the entire batch of shares to be invalid. In optimistic mode, this check
+	// is deferred until reconstruction.
+	if !opener.optimistic {
+		for i, share := range shareBatch {
+			if !shamir.IsValid(opener.h, &opener.commitmentBatch[i], &share) {
+				return nil, nil, ErrInvalidShares
+			}
 		}
 	}
 
@@ -167,28 +200,85 @@ func (opener *Opener) HandleShareBatch(shareBatch shamir.VerifiableShares) (
 	}
 
 	// If we have just added the kth share, we can reconstruct.
-	numShares := len(opener.shareBufs[0])
-	if numShares == opener.K() {
-		secrets := make([]secp256k1.Fn, opener.BatchSize())
-		decommitments := make([]secp256k1.Fn, opener.BatchSize())
-		shareBuf := make(shamir.Shares, numShares)
-		for i := 0; i < int(opener.BatchSize()); i++ {
-			for j := range opener.shareBufs[i] {
-				shareBuf[j].Index = opener.shareBufs[i][j].Share.Index
-				shareBuf[j].Value = opener.shareBufs[i][j].Share.Value
+	if len(opener.shareBufs[0]) != opener.K() {
+		// We have added the shares to the respective buffers but we were not
+		// yet able to reconstruct the secrets.
+		return nil, nil, nil
+	}
+	secrets, decommitments := opener.reconstruct()
+	if !opener.optimistic {
+		return secrets, decommitments, nil
+	}
+
+	// In optimistic mode, the reconstructed values need to be checked against
+	// the commitments. For a given batch element, the reconstruction can only
+	// be consistent with the constant term of the commitment if all of the
+	// shares were valid (unless the discrete log of h is known).
+	invalid := make([]int, 0, opener.BatchSize())
+	var com, hPow secp256k1.Point
+	for i := range secrets {
+		com.BaseExp(&secrets[i])
+		hPow.Scale(&opener.h, &decommitments[i])
+		com.Add(&com, &hPow)
+		if !com.Eq(&opener.commitmentBatch[i][0]) {
+			invalid = append(invalid, i)
+		}
+	}
+	if len(invalid) == 0 {
+		return secrets, decommitments, nil
+	}
+
+	// At least one of the buffered share batches was invalid, so we fall back
+	// to checking the shares individually for the batch elements that failed
+	// the check. Any player that sent an invalid share for one of these
+	// elements has its entire share batch removed from the buffers.
+	isInvalid := make([]bool, len(opener.shareBufs[0]))
+	for _, i := range invalid {
+		for j := range opener.shareBufs[i] {
+			if isInvalid[j] {
+				continue
 			}
-			secrets[i] = shamir.Open(shareBuf)
-			for j := range opener.shareBufs[i] {
-				shareBuf[j].Index = opener.shareBufs[i][j].Share.Index
-				shareBuf[j].Value = opener.shareBufs[i][j].Decommitment
+			if !shamir.IsValid(opener.h, &opener.commitmentBatch[i], &opener.shareBufs[i][j]) {
+				isInvalid[j] = true
 			}
-			decommitments[i] = shamir.Open(shareBuf)
 		}
-
-		return secrets, decommitments, nil
+	}
+	for i := range opener.shareBufs {
+		buf := opener.shareBufs[i][:0]
+		for j, share := range opener.shareBufs[i] {
+			if !isInvalid[j] {
+				buf = append(buf, share)
+			}
+		}
+		opener.shareBufs[i] = buf
 	}
 
-	// We have added the shares to the respective buffers but we were not yet
-	// able to reconstruct the secrets.
+	// The share batch that was just handled is the last one in the buffer
+	// before the invalid batches were removed.
+	if isInvalid[len(isInvalid)-1] {
+		return nil, nil, ErrInvalidShares
+	}
 	return nil, nil, nil
 }
+
+// reconstruct opens the secrets and decommitments using the shares in the
+// buffers. It assumes that there are at least k shares in each buffer.
+func (opener *Opener) reconstruct() ([]secp256k1.Fn, []secp256k1.Fn) {
+	numShares := len(opener.shareBufs[0])
+	secrets := make([]secp256k1.Fn, opener.BatchSize())
+	decommitments := make([]secp256k1.Fn, opener.BatchSize())
+	shareBuf := make(shamir.Shares, numShares)
+	for i := 0; i < int(opener.BatchSize()); i++ {
+		for j := range opener.shareBufs[i] {
+			shareBuf[j].Index = opener.shareBufs[i][j].Share.Index
+			shareBuf[j].Value = opener.shareBufs[i][j].Share.Value
+		}
+		secrets[i] = shamir.Open(shareBuf)
+		for j := range opener.shareBufs[i] {
+			shareBuf[j].Index = opener.shareBufs[i][j].Share.Index
+			shareBuf[j].Value = opener.shareBufs[i][j].Decommitment
+		}
+		decommitments[i] = shamir.Open(shareBuf)
+	}
+	return secrets, decommitments
+}
@@ -1,8 +1,12 @@
 package open_test
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/renproject/mpc/open"
@@ -190,6 +194,70 @@ var _ = Describe("Opener", func() {
 			})
 		})
 
+		Context("optimistic mode", func() {
+			It("should reconstruct the correct secrets when all shares are valid", func() {
+				indices, _, secrets, decommitments, shareBatchesByPlayer, commitments := Setup(n, k, b)
+				opener := open.NewOptimistic(commitments, indices, h)
+
+				for i, shareBatch := range shareBatchesByPlayer[:k] {
+					reconstructedSecrets, reconstructedDecommitments, err := opener.HandleShareBatch(shareBatch)
+					Expect(err).ToNot(HaveOccurred())
+					if i < k-1 {
+						Expect(reconstructedSecrets).To(BeNil())
+						Expect(reconstructedDecommitments).To(BeNil())
+						continue
+					}
+					for j := range reconstructedSecrets {
+						Expect(reconstructedSecrets[j].Eq(&secrets[j])).To(BeTrue())
+						Expect(reconstructedDecommitments[j].Eq(&decommitments[j])).To(BeTrue())
+					}
+				}
+			})
+
+			It("should drop invalid shares and still reconstruct the correct secrets", func() {
+				indices, _, secrets, decommitments, shareBatchesByPlayer, commitments := Setup(n, k, b)
+				opener := open.NewOptimistic(commitments, indices, h)
+
+				// Some of the first k share batches will be invalid, which
+				// will only be detected when reconstruction is attempted.
+				numInvalid := rand.Intn(n-k) + 1
+				isInvalid := make(map[int]bool, numInvalid)
+				for _, i := range rand.Perm(k)[:shamirutil.Min(numInvalid, k)] {
+					isInvalid[i] = true
+				}
+
+				var reconstructedSecrets, reconstructedDecommitments []secp256k1.Fn
+				for i, shareBatch := range shareBatchesByPlayer {
+					if isInvalid[i] {
+						shareBatch = make(shamir.VerifiableShares, b)
+						copy(shareBatch, shareBatchesByPlayer[i])
+						j := rand.Intn(b)
+						shareBatch[j].Share.Value = secp256k1.RandomFn()
+					}
+					var err error
+					reconstructedSecrets, reconstructedDecommitments, err = opener.HandleShareBatch(shareBatch)
+					if opener.I() < k && i == k-1 {
+						// The kth share batch triggered the fallback check.
+						Expect(opener.I()).To(Equal(k - len(isInvalid)))
+						if isInvalid[i] {
+							Expect(err).To(Equal(open.ErrInvalidShares))
+						} else {
+							Expect(err).ToNot(HaveOccurred())
+						}
+					}
+					if reconstructedSecrets != nil {
+						break
+					}
+				}
+
+				Expect(reconstructedSecrets).ToNot(BeNil())
+				for j := range reconstructedSecrets {
+					Expect(reconstructedSecrets[j].Eq(&secrets[j])).To(BeTrue())
+					Expect(reconstructedDecommitments[j].Eq(&decommitments[j])).To(BeTrue())
+				}
+			})
+		})
+
 		Context("panics", func() {
 			Specify("insecure pedersen parameter", func() {
 				indices := []secp256k1.Fn{}
@@ -273,4 +341,174 @@ var _ = Describe("Opener", func() {
 			}
 		})
 	})
+
+	Context("Network (model checking)", func() {
+		b := 1
+		n := 4
+		t := 1
+
+		// check explores all delivery orders and crashes of at most t machines
+		// for a network of openers with the given reconstruction threshold.
+		check := func(k int) (int, error) {
+			indices := shamirutil.RandomIndices(n)
+			shareBatchesByPlayer, commitments, secrets, decommitments :=
+				RandomVerifiableSharingBatch(indices, k, b)
+
+			ids := make([]ID, n)
+			for i := range ids {
+				ids[i] = ID(i + 1)
+			}
+			machines := make([]Machine, n)
+			for i := range machines {
+				machine := openutil.NewMachine(ids[i], ids, uint32(n), shareBatchesByPlayer[i], commitments,
+					open.New(commitments, indices, h))
+				machines[i] = &machine
+			}
+
+			checker := NewChecker(machines, t)
+			return checker.Check(func(honest []Machine) error {
+				for _, machine := range honest {
+					m := machine.(*openutil.Machine)
+					if len(m.Secrets) != b {
+						return fmt.Errorf("machine with ID %v did not open the secret", m.ID())
+					}
+					for i := 0; i < b; i++ {
+						if !m.Secrets[i].Eq(&secrets[i]) || !m.Decommitments[i].Eq(&decommitments[i]) {
+							return fmt.Errorf("machine with ID %v got the wrong secret", m.ID())
+						}
+					}
+				}
+				return nil
+			})
+		}
+
+		It("all honest openers should open the correct secret in every run", func() {
+			states, err := check(n - t)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(states).To(BeNumerically(">", 1))
+		})
+
+		It("should find a run with a crash when the threshold is too large", func() {
+			_, err := check(n)
+			Expect(err).To(HaveOccurred())
+			violation, ok := err.(*Violation)
+			Expect(ok).To(BeTrue())
+			Expect(violation.Crashed).To(HaveLen(t))
+
+			dir, err := ioutil.TempDir("", "open")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			filename := filepath.Join(dir, "violation.dump")
+			violation.Dump(filename)
+
+			dbg := NewDebugger(filename, openutil.Message{}, openutil.Machine{})
+			Expect(dbg.Len()).To(Equal(len(violation.Delivered)))
+		})
+	})
+
+	Context("Network (asynchronous)", func() {
+		b := 5
+		n := 20
+		k := 7
+
+		It("all online openers should open the correct secret with asynchronous delivery", func() {
+			indices := shamirutil.RandomIndices(n)
+			shareBatchesByPlayer, commitments, secrets, decommitments :=
+				RandomVerifiableSharingBatch(indices, k, b)
+
+			ids := make([]ID, n)
+			for i := range ids {
+				ids[i] = ID(i + 1)
+			}
+			machines := make([]Machine, n)
+			for i := range machines {
+				machine := openutil.NewMachine(ids[i], ids, uint32(n), shareBatchesByPlayer[i], commitments,
+					open.New(commitments, indices, h))
+				machines[i] = &machine
+			}
+
+			latency := WithJitter(ExponentialLatency(10*time.Millisecond, 50*time.Millisecond), 5*time.Millisecond)
+			scheduler := NewScheduler(machines, latency, rand.Int63())
+			scheduler.SetCaptureHist(true)
+
+			// Machines go offline at random times before, during and after
+			// the time that it takes to deliver the messages, and some of
+			// them come back online.
+			isOffline := make(map[ID]bool)
+			for _, i := range rand.Perm(n)[:n-k] {
+				start := time.Duration(rand.Int63n(int64(100 * time.Millisecond)))
+				end := time.Duration(0)
+				if rand.Int()&1 == 1 {
+					end = start + time.Duration(rand.Int63n(int64(100*time.Millisecond)))
+				}
+				scheduler.SetOffline(ids[i], start, end)
+				isOffline[ids[i]] = true
+			}
+
+			Expect(scheduler.Run()).To(Succeed())
+
+			for _, machine := range machines {
+				if isOffline[machine.ID()] {
+					continue
+				}
+				reconstructedSecrets := machine.(*openutil.Machine).Secrets
+				reconstructedDecommitments := machine.(*openutil.Machine).Decommitments
+
+				Expect(len(reconstructedSecrets)).To(Equal(b))
+				for i := 0; i < b; i++ {
+					if !reconstructedSecrets[i].Eq(&secrets[i]) ||
+						!reconstructedDecommitments[i].Eq(&decommitments[i]) {
+						scheduler.Dump("test.dump")
+						Fail(fmt.Sprintf("machine with ID %v got the wrong secret", machine.ID()))
+					}
+				}
+			}
+		})
+	})
+	Context("Network (concurrent)", func() {
+		b := 5
+		n := 20
+		k := 7
+
+		It("all online openers should open the correct secret when run concurrently", func() {
+			indices := shamirutil.RandomIndices(n)
+			shareBatchesByPlayer, commitments, secrets, _ :=
+				RandomVerifiableSharingBatch(indices, k, b)
+
+			ids := make([]ID, n)
+			for i := range ids {
+				ids[i] = ID(i + 1)
+			}
+			machines := make([]Machine, n)
+			for i := range machines {
+				machine := openutil.NewMachine(ids[i], ids, uint32(n), shareBatchesByPlayer[i], commitments,
+					open.New(commitments, indices, h))
+				machines[i] = &machine
+			}
+
+			shuffleMsgs, isOffline := MessageShufflerDropper(ids, n-k)
+			network := NewConcurrentNetwork(machines, shuffleMsgs)
+			network.SetCaptureStats(true)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			Expect(network.Run(ctx)).To(Succeed())
+
+			// Only the online openers open the secrets, and every message
+			// between them is delivered.
+			outputs := network.Outputs()
+			Expect(outputs).To(HaveLen(k))
+			for _, id := range ids {
+				if isOffline[id] {
+					Expect(network.Unfinished()).To(ContainElement(id))
+					continue
+				}
+				reconstructedSecrets := outputs[id].([]secp256k1.Fn)
+				Expect(len(reconstructedSecrets)).To(Equal(b))
+				for i := 0; i < b; i++ {
+					Expect(reconstructedSecrets[i].Eq(&secrets[i])).To(BeTrue())
+				}
+				Expect(network.Report().Machine(id).MessagesReceived).To(Equal(k - 1))
+			}
+		})
+	})
 })
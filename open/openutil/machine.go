@@ -63,6 +63,13 @@ func (m *Machine) Handle(msg mpcutil.Message) []mpcutil.Message {
 	return nil
 }
 
+// Done implements the mpcutil.Terminator interface.
+func (m Machine) Done() bool { return len(m.Secrets) != 0 }
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of opened secrets.
+func (m Machine) Result() interface{} { return m.Secrets }
+
 // SizeHint implements the surge.SizeHinter interface.
 func (m Machine) SizeHint() int {
 	return m.ownID.SizeHint() +
@@ -70,7 +77,9 @@ func (m Machine) SizeHint() int {
 		surge.SizeHint(m.n) +
 		m.shares.SizeHint() +
 		surge.SizeHint(m.commitments) +
-		m.opener.SizeHint()
+		m.opener.SizeHint() +
+		surge.SizeHint(m.Secrets) +
+		surge.SizeHint(m.Decommitments)
 }
 
 // Marshal implements the surge.Marshaler interface.
@@ -95,7 +104,15 @@ func (m Machine) Marshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, err
 	}
-	return m.opener.Marshal(buf, rem)
+	buf, rem, err = m.opener.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.Secrets, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(m.Decommitments, buf, rem)
 }
 
 // Unmarshal implements the surge.Unmarshaler interface.
@@ -104,7 +121,7 @@ func (m *Machine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, err
 	}
-	buf, rem, err = surge.Unmarshal(m.ids, buf, rem)
+	buf, rem, err = surge.Unmarshal(&m.ids, buf, rem)
 	if err != nil {
 		return buf, rem, err
 	}
@@ -120,5 +137,13 @@ func (m *Machine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 	if err != nil {
 		return buf, rem, err
 	}
-	return m.opener.Unmarshal(buf, rem)
+	buf, rem, err = m.opener.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.Secrets, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&m.Decommitments, buf, rem)
 }
@@ -0,0 +1,100 @@
+// Package random provides helpers for using an injectable source of
+// randomness, so that the randomness used by the protocols in this library can
+// be controlled by the caller. Constructors that consume randomness have a
+// variant with the suffix WithRand that takes an io.Reader; the variant
+// without the suffix uses Reader.
+//
+// Outside of testing, the source of randomness must be cryptographically
+// secure, and so should always be Reader. In testing, a seeded source (for
+// example a *math/rand.Rand, which implements io.Reader) allows a randomised
+// run to be replayed exactly from the seed, including all of the shares that
+// are dealt and the proofs that are created.
+package random
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// Reader is the default source of randomness, which is cryptographically
+// secure.
+var Reader io.Reader = rand.Reader
+
+// Fn returns a random field element read from the given source.
+//
+// Panics: This function will panic if the source returns an error.
+func Fn(r io.Reader) secp256k1.Fn {
+	var bs [32]byte
+	if _, err := io.ReadFull(r, bs[:]); err != nil {
+		panic(fmt.Sprintf("could not generate random bytes: %v", err))
+	}
+	// This will reduce the value modulo N, so it does not matter if the bytes
+	// represent a number greater than N.
+	var x secp256k1.Fn
+	x.SetB32(bs[:])
+	return x
+}
+
+// VShareSecret is the same as shamir.VShareSecret, except that the random
+// coefficients of the sharing and decommitment polynomials are read from the
+// given source.
+//
+// Panics: This function will panic in the same cases as shamir.VShareSecret,
+// or if the source returns an error.
+func VShareSecret(
+	r io.Reader,
+	vshares *shamir.VerifiableShares,
+	c *shamir.Commitment,
+	indices []secp256k1.Fn,
+	h secp256k1.Point,
+	secret secp256k1.Fn,
+	k int,
+) error {
+	if k > len(indices) {
+		return fmt.Errorf(
+			"reconstruction threshold too large: expected k <= %v, got k = %v",
+			len(indices), k,
+		)
+	}
+
+	coeffs := make([]secp256k1.Fn, k)
+	decomCoeffs := make([]secp256k1.Fn, k)
+	coeffs[0] = secret
+	for i := 1; i < k; i++ {
+		coeffs[i] = Fn(r)
+	}
+	for i := range decomCoeffs {
+		decomCoeffs[i] = Fn(r)
+	}
+
+	*vshares = (*vshares)[:len(indices)]
+	for i := range indices {
+		(*vshares)[i].Share.Index = indices[i]
+		polyEval(&(*vshares)[i].Share.Value, &indices[i], coeffs)
+		polyEval(&(*vshares)[i].Decommitment, &indices[i], decomCoeffs)
+	}
+
+	*c = (*c)[:k]
+	var hPow secp256k1.Point
+	for i := range coeffs {
+		(*c)[i].BaseExp(&coeffs[i])
+		hPow.Scale(&h, &decomCoeffs[i])
+		(*c)[i].Add(&(*c)[i], &hPow)
+	}
+
+	return nil
+}
+
+// polyEval evaluates the polynomial with the given coefficients at x and
+// stores the result in y.
+func polyEval(y, x *secp256k1.Fn, coeffs []secp256k1.Fn) {
+	*y = coeffs[len(coeffs)-1]
+	for i := len(coeffs) - 2; i >= 0; i-- {
+		y.Mul(y, x)
+		y.Add(y, &coeffs[i])
+	}
+}
@@ -0,0 +1,88 @@
+package random_test
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/random"
+)
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("error") }
+
+var _ = Describe("Random", func() {
+	trials := 10
+
+	Context("field elements", func() {
+		Specify("the same seed should give the same field elements", func() {
+			for i := 0; i < trials; i++ {
+				seed := rand.Int63()
+				r1, r2 := rand.New(rand.NewSource(seed)), rand.New(rand.NewSource(seed))
+				x, y := Fn(r1), Fn(r2)
+				Expect(x.Eq(&y)).To(BeTrue())
+				y = Fn(r2)
+				Expect(x.Eq(&y)).To(BeFalse())
+			}
+		})
+
+		Specify("a source that returns an error should panic", func() {
+			Expect(func() { Fn(errReader{}) }).To(Panic())
+		})
+	})
+
+	Context("verifiable sharing", func() {
+		Specify("the shares should be valid and consistent", func() {
+			for i := 0; i < trials; i++ {
+				n := shamirutil.RandRange(1, 20)
+				k := shamirutil.RandRange(1, n)
+				indices := shamirutil.RandomIndices(n)
+				h := secp256k1.RandomPoint()
+				secret := secp256k1.RandomFn()
+
+				shares := make(shamir.VerifiableShares, n)
+				com := shamir.NewCommitmentWithCapacity(k)
+				Expect(VShareSecret(Reader, &shares, &com, indices, h, secret, k)).To(Succeed())
+				Expect(com.Len()).To(Equal(k))
+				Expect(shamirutil.VsharesAreConsistent(shares, k)).To(BeTrue())
+				for j := range shares {
+					Expect(shamir.IsValid(h, &com, &shares[j])).To(BeTrue())
+				}
+				opened := shamir.Open(shares.Shares())
+				Expect(opened.Eq(&secret)).To(BeTrue())
+			}
+		})
+
+		Specify("the same seed should give the same sharing", func() {
+			for i := 0; i < trials; i++ {
+				n := shamirutil.RandRange(1, 20)
+				k := shamirutil.RandRange(1, n)
+				indices := shamirutil.RandomIndices(n)
+				h := secp256k1.RandomPoint()
+				secret := secp256k1.RandomFn()
+				seed := rand.Int63()
+
+				shares1, shares2 := make(shamir.VerifiableShares, n), make(shamir.VerifiableShares, n)
+				com1, com2 := shamir.NewCommitmentWithCapacity(k), shamir.NewCommitmentWithCapacity(k)
+				VShareSecret(rand.New(rand.NewSource(seed)), &shares1, &com1, indices, h, secret, k)
+				VShareSecret(rand.New(rand.NewSource(seed)), &shares2, &com2, indices, h, secret, k)
+				Expect(shares1).To(Equal(shares2))
+				Expect(com1.Eq(com2)).To(BeTrue())
+			}
+		})
+
+		Specify("a threshold larger than the number of indices should return an error", func() {
+			indices := shamirutil.RandomIndices(5)
+			shares := make(shamir.VerifiableShares, 5)
+			com := shamir.NewCommitmentWithCapacity(6)
+			err := VShareSecret(Reader, &shares, &com, indices, secp256k1.RandomPoint(), secp256k1.RandomFn(), 6)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,146 @@
+package rkpg
+
+import (
+	"fmt"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+
+	"github.com/renproject/mpc/rng"
+)
+
+// A FusedRKPGer is a state machine that implements the RNG and RKPG protocols
+// together, so that a batch of random key pairs can be created in a single
+// round of messaging, instead of one round for RNG followed by another for
+// RKPG.
+//
+// The public key for an RNG output x is computed as in RKPG: the output
+// commitment for x is xG + dH, where d is the decommitment for the first BRNG
+// output, and so once d is reconstructed the public key is xG = (xG + dH) -
+// dH. Each player already holds a share of d from BRNG, and so it can send
+// this share (masked by a share of zero) in the same message as its directed
+// openings for RNG.
+type FusedRKPGer struct {
+	rnger     rng.RNGer
+	rkpger    RKPGer
+	keyShares shamir.VerifiableShares
+	pubKeys   []secp256k1.Point
+}
+
+// A FusedMessage is the message that a player sends to another player in the
+// fused protocol. The openings are specific to the player that the message is
+// for, and the decommitment shares are the same for every player.
+type FusedMessage struct {
+	Openings           shamir.VerifiableShares
+	DecommitmentShares shamir.Shares
+}
+
+// NewFused returns a new FusedRKPGer state machine, along with the initial
+// messages that are to be sent to the other players, indexed by the index of
+// the player that the message is for, and the output commitments for the
+// batch of secret keys. The state machine will handle its own message before
+// being returned. The BRNG share and commitment batches are as for rng.New in
+// RNG mode, and the RZG shares should be shares for this player of a batch of
+// sharings of zero with the same threshold as the BRNG outputs. As for
+// rng.New, if the BRNG shares are nil they will be ignored, and the returned
+// messages will also be nil.
+//
+// Panics: This function will panic in the same cases as rng.New, or if the
+// RZG shares are not nil and do not have the same batch size as the BRNG
+// outputs.
+func NewFused(
+	ownIndex secp256k1.Fn,
+	indices []secp256k1.Fn,
+	h secp256k1.Point,
+	brngShareBatch []shamir.VerifiableShares,
+	brngCommitmentBatch [][]shamir.Commitment,
+	rzgShares shamir.VerifiableShares,
+) (FusedRKPGer, map[secp256k1.Fn]FusedMessage, []shamir.Commitment) {
+	rnger, directedOpenings, commitments := rng.New(
+		ownIndex, indices, h, brngShareBatch, brngCommitmentBatch, false,
+	)
+	b := len(brngCommitmentBatch)
+	if brngShareBatch != nil && len(rzgShares) != b {
+		panic(fmt.Sprintf(
+			"invalid rzg share batch size: expected %v (brng), got %v",
+			b, len(rzgShares),
+		))
+	}
+
+	points := make([]secp256k1.Point, b)
+	for i := range points {
+		points[i] = commitments[i][0]
+	}
+	fused := FusedRKPGer{
+		rnger:     rnger,
+		rkpger:    newRKPGer(indices, h, points, brngCommitmentBatch[0][0].Len()),
+		keyShares: nil,
+		pubKeys:   nil,
+	}
+
+	if directedOpenings == nil {
+		return fused, nil, commitments
+	}
+
+	decommitmentShares := make(shamir.Shares, b)
+	for i := range decommitmentShares {
+		dShare := shamir.NewShare(ownIndex, brngShareBatch[i][0].Decommitment)
+		decommitmentShares[i].Add(&dShare, &rzgShares[i].Share)
+	}
+	msgs := make(map[secp256k1.Fn]FusedMessage, len(directedOpenings))
+	for index, openings := range directedOpenings {
+		msgDecommitmentShares := make(shamir.Shares, b)
+		copy(msgDecommitmentShares, decommitmentShares)
+		msgs[index] = FusedMessage{
+			Openings:           openings,
+			DecommitmentShares: msgDecommitmentShares,
+		}
+	}
+
+	// Process own message. The opener in the RNGer has already handled the
+	// openings for this player.
+	if _, err := fused.rkpger.HandleShareBatch(decommitmentShares); err != nil {
+		panic(fmt.Sprintf("unexpected error: %v", err))
+	}
+
+	return fused, msgs, commitments
+}
+
+// HandleMessage handles a message from another player. The openings and the
+// decommitment shares in the message are handled independently, so that an
+// invalid part of a message does not cause the other part to be ignored; if
+// either part is invalid, the corresponding error is returned, as for
+// rng.RNGer.HandleShareBatch and RKPGer.HandleShareBatch respectively. The
+// return values are the shares of the secret keys for this player and the
+// public keys; each will be nil until enough messages have been received to
+// reconstruct it, and will then be returned for every subsequent message.
+func (fused *FusedRKPGer) HandleMessage(msg FusedMessage) (
+	shamir.VerifiableShares, []secp256k1.Point, error,
+) {
+	var err error
+	if len(fused.keyShares) == 0 {
+		keyShares, openErr := fused.rnger.HandleShareBatch(msg.Openings)
+		if openErr != nil {
+			err = openErr
+		}
+		if keyShares != nil {
+			fused.keyShares = keyShares
+		}
+	}
+	if len(fused.pubKeys) == 0 {
+		pubKeys, rkpgErr := fused.rkpger.HandleShareBatch(msg.DecommitmentShares)
+		if rkpgErr != nil && err == nil {
+			err = rkpgErr
+		}
+		if pubKeys != nil {
+			fused.pubKeys = pubKeys
+		}
+	}
+	return fused.keyShares, fused.pubKeys, err
+}
+
+// Done returns true if both the shares of the secret keys and the public keys
+// have been reconstructed, and false otherwise.
+func (fused FusedRKPGer) Done() bool {
+	return len(fused.keyShares) != 0 && len(fused.pubKeys) != 0
+}
@@ -0,0 +1,175 @@
+package rkpg_test
+
+import (
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/mpc/rng/rngutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/rkpg"
+)
+
+var _ = Describe("Fused RNG and RKPG", func() {
+	trials := 5
+
+	RandomTestParams := func() (int, int, int, secp256k1.Point, []secp256k1.Fn) {
+		k := shamirutil.RandRange(2, 5)
+		n := 3 * k
+		b := shamirutil.RandRange(1, 4)
+		h := secp256k1.RandomPoint()
+		indices := shamirutil.RandomIndices(n)
+		return n, k, b, h, indices
+	}
+
+	BRNGAndRZGOutputs := func(indices []secp256k1.Fn, k, b int, h secp256k1.Point) (
+		map[secp256k1.Fn][]shamir.VerifiableShares,
+		[][]shamir.Commitment,
+		[]shamir.VerifiableShares,
+	) {
+		brngShares, brngComs := rngutil.BRNGOutputFullBatch(indices, b, k, k, h)
+		rzgShares, _ := rkpgutil.RZGOutputBatch(indices, k, b, h)
+		return brngShares, brngComs, rzgShares
+	}
+
+	// CheckOutputs checks that the key shares are valid and consistent, and
+	// that the public keys correspond to the secrets of the key shares.
+	CheckOutputs := func(
+		h secp256k1.Point,
+		k, b int,
+		coms []shamir.Commitment,
+		keyShares []shamir.VerifiableShares,
+		pubKeys [][]secp256k1.Point,
+	) {
+		for i := 0; i < b; i++ {
+			shares := make(shamir.Shares, len(keyShares))
+			for j := range keyShares {
+				Expect(shamir.IsValid(h, &coms[i], &keyShares[j][i])).To(BeTrue())
+				shares[j] = keyShares[j][i].Share
+			}
+			Expect(shamirutil.SharesAreConsistent(shares, k)).To(BeTrue())
+
+			secret := shamir.Open(shares)
+			var pubKey secp256k1.Point
+			pubKey.BaseExp(&secret)
+			for j := range pubKeys {
+				Expect(pubKeys[j][i].Eq(&pubKey)).To(BeTrue())
+			}
+		}
+	}
+
+	Context("initial messages", func() {
+		Specify("the decommitment shares should be the same for all players", func() {
+			for i := 0; i < trials; i++ {
+				_, k, b, h, indices := RandomTestParams()
+				brngShares, brngComs, rzgShares := BRNGAndRZGOutputs(indices, k, b, h)
+				_, msgs, coms := NewFused(indices[0], indices, h, brngShares[indices[0]], brngComs, rzgShares[0])
+
+				Expect(len(coms)).To(Equal(b))
+				Expect(len(msgs)).To(Equal(len(indices)))
+				for _, msg := range msgs {
+					Expect(len(msg.Openings)).To(Equal(b))
+					Expect(msg.DecommitmentShares).To(Equal(msgs[indices[0]].DecommitmentShares))
+				}
+			}
+		})
+
+		Specify("nil brng shares should produce nil messages", func() {
+			for i := 0; i < trials; i++ {
+				_, k, b, h, indices := RandomTestParams()
+				_, brngComs, _ := BRNGAndRZGOutputs(indices, k, b, h)
+				fused, msgs, coms := NewFused(indices[0], indices, h, nil, brngComs, nil)
+
+				Expect(msgs).To(BeNil())
+				Expect(len(coms)).To(Equal(b))
+				Expect(fused.Done()).To(BeFalse())
+			}
+		})
+	})
+
+	Context("panics", func() {
+		Specify("rzg shares with the wrong batch size", func() {
+			_, k, b, h, indices := RandomTestParams()
+			brngShares, brngComs, rzgShares := BRNGAndRZGOutputs(indices, k, b, h)
+			Expect(func() {
+				NewFused(indices[0], indices, h, brngShares[indices[0]], brngComs, rzgShares[0][1:])
+			}).To(Panic())
+			Expect(func() {
+				NewFused(indices[0], indices, h, brngShares[indices[0]], brngComs, nil)
+			}).To(Panic())
+		})
+	})
+
+	Context("network", func() {
+		Specify("honest players should compute random keys and public keys", func() {
+			for i := 0; i < trials; i++ {
+				n, k, b, h, indices := RandomTestParams()
+				brngShares, brngComs, rzgShares := BRNGAndRZGOutputs(indices, k, b, h)
+
+				fuseds := make([]FusedRKPGer, n)
+				msgs := make([]map[secp256k1.Fn]FusedMessage, n)
+				var coms []shamir.Commitment
+				for j, index := range indices {
+					fuseds[j], msgs[j], coms = NewFused(index, indices, h, brngShares[index], brngComs, rzgShares[j])
+				}
+
+				keyShares := make([]shamir.VerifiableShares, n)
+				pubKeys := make([][]secp256k1.Point, n)
+				for j := range fuseds {
+					for l := range fuseds {
+						if l == j {
+							continue
+						}
+						var err error
+						keyShares[j], pubKeys[j], err = fuseds[j].HandleMessage(msgs[l][indices[j]])
+						Expect(err).ToNot(HaveOccurred())
+					}
+					Expect(fuseds[j].Done()).To(BeTrue())
+				}
+
+				CheckOutputs(h, k, b, coms, keyShares, pubKeys)
+			}
+		})
+
+		Specify("outputs should be correct with offline and malicious players", func() {
+			for i := 0; i < trials; i++ {
+				n, k, b, h, indices := RandomTestParams()
+				brngShares, brngComs, rzgShares := BRNGAndRZGOutputs(indices, k, b, h)
+
+				fuseds := make([]FusedRKPGer, n)
+				msgs := make([]map[secp256k1.Fn]FusedMessage, n)
+				var coms []shamir.Commitment
+				for j, index := range indices {
+					fuseds[j], msgs[j], coms = NewFused(index, indices, h, brngShares[index], brngComs, rzgShares[j])
+				}
+
+				// The last player is offline and the second last player sends
+				// incorrect decommitment shares to everyone.
+				offline, malicious := n-1, n-2
+				for _, msg := range msgs[malicious] {
+					for l := range msg.DecommitmentShares {
+						msg.DecommitmentShares[l].Value = secp256k1.RandomFn()
+					}
+				}
+
+				keyShares := make([]shamir.VerifiableShares, n-1)
+				pubKeys := make([][]secp256k1.Point, n-1)
+				for j := range keyShares {
+					for l := range fuseds {
+						if l == j || l == offline {
+							continue
+						}
+						var err error
+						keyShares[j], pubKeys[j], err = fuseds[j].HandleMessage(msgs[l][indices[j]])
+						Expect(err).ToNot(HaveOccurred())
+					}
+					Expect(fuseds[j].Done()).To(BeTrue())
+				}
+
+				CheckOutputs(h, k, b, coms, keyShares, pubKeys)
+			}
+		})
+	})
+})
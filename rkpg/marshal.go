@@ -4,7 +4,9 @@ import (
 	"math/rand"
 	"reflect"
 
+	"github.com/renproject/mpc/rng"
 	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
 	"github.com/renproject/shamir/rs"
 	"github.com/renproject/surge"
 )
@@ -92,3 +94,104 @@ func (rkpger *RKPGer) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
 	}
 	return rkpger.h.Unmarshal(buf, rem)
 }
+
+// Generate implements the quick.Generator interface.
+func (fused FusedRKPGer) Generate(rand *rand.Rand, size int) reflect.Value {
+	size /= 4
+	b := rand.Intn(3) + 1
+	keyShares := make(shamir.VerifiableShares, b)
+	for i := range keyShares {
+		keyShares[i] = shamir.VerifiableShare{}.Generate(rand, size).Interface().(shamir.VerifiableShare)
+	}
+	pubKeys := make([]secp256k1.Point, b)
+	for i := range pubKeys {
+		pubKeys[i] = secp256k1.RandomPoint()
+	}
+	f := FusedRKPGer{
+		rnger:     rng.RNGer{}.Generate(rand, size).Interface().(rng.RNGer),
+		rkpger:    RKPGer{}.Generate(rand, size).Interface().(RKPGer),
+		keyShares: keyShares,
+		pubKeys:   pubKeys,
+	}
+	return reflect.ValueOf(f)
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (fused FusedRKPGer) SizeHint() int {
+	return fused.rnger.SizeHint() +
+		fused.rkpger.SizeHint() +
+		fused.keyShares.SizeHint() +
+		surge.SizeHint(fused.pubKeys)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (fused FusedRKPGer) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := fused.rnger.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = fused.rkpger.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = fused.keyShares.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(fused.pubKeys, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (fused *FusedRKPGer) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := fused.rnger.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = fused.rkpger.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = fused.keyShares.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&fused.pubKeys, buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (msg FusedMessage) Generate(rand *rand.Rand, size int) reflect.Value {
+	b := rand.Intn(3) + 1
+	openings := make(shamir.VerifiableShares, b)
+	decommitmentShares := make(shamir.Shares, b)
+	for i := range openings {
+		openings[i] = shamir.VerifiableShare{}.Generate(rand, size).Interface().(shamir.VerifiableShare)
+		decommitmentShares[i] = shamir.NewShare(secp256k1.RandomFn(), secp256k1.RandomFn())
+	}
+	return reflect.ValueOf(FusedMessage{
+		Openings:           openings,
+		DecommitmentShares: decommitmentShares,
+	})
+}
+
+// SizeHint implements the surge.SizeHinter interface.
+func (msg FusedMessage) SizeHint() int {
+	return msg.Openings.SizeHint() + msg.DecommitmentShares.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (msg FusedMessage) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.Openings.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return msg.DecommitmentShares.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (msg *FusedMessage) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := msg.Openings.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return msg.DecommitmentShares.Unmarshal(buf, rem)
+}
@@ -16,6 +16,8 @@ var _ = Describe("Surge marshalling", func() {
 	ts := []reflect.Type{
 		reflect.TypeOf(rkpg.State{}),
 		reflect.TypeOf(rkpg.RKPGer{}),
+		reflect.TypeOf(rkpg.FusedRKPGer{}),
+		reflect.TypeOf(rkpg.FusedMessage{}),
 	}
 
 	for _, t := range ts {
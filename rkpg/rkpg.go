@@ -35,7 +35,6 @@ func New(
 	if !params.ValidPedersenParameter(h) {
 		panic("insecure choice of pedersen parameter")
 	}
-	n := len(indices)
 	b := len(rngShares)
 	if len(rzgShares) != b {
 		panic(fmt.Sprintf(
@@ -58,21 +57,11 @@ func New(
 		shares[i].Add(&dRnShare, &rzgShares[i].Share)
 	}
 
-	state := NewState(n, b)
 	points := make([]secp256k1.Point, b)
 	for i := range points {
 		points[i] = rngComs[i][0]
 	}
-	indicesCopy := make([]secp256k1.Fn, n)
-	copy(indicesCopy, indices)
-	rkpger := RKPGer{
-		state:   state,
-		k:       int32(k),
-		points:  points,
-		decoder: rs.NewDecoder(indices, k),
-		indices: indicesCopy,
-		h:       h,
-	}
+	rkpger := newRKPGer(indices, h, points, k)
 
 	// Proccess own share.
 	_, err := rkpger.HandleShareBatch(shares)
@@ -83,6 +72,23 @@ func New(
 	return rkpger, shares
 }
 
+// newRKPGer constructs an RKPG state machine that will compute the public keys
+// from the given commitments to the secret keys, once the decommitments have
+// been reconstructed from shares with threshold k.
+func newRKPGer(indices []secp256k1.Fn, h secp256k1.Point, points []secp256k1.Point, k int) RKPGer {
+	n := len(indices)
+	indicesCopy := make([]secp256k1.Fn, n)
+	copy(indicesCopy, indices)
+	return RKPGer{
+		state:   NewState(n, len(points)),
+		k:       int32(k),
+		points:  points,
+		decoder: rs.NewDecoder(indices, k),
+		indices: indicesCopy,
+		h:       h,
+	}
+}
+
 // HandleShareBatch applies a state transition to the given state upon
 // receiveing the given shares from another party during the open in the RKPG
 // protocol. Once enough shares have been received to reconstruct, the output
@@ -284,19 +284,21 @@ var _ = Describe("RKPG", func() {
 					err := network.Run()
 					Expect(err).ToNot(HaveOccurred())
 
-					// All players should have the same public keys.
+					// Only the honest players have outputs, and they should
+					// all have the same public keys.
+					Expect(network.Unfinished()).To(BeEmpty())
+					outputs := network.Outputs()
 					var refPoints []secp256k1.Point
-					for i := range machines {
-						if machineType[machines[i].ID()] == rkpgutil.Honest {
-							refPoints = machines[i].(*rkpgutil.HonestMachine).Points
-							break
-						}
-					}
-					for i := range machines {
-						if machineType[machines[i].ID()] != rkpgutil.Honest {
+					for _, id := range ids {
+						if machineType[id] != rkpgutil.Honest {
+							Expect(outputs).ToNot(HaveKey(id))
 							continue
 						}
-						points := machines[i].(*rkpgutil.HonestMachine).Points
+						Expect(outputs).To(HaveKey(id))
+						points := outputs[id].([]secp256k1.Point)
+						if refPoints == nil {
+							refPoints = points
+						}
 						for j := range refPoints {
 							Expect(refPoints[j].Eq(&points[j])).To(BeTrue())
 						}
@@ -312,4 +314,80 @@ var _ = Describe("RKPG", func() {
 			})
 		}
 	})
+
+	Context("network simulation with an adversary", func() {
+		Specify("players should compute the correct public keys against a rushing adversary", func() {
+			n, k, t, b, h, indices := RandomTestParams()
+			rngShares, rzgShares, rngComs, secrets := RXGOutputs(k, b, indices, h)
+			ids := make([]mpcutil.ID, n)
+			for i := range ids {
+				ids[i] = mpcutil.ID(i + 1)
+			}
+			machines := make([]mpcutil.Machine, n)
+			for i := range machines {
+				m := rkpgutil.NewHonestMachine(ids[i], ids, indices, h, rngComs, rngShares[i], rzgShares[i])
+				machines[i] = &m
+			}
+
+			// The corrupted players wait to see the shares of the honest
+			// players, and then send shares that are the honest shares with
+			// their own index shifted by a random amount, so that the shares
+			// look plausible but are incorrect.
+			rushing := mpcutil.StrategyFunc(func(_ int, view, outgoing []mpcutil.Message) []mpcutil.Message {
+				if len(view) == 0 {
+					return outgoing
+				}
+				msgs := make([]mpcutil.Message, len(outgoing))
+				for i, msg := range outgoing {
+					seen := view[rand.Intn(len(view))].(*rkpgutil.Message)
+					own := msg.(*rkpgutil.Message)
+					shares := make(shamir.Shares, len(seen.ShareBatch))
+					for j := range shares {
+						offset := secp256k1.RandomFn()
+						shares[j] = own.ShareBatch[j]
+						shares[j].Value.Add(&seen.ShareBatch[j].Value, &offset)
+					}
+					msgs[i] = &rkpgutil.Message{ToID: own.ToID, FromID: own.FromID, ShareBatch: shares}
+				}
+				return msgs
+			})
+
+			// Half of the corruptions are static, and the rest are chosen
+			// adaptively after seeing the initial messages.
+			adversary := mpcutil.NewAdversary(t, rushing)
+			perm := rand.Perm(n)
+			for _, i := range perm[:t/2] {
+				adversary.Corrupt(ids[i])
+			}
+			adversary.SetCorruptionPolicy(func(_ int, msgs []mpcutil.Message, corrupted map[mpcutil.ID]bool) []mpcutil.ID {
+				targets := make([]mpcutil.ID, 0, len(msgs))
+				for _, msg := range msgs {
+					if !corrupted[msg.From()] {
+						targets = append(targets, msg.From())
+					}
+				}
+				return targets
+			})
+
+			shuffleMsgs, _ := mpcutil.MessageShufflerDropper(ids, 0)
+			network := mpcutil.NewNetwork(machines, shuffleMsgs)
+			network.SetAdversary(&adversary)
+			network.SetCaptureHist(true)
+			Expect(network.Run()).To(Succeed())
+			Expect(len(adversary.Corrupted())).To(Equal(t))
+
+			for i := range machines {
+				if adversary.IsCorrupted(machines[i].ID()) {
+					continue
+				}
+				points := machines[i].(*rkpgutil.HonestMachine).Points
+				Expect(len(points)).To(Equal(b))
+				for j := range points {
+					var expected secp256k1.Point
+					expected.BaseExpUnsafe(&secrets[j])
+					Expect(expected.Eq(&points[j])).To(BeTrue())
+				}
+			}
+		})
+	})
 })
@@ -104,6 +104,13 @@ func (m *HonestMachine) Handle(msg mpcutil.Message) []mpcutil.Message {
 	return nil
 }
 
+// Done implements the mpcutil.Terminator interface.
+func (m HonestMachine) Done() bool { return len(m.Points) != 0 }
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of public keys.
+func (m HonestMachine) Result() interface{} { return m.Points }
+
 // A MaliciousMachine represents a player that acts maliciously by sending
 // shares with incorrect values.
 type MaliciousMachine struct {
@@ -19,7 +19,9 @@ func RNGOutputBatch(
 
 // RZGOutputBatch returns a random valid output of an instance of the RZG
 // protocol. In the returned shares, shares[i] are the outputs for player i and
-// has length equal to the batch size.
+// has length equal to the batch size. As for the outputs of the RZG protocol,
+// the constant terms of the commitments are the point at infinity (see
+// RZGOutput).
 func RZGOutputBatch(
 	indices []secp256k1.Fn,
 	k, b int,
@@ -41,7 +43,7 @@ func RXGOutputBatch(
 	secrets := make([]secp256k1.Fn, b)
 	for i := range shares {
 		if zero {
-			shares[i], coms[i] = RXGOutput(indices, k, h, secp256k1.NewFnFromU16(0))
+			shares[i], coms[i] = RZGOutput(indices, k, h)
 		} else {
 			secrets[i] = secp256k1.RandomFn()
 			shares[i], coms[i] = RXGOutput(indices, k, h, secrets[i])
@@ -72,3 +74,29 @@ func RXGOutput(
 	shamir.VShareSecret(&shares, &com, indices, h, x, k)
 	return shares, com
 }
+
+// RZGOutput returns the shares and a commitment for a valid verifiable sharing
+// of zero with threshold k and Pedersen parameter h, of the same form as the
+// outputs of the RZG protocol: both the sharing and the decommitment
+// polynomials are of the form x·g(x), and so the constant term of the
+// commitment is the point at infinity. If k is less than 2, a sharing of zero
+// with a random decommitment is returned instead.
+func RZGOutput(
+	indices []secp256k1.Fn,
+	k int,
+	h secp256k1.Point,
+) (shamir.VerifiableShares, shamir.Commitment) {
+	if k < 2 {
+		return RXGOutput(indices, k, h, secp256k1.NewFnFromU16(0))
+	}
+	shares, gCom := RXGOutput(indices, k-1, h, secp256k1.RandomFn())
+	for i := range shares {
+		shares[i].Scale(&shares[i], &indices[i])
+	}
+	com := shamir.NewCommitmentWithCapacity(k)
+	com.Append(secp256k1.NewPointInfinity())
+	for i := 0; i < gCom.Len(); i++ {
+		com.Append(gCom[i])
+	}
+	return shares, com
+}
@@ -40,3 +40,73 @@ func ShareOfShare(index secp256k1.Fn, vshares shamir.VerifiableShares) shamir.Ve
 
 	return acc
 }
+
+// ExtractionMatrix returns the rows x cols Vandermonde matrix whose entry in
+// row i and column j is (j+1)^i. Since the evaluation points 1, ..., cols are
+// distinct, any square submatrix formed from all of the rows and a subset of
+// the columns is invertible. This means that when the matrix is applied to a
+// vector of inputs of which at least rows are uniformly random and
+// independent of the others, the outputs are also uniformly random.
+//
+// Panics: This function panics if the number of columns is greater than 65535.
+func ExtractionMatrix(rows, cols int) [][]secp256k1.Fn {
+	if cols > 0xFFFF {
+		panic("number of columns is too large")
+	}
+	matrix := make([][]secp256k1.Fn, rows)
+	for i := range matrix {
+		matrix[i] = make([]secp256k1.Fn, cols)
+		for j := range matrix[i] {
+			if i == 0 {
+				matrix[i][j].SetU16(1)
+				continue
+			}
+			point := secp256k1.NewFnFromU16(uint16(j + 1))
+			matrix[i][j].Mul(&matrix[i-1][j], &point)
+		}
+	}
+	return matrix
+}
+
+// WeightedShare computes the linear combination of the given verifiable shares
+// with the given weights. The shares are assumed to all have the same index.
+//
+// Panics: This function panics if the number of weights and shares are not the
+// same, or if there are no shares.
+func WeightedShare(weights []secp256k1.Fn, vshares shamir.VerifiableShares) shamir.VerifiableShare {
+	if len(weights) != len(vshares) {
+		panic("number of weights and shares must be the same")
+	}
+	var acc, term shamir.VerifiableShare
+	acc.Scale(&vshares[0], &weights[0])
+	for j := 1; j < len(vshares); j++ {
+		term.Scale(&vshares[j], &weights[j])
+		acc.Add(&acc, &term)
+	}
+
+	return acc
+}
+
+// WeightedCommitment computes the linear combination of the given commitments
+// with the given weights, which is the commitment for the linear combination
+// of the corresponding sharings. The commitments are assumed to all have the
+// same threshold, and they may contain the point at infinity.
+//
+// Panics: This function panics if the number of weights and commitments are
+// not the same, or if there are no commitments.
+func WeightedCommitment(weights []secp256k1.Fn, coms []shamir.Commitment) shamir.Commitment {
+	if len(weights) != len(coms) {
+		panic("number of weights and commitments must be the same")
+	}
+	acc := shamir.NewCommitmentWithCapacity(coms[0].Len())
+	var term secp256k1.Point
+	for l := range coms[0] {
+		acc.Append(secp256k1.NewPointInfinity())
+		for j := range coms {
+			term.ScaleExt(&coms[j][l], &weights[j])
+			acc[l].Add(&acc[l], &term)
+		}
+	}
+
+	return acc
+}
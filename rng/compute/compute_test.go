@@ -121,4 +121,67 @@ var _ = Describe("RNG computation helper functions", func() {
 			Expect(actual.Eq(&expected)).To(BeTrue())
 		}
 	})
+
+	Specify("extraction matrices should have the correct entries", func() {
+		matrix := ExtractionMatrix(k, 2*k)
+		Expect(matrix).To(HaveLen(k))
+		for i := range matrix {
+			Expect(matrix[i]).To(HaveLen(2 * k))
+			for j := range matrix[i] {
+				expected := secp256k1.NewFnFromU16(1)
+				point := secp256k1.NewFnFromU16(uint16(j + 1))
+				for l := 0; l < i; l++ {
+					expected.Mul(&expected, &point)
+				}
+				Expect(matrix[i][j].Eq(&expected)).To(BeTrue())
+			}
+		}
+	})
+
+	Specify("weighted shares and commitments should be computed correctly", func() {
+		weights := make([]secp256k1.Fn, k)
+		values := make([]secp256k1.Fn, k)
+		decoms := make([]secp256k1.Fn, k)
+		vshares := make(shamir.VerifiableShares, k)
+		coms := make([]shamir.Commitment, k)
+
+		for i := 0; i < trials; i++ {
+			index := secp256k1.RandomFn()
+			h := secp256k1.RandomPoint()
+			var expectedValue, expectedDecom, tmp secp256k1.Fn
+			for j := 0; j < k; j++ {
+				weights[j] = secp256k1.RandomFn()
+				values[j] = secp256k1.RandomFn()
+				decoms[j] = secp256k1.RandomFn()
+				vshares[j] = shamir.NewVerifiableShare(shamir.NewShare(index, values[j]), decoms[j])
+
+				// A commitment with a threshold of 1 to the share.
+				var gPow, hPow secp256k1.Point
+				gPow.BaseExp(&values[j])
+				hPow.Scale(&h, &decoms[j])
+				gPow.Add(&gPow, &hPow)
+				coms[j] = shamir.Commitment{gPow}
+
+				tmp.Mul(&weights[j], &values[j])
+				expectedValue.Add(&expectedValue, &tmp)
+				tmp.Mul(&weights[j], &decoms[j])
+				expectedDecom.Add(&expectedDecom, &tmp)
+			}
+
+			output := WeightedShare(weights, vshares)
+			Expect(output.Share.Index.Eq(&index)).To(BeTrue())
+			Expect(output.Share.Value.Eq(&expectedValue)).To(BeTrue())
+			Expect(output.Decommitment.Eq(&expectedDecom)).To(BeTrue())
+
+			com := WeightedCommitment(weights, coms)
+			Expect(shamir.IsValid(h, &com, &output)).To(BeTrue())
+		}
+	})
+
+	Specify("weighted computations with the wrong number of weights should panic", func() {
+		vshares := make(shamir.VerifiableShares, k)
+		coms := make([]shamir.Commitment, k)
+		Expect(func() { WeightedShare(make([]secp256k1.Fn, k-1), vshares) }).To(Panic())
+		Expect(func() { WeightedCommitment(make([]secp256k1.Fn, k-1), coms) }).To(Panic())
+	})
 })
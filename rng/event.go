@@ -6,4 +6,41 @@ var (
 	// ErrSharesIgnored represents the event returned when the RNG state machine
 	// received `b` sets of verifiable shares that were invalid in some way
 	ErrSharesIgnored = errors.New("shares ignored")
+
+	// ErrInsecurePedersenParameter is returned when the Pedersen parameter is
+	// known to be insecure.
+	ErrInsecurePedersenParameter = errors.New("insecure pedersen parameter")
+
+	// ErrInvalidMode is returned when the mode is neither RNG nor RZG.
+	ErrInvalidMode = errors.New("invalid mode")
+
+	// ErrInvalidBatchSize is returned when the batch size of the BRNG outputs
+	// is less than 1.
+	ErrInvalidBatchSize = errors.New("invalid batch size")
+
+	// ErrInvalidThreshold is returned when the output threshold (k) is less
+	// than 2.
+	ErrInvalidThreshold = errors.New("invalid threshold")
+
+	// ErrInvalidCommitmentDimensions is returned when not every element of the
+	// batch of BRNG commitments has the number of commitments that is
+	// required for the output threshold (see RequiredBRNGBatchSize).
+	ErrInvalidCommitmentDimensions = errors.New("invalid commitment dimensions")
+
+	// ErrInvalidBRNGThreshold is returned when the threshold of the BRNG
+	// outputs is less than 2.
+	ErrInvalidBRNGThreshold = errors.New("invalid brng threshold")
+
+	// ErrInconsistentBRNGThreshold is returned when not all of the BRNG
+	// commitments have the same threshold.
+	ErrInconsistentBRNGThreshold = errors.New("inconsistent brng threshold")
+
+	// ErrIncorrectShareBatchSize is returned when the batch size of the BRNG
+	// shares is not equal to the batch size of the BRNG commitments.
+	ErrIncorrectShareBatchSize = errors.New("incorrect share batch size")
+
+	// ErrInvalidShareDimensions is returned when not every element of the
+	// batch of BRNG shares has the number of shares that is required for the
+	// output threshold (see RequiredBRNGBatchSize).
+	ErrInvalidShareDimensions = errors.New("invalid share dimensions")
 )
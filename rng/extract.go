@@ -0,0 +1,98 @@
+package rng
+
+import (
+	"fmt"
+
+	"github.com/renproject/shamir"
+
+	"github.com/renproject/mpc/rng/compute"
+)
+
+// Extract computes unbiased random sharings from outputs of the BRNG protocol
+// using randomness extraction, which unlike New does not require any
+// interaction between the players. The share and commitment batch arguments
+// are in the same form as for New, but the n BRNG outputs in each element of
+// the batch are multiplied by the (n - t) x n extraction matrix (see
+// compute.ExtractionMatrix) to obtain n - t output sharings, instead of being
+// used as the coefficients of a single output sharing. As long as at most t of
+// the n BRNG outputs in each element of the batch can be known to or chosen by
+// the adversary, the output sharings will be uniformly random. The threshold
+// of the output sharings is the same as that of the BRNG outputs.
+//
+// The outputs are the shares for this player and the commitments for each of
+// the b * (n - t) output sharings, ordered first by the element of the batch
+// and then by the row of the extraction matrix. As for New, the commitments
+// can be computed even if the given shares are nil, in which case the output
+// shares will also be nil.
+//
+// Panics: This function will panic in the following cases.
+//   - The batch size is less than 1.
+//   - t is negative, or not less than the number of BRNG outputs (n) in each
+//     element of the batch.
+//   - Not all elements of the batch have n commitments.
+//   - Not all commitments have the same threshold.
+//   - The shares and commitments have a different batch size, or not all
+//     elements of the share batch have n shares.
+func Extract(
+	t int,
+	brngShareBatch []shamir.VerifiableShares,
+	brngCommitmentBatch [][]shamir.Commitment,
+) (shamir.VerifiableShares, []shamir.Commitment) {
+	b := len(brngCommitmentBatch)
+	if b <= 0 {
+		panic(fmt.Sprintf("b must be greater than 0, got: %v", b))
+	}
+	n := len(brngCommitmentBatch[0])
+	if t < 0 || t >= n {
+		panic(fmt.Sprintf("t must be in the range [0, %v), got: %v", n, t))
+	}
+	threshold := brngCommitmentBatch[0][0].Len()
+	for _, commitments := range brngCommitmentBatch {
+		if len(commitments) != n {
+			panic("invalid commitment dimensions")
+		}
+		for _, commitment := range commitments {
+			if commitment.Len() != threshold {
+				panic(fmt.Sprintf(
+					"inconsistent commitment threshold: expected %v, got %v",
+					threshold, commitment.Len(),
+				))
+			}
+		}
+	}
+
+	ignoreShares := brngShareBatch == nil
+	if !ignoreShares {
+		if len(brngShareBatch) != b {
+			panic(fmt.Sprintf(
+				"incorrect share batch size: expected %v (commitments), got %v\n",
+				b, len(brngShareBatch),
+			))
+		}
+		for _, shares := range brngShareBatch {
+			if len(shares) != n {
+				panic("invalid set of shares")
+			}
+		}
+	}
+
+	matrix := compute.ExtractionMatrix(n-t, n)
+	outputCommitments := make([]shamir.Commitment, 0, b*(n-t))
+	for _, commitments := range brngCommitmentBatch {
+		for _, row := range matrix {
+			outputCommitments = append(outputCommitments, compute.WeightedCommitment(row, commitments))
+		}
+	}
+
+	var outputShares shamir.VerifiableShares = nil
+	if !ignoreShares {
+		outputShares = make(shamir.VerifiableShares, 0, b*(n-t))
+		for _, shares := range brngShareBatch {
+			for _, row := range matrix {
+				outputShares = append(outputShares, compute.WeightedShare(row, shares))
+			}
+		}
+	}
+
+	return outputShares, outputCommitments
+}
@@ -0,0 +1,102 @@
+package rng_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/rng"
+
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+
+	"github.com/renproject/mpc/rng/rngutil"
+)
+
+var _ = Describe("Randomness extraction", func() {
+	var n, t, b, k int
+	var indices []secp256k1.Fn
+	var h secp256k1.Point
+
+	BeforeEach(func() {
+		n = 5 + rand.Intn(6)
+		t = rand.Intn(n)
+		b = 1 + rand.Intn(3)
+		k = 2 + rand.Intn(n-2)
+		indices = shamirutil.RandomIndices(n)
+		h = secp256k1.RandomPoint()
+	})
+
+	Specify("every player should obtain shares of the same n - t sharings per batch element", func() {
+		sharesByPlayer, brngCommitmentBatch := rngutil.BRNGOutputFullBatch(indices, b, n, k, h)
+
+		outputShares := make([]shamir.VerifiableShares, n)
+		var outputCommitments []shamir.Commitment
+		for i, index := range indices {
+			shares, commitments := Extract(t, sharesByPlayer[index], brngCommitmentBatch)
+			Expect(shares).To(HaveLen(b * (n - t)))
+			Expect(commitments).To(HaveLen(b * (n - t)))
+			if outputCommitments == nil {
+				outputCommitments = commitments
+			}
+			for j := range commitments {
+				Expect(commitments[j].Eq(outputCommitments[j])).To(BeTrue())
+				Expect(commitments[j].Len()).To(Equal(k))
+			}
+			outputShares[i] = shares
+		}
+
+		for j := range outputCommitments {
+			shares := make(shamir.VerifiableShares, n)
+			for i := range shares {
+				shares[i] = outputShares[i][j]
+				Expect(shares[i].Share.Index.Eq(&indices[i])).To(BeTrue())
+				Expect(shamir.IsValid(h, &outputCommitments[j], &shares[i])).To(BeTrue())
+			}
+			Expect(shamirutil.VsharesAreConsistent(shares, k)).To(BeTrue())
+		}
+	})
+
+	Specify("the first output should be the sum of the BRNG outputs", func() {
+		sharesByPlayer, brngCommitmentBatch := rngutil.BRNGOutputFullBatch(indices, b, n, k, h)
+		index := indices[rand.Intn(n)]
+		shares, commitments := Extract(t, sharesByPlayer[index], brngCommitmentBatch)
+		for i := 0; i < b; i++ {
+			expected := sharesByPlayer[index][i][0]
+			for _, share := range sharesByPlayer[index][i][1:] {
+				expected.Add(&expected, &share)
+			}
+			Expect(shares[i*(n-t)].Eq(&expected)).To(BeTrue())
+			Expect(shamir.IsValid(h, &commitments[i*(n-t)], &expected)).To(BeTrue())
+		}
+	})
+
+	Specify("nil shares should give nil output shares", func() {
+		_, brngCommitmentBatch := rngutil.BRNGOutputFullBatch(indices, b, n, k, h)
+		shares, commitments := Extract(t, nil, brngCommitmentBatch)
+		Expect(shares).To(BeNil())
+		Expect(commitments).To(HaveLen(b * (n - t)))
+	})
+
+	Context("panics", func() {
+		Specify("invalid parameters", func() {
+			sharesByPlayer, brngCommitmentBatch := rngutil.BRNGOutputFullBatch(indices, b, n, k, h)
+			shares := sharesByPlayer[indices[0]]
+
+			Expect(func() { Extract(t, shares, [][]shamir.Commitment{}) }).To(Panic())
+			Expect(func() { Extract(-1, shares, brngCommitmentBatch) }).To(Panic())
+			Expect(func() { Extract(n, shares, brngCommitmentBatch) }).To(Panic())
+			Expect(func() { Extract(t, shares[1:], brngCommitmentBatch) }).To(Panic())
+
+			badShares := append([]shamir.VerifiableShares{}, shares...)
+			badShares[0] = badShares[0][1:]
+			Expect(func() { Extract(t, badShares, brngCommitmentBatch) }).To(Panic())
+
+			badCommitments := append([][]shamir.Commitment{}, brngCommitmentBatch...)
+			badCommitments[0] = append([]shamir.Commitment{}, badCommitments[0]...)
+			badCommitments[0][0] = badCommitments[0][0][1:]
+			Expect(func() { Extract(t, shares, badCommitments) }).To(Panic())
+		})
+	})
+})
@@ -17,6 +17,43 @@ type RNGer struct {
 	opener open.Opener
 }
 
+// Mode determines whether an RNGer carries out the RNG protocol, in which the
+// output sharings are of random numbers, or the RZG protocol, in which the
+// output sharings are of zero.
+type Mode uint8
+
+const (
+	// ModeRNG represents the RNG protocol.
+	ModeRNG = Mode(iota)
+
+	// ModeRZG represents the RZG protocol.
+	ModeRZG
+)
+
+// RequiredBRNGBatchSize returns the number of BRNG outputs that are required
+// in each element of the batch for an instance of the protocol with the given
+// mode and output threshold (k). For RNG this is k, as the BRNG outputs are
+// the coefficients of the output sharing, and for RZG this is k-1, as the
+// constant term of the output sharing is zero. The BRNG outputs can have any
+// threshold of at least 2; this is the threshold that is used when opening
+// the shares of the output sharings to the players.
+func RequiredBRNGBatchSize(mode Mode, k uint32) int {
+	if mode == ModeRZG {
+		return int(k) - 1
+	}
+	return int(k)
+}
+
+// MulOpenRZGThreshold returns the output threshold for RZG sharings that can
+// be used with mulopen.New when the inputs to be multiplied have threshold k.
+// The product of two sharings with threshold k has threshold 2k-1, and so the
+// RZG sharing that is used to randomise it must have the same threshold. The
+// corresponding number of BRNG outputs that are required can be obtained using
+// RequiredBRNGBatchSize.
+func MulOpenRZGThreshold(k uint32) uint32 {
+	return 2*k - 1
+}
+
 // New creates a new intance of a state machine that carries out either the RNG
 // or RZG protocol. Which one of these two cases is instantiated is determined
 // by the isZero argument. The share and commitment batch arguments are outputs
@@ -26,6 +63,9 @@ type RNGer struct {
 // map indexed by the index of the player that the message is destined for. If
 // this function is called with a nil share batch, this returned map will also
 // be nil, as the initial messages cannot be computed without input shares.
+// The output threshold (k) is inferred from the number of BRNG outputs in each
+// element of the batch; to specify it explicitly and receive errors instead of
+// panics, use NewWithThreshold.
 //
 // Panics: This function will panic in the following cases.
 //	- The batch size is less than 1.
@@ -43,44 +83,69 @@ func New(
 	brngCommitmentBatch [][]shamir.Commitment,
 	isZero bool,
 ) (RNGer, map[secp256k1.Fn]shamir.VerifiableShares, []shamir.Commitment) {
+	mode := ModeRNG
+	if isZero {
+		mode = ModeRZG
+	}
+	var k uint32
+	if len(brngCommitmentBatch) > 0 {
+		k = uint32(len(brngCommitmentBatch[0]))
+		if isZero {
+			k++
+		}
+	}
+	rnger, directedOpenings, outputCommitments, err := NewWithThreshold(
+		ownIndex, indices, h, brngShareBatch, brngCommitmentBatch, mode, k,
+	)
+	if err != nil {
+		panic(err.Error())
+	}
+	return rnger, directedOpenings, outputCommitments
+}
+
+// NewWithThreshold is the same as New, except that the mode and the output
+// threshold (k) are given explicitly, and instead of panicking when the
+// arguments are invalid, a corresponding error is returned. Each element of
+// the BRNG batch must contain the number of BRNG outputs given by
+// RequiredBRNGBatchSize. For example, RZG sharings for use with mulopen.New
+// can be created by using MulOpenRZGThreshold as the output threshold.
+func NewWithThreshold(
+	ownIndex secp256k1.Fn,
+	indices []secp256k1.Fn,
+	h secp256k1.Point,
+	brngShareBatch []shamir.VerifiableShares,
+	brngCommitmentBatch [][]shamir.Commitment,
+	mode Mode,
+	k uint32,
+) (RNGer, map[secp256k1.Fn]shamir.VerifiableShares, []shamir.Commitment, error) {
 	if !params.ValidPedersenParameter(h) {
-		panic("insecure choice of pedersen parameter")
+		return RNGer{}, nil, nil, ErrInsecurePedersenParameter
 	}
-	b := uint32(len(brngCommitmentBatch))
-	if b <= 0 {
-		panic(fmt.Sprintf("b must be greater than 0, got: %v", b))
+	if mode != ModeRNG && mode != ModeRZG {
+		return RNGer{}, nil, nil, ErrInvalidMode
 	}
-	k := uint32(len(brngCommitmentBatch[0]))
-	if isZero {
-		k++
+	b := len(brngCommitmentBatch)
+	if b <= 0 {
+		return RNGer{}, nil, nil, ErrInvalidBatchSize
 	}
 	if k <= 1 {
-		panic(fmt.Sprintf("k must be greater than 1, got: %v", k))
+		return RNGer{}, nil, nil, ErrInvalidThreshold
+	}
+	isZero := mode == ModeRZG
+	requiredBrngBatchSize := RequiredBRNGBatchSize(mode, k)
+	for _, commitments := range brngCommitmentBatch {
+		if len(commitments) != requiredBrngBatchSize {
+			return RNGer{}, nil, nil, ErrInvalidCommitmentDimensions
+		}
 	}
 	threshold := brngCommitmentBatch[0][0].Len()
 	if threshold < 2 {
-		panic(fmt.Sprintf("threshold must be greater than 1, got: %v", threshold))
-	}
-
-	var requiredBrngBatchSize int
-	if isZero {
-		// The constant term of the polynomial is zero so we don't need a share
-		// for it.
-		requiredBrngBatchSize = int(k - 1)
-	} else {
-		requiredBrngBatchSize = int(k)
+		return RNGer{}, nil, nil, ErrInvalidBRNGThreshold
 	}
-
 	for _, commitments := range brngCommitmentBatch {
-		if len(commitments) != requiredBrngBatchSize {
-			panic("invalid commitment dimensions")
-		}
 		for _, commitment := range commitments {
 			if commitment.Len() != threshold {
-				panic(fmt.Sprintf(
-					"inconsistent commitment threshold: expected %v, got %v",
-					threshold, commitment.Len(),
-				))
+				return RNGer{}, nil, nil, ErrInconsistentBRNGThreshold
 			}
 		}
 	}
@@ -91,17 +156,14 @@ func New(
 	ignoreShares := brngShareBatch == nil
 
 	if !ignoreShares {
-		if len(brngShareBatch) != int(b) {
-			panic(fmt.Sprintf(
-				"incorrect share batch size: expected %v (commitments), got %v\n",
-				b, len(brngShareBatch),
-			))
+		if len(brngShareBatch) != b {
+			return RNGer{}, nil, nil, ErrIncorrectShareBatchSize
 		}
 
 		// Each set of shares in the batch should have the correct length.
 		for _, shares := range brngShareBatch {
 			if len(shares) != requiredBrngBatchSize {
-				panic("invalid set of shares")
+				return RNGer{}, nil, nil, ErrInvalidShareDimensions
 			}
 		}
 	}
@@ -160,7 +222,7 @@ func New(
 		opener: opener,
 	}
 
-	return rnger, directedOpenings, outputCommitments
+	return rnger, directedOpenings, outputCommitments, nil
 }
 
 // HandleShareBatch handles a batch of shares received from another player. If
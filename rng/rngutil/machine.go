@@ -73,6 +73,17 @@ func (machine RngMachine) Commitments() []shamir.Commitment {
 	return machine.outputCommitments
 }
 
+// Done implements the mpcutil.Terminator interface.
+func (machine RngMachine) Done() bool {
+	return len(machine.outputShares) != 0
+}
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of shares of the random numbers.
+func (machine RngMachine) Result() interface{} {
+	return machine.outputShares
+}
+
 // InitialMessages implements the interface as required by a Network machine
 // It returns the initial messages to be sent by a machine to another machine
 // participating in the said protocol
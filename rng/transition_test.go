@@ -93,6 +93,104 @@ var _ = Describe("RNG/RZG state transitions", func() {
 			})
 		})
 
+		Context("creating a new RNGer with an explicit threshold", func() {
+			mode := rng.ModeRNG
+			if isZero {
+				mode = rng.ModeRZG
+			}
+
+			Specify("the outputs should be the same as for New", func() {
+				_, indices, index, b, c, k, h := RandomTestParameters(isZero)
+				brngShareBatch, brngCommitmentBatch := rngutil.BRNGOutputBatch(index, b, c, k, h)
+				_, expectedOpenings, expectedCommitments := rng.New(
+					index, indices, h, brngShareBatch, brngCommitmentBatch, isZero,
+				)
+				_, directedOpenings, commitments, err := rng.NewWithThreshold(
+					index, indices, h, brngShareBatch, brngCommitmentBatch, mode, uint32(k),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(commitments)).To(Equal(len(expectedCommitments)))
+				for i := range commitments {
+					Expect(commitments[i].Len()).To(Equal(k))
+					Expect(commitments[i].Eq(expectedCommitments[i])).To(BeTrue())
+				}
+				for _, j := range indices {
+					for i := range directedOpenings[j] {
+						Expect(directedOpenings[j][i].Eq(&expectedOpenings[j][i])).To(BeTrue())
+					}
+				}
+			})
+
+			Specify("the required number of BRNG outputs should match the threshold", func() {
+				_, _, _, _, c, k, _ := RandomTestParameters(isZero)
+				Expect(rng.RequiredBRNGBatchSize(mode, uint32(k))).To(Equal(c))
+			})
+
+			Specify("invalid arguments should return an error", func() {
+				_, indices, index, b, c, k, h := RandomTestParameters(isZero)
+				// Having a batch size of at least 2 ensures we can test all
+				// of the conditions.
+				if b == 1 {
+					b++
+				}
+				NewWithThreshold := func(
+					h secp256k1.Point,
+					brngShareBatch []shamir.VerifiableShares,
+					brngCommitmentBatch [][]shamir.Commitment,
+					mode rng.Mode,
+					k int,
+				) error {
+					_, _, _, err := rng.NewWithThreshold(
+						index, indices, h, brngShareBatch, brngCommitmentBatch, mode, uint32(k),
+					)
+					return err
+				}
+				Fresh := func() ([]shamir.VerifiableShares, [][]shamir.Commitment) {
+					return rngutil.BRNGOutputBatch(index, b, c, k, h)
+				}
+
+				shares, coms := Fresh()
+				Expect(NewWithThreshold(secp256k1.NewPointInfinity(), shares, coms, mode, k)).
+					To(Equal(rng.ErrInsecurePedersenParameter))
+				Expect(NewWithThreshold(h, shares, coms, rng.Mode(2), k)).
+					To(Equal(rng.ErrInvalidMode))
+				Expect(NewWithThreshold(h, shares, [][]shamir.Commitment{}, mode, k)).
+					To(Equal(rng.ErrInvalidBatchSize))
+				Expect(NewWithThreshold(h, shares, coms, mode, 1)).
+					To(Equal(rng.ErrInvalidThreshold))
+				Expect(NewWithThreshold(h, shares, coms, mode, k+1)).
+					To(Equal(rng.ErrInvalidCommitmentDimensions))
+
+				shares, coms = Fresh()
+				coms[1] = coms[1][1:]
+				Expect(NewWithThreshold(h, shares, coms, mode, k)).
+					To(Equal(rng.ErrInvalidCommitmentDimensions))
+
+				shares, coms = Fresh()
+				for i := range coms {
+					for j := range coms[i] {
+						coms[i][j] = coms[i][j][:1]
+					}
+				}
+				Expect(NewWithThreshold(h, shares, coms, mode, k)).
+					To(Equal(rng.ErrInvalidBRNGThreshold))
+
+				shares, coms = Fresh()
+				coms[1][0] = coms[1][0][1:]
+				Expect(NewWithThreshold(h, shares, coms, mode, k)).
+					To(Equal(rng.ErrInconsistentBRNGThreshold))
+
+				shares, coms = Fresh()
+				Expect(NewWithThreshold(h, shares[1:], coms, mode, k)).
+					To(Equal(rng.ErrIncorrectShareBatchSize))
+
+				shares, coms = Fresh()
+				shares[0] = shares[0][1:]
+				Expect(NewWithThreshold(h, shares, coms, mode, k)).
+					To(Equal(rng.ErrInvalidShareDimensions))
+			})
+		})
+
 		Context("handling share batches", func() {
 			Specify("invalid share batches should return an error", func() {
 				_, indices, index, b, c, k, h := RandomTestParameters(isZero)
@@ -246,4 +344,58 @@ var _ = Describe("RNG/RZG state transitions", func() {
 			})
 		})
 	}
+
+	Context("RZG for multiply and open", func() {
+		It("should produce valid sharings of zero with threshold 2k-1", func() {
+			n := 10
+			k := 3
+			b := 1 + rand.Intn(3)
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+
+			threshold := rng.MulOpenRZGThreshold(uint32(k))
+			Expect(threshold).To(Equal(uint32(2*k - 1)))
+			c := rng.RequiredBRNGBatchSize(rng.ModeRZG, threshold)
+			sharesByPlayer, brngCommitmentBatch := rngutil.BRNGOutputFullBatch(indices, b, c, k, h)
+
+			rngers := make([]rng.RNGer, n)
+			openings := make([]map[secp256k1.Fn]shamir.VerifiableShares, n)
+			var commitments []shamir.Commitment
+			for i, index := range indices {
+				var err error
+				rngers[i], openings[i], commitments, err = rng.NewWithThreshold(
+					index, indices, h, sharesByPlayer[index], brngCommitmentBatch, rng.ModeRZG, threshold,
+				)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			for _, commitment := range commitments {
+				Expect(commitment.Len()).To(Equal(int(threshold)))
+				Expect(commitment[0].IsInfinity()).To(BeTrue())
+			}
+
+			outputs := make([]shamir.VerifiableShares, n)
+			for i, index := range indices {
+				for j := range indices {
+					if i == j {
+						continue
+					}
+					shares, err := rngers[i].HandleShareBatch(openings[j][index])
+					Expect(err).ToNot(HaveOccurred())
+					if shares != nil {
+						outputs[i] = shares
+					}
+				}
+				Expect(outputs[i]).To(HaveLen(b))
+			}
+
+			for l := 0; l < b; l++ {
+				shares := make(shamir.VerifiableShares, n)
+				for i := range shares {
+					shares[i] = outputs[i][l]
+					Expect(shamir.IsValid(h, &commitments[l], &shares[i])).To(BeTrue())
+				}
+				Expect(shamirutil.VsharesAreConsistent(shares, int(threshold))).To(BeTrue())
+			}
+		})
+	})
 })
@@ -0,0 +1,19 @@
+package zerotest
+
+import "fmt"
+
+// ZeroMaskError is returned when the opened product of the random mask and the
+// second random mask is zero for some of the elements in the batch. In this
+// case the mask might be zero, and so the result of the zero test can not be
+// trusted for these elements. They need to be tested again with fresh random
+// masks.
+type ZeroMaskError struct {
+	// Indices contains the positions in the batch of the elements for which
+	// the product of the masks was zero.
+	Indices []int
+}
+
+// Error implements the error interface.
+func (err ZeroMaskError) Error() string {
+	return fmt.Sprintf("zero mask for batch elements %v", err.Indices)
+}
@@ -0,0 +1,29 @@
+package zerotest
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/renproject/mpc/mulopen"
+)
+
+// SizeHint implements the surge.SizeHinter interface.
+func (zt ZeroTester) SizeHint() int {
+	return zt.mulopener.SizeHint()
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (zt ZeroTester) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	return zt.mulopener.Marshal(buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (zt *ZeroTester) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	return zt.mulopener.Unmarshal(buf, rem)
+}
+
+// Generate implements the quick.Generator interface.
+func (zt ZeroTester) Generate(rand *rand.Rand, size int) reflect.Value {
+	mulopener := mulopen.MulOpener{}.Generate(rand, size).Interface().(mulopen.MulOpener)
+	return reflect.ValueOf(ZeroTester{mulopener})
+}
@@ -0,0 +1,185 @@
+// Package zerotest implements a protocol that allows the players to publicly
+// learn whether a shared value is equal to zero, without learning anything
+// else about the value.
+//
+// The protocol works by multiplying the shared value a by a fresh random
+// shared value r and opening the product a·r. If a = 0 then the product will
+// be zero. If a is not zero then the product will be a uniformly random non
+// zero value, unless r = 0, in which case the output would incorrectly be
+// true. To detect this, the product r·s of the mask and a second fresh random
+// shared value s is opened at the same time. If r·s is not zero then r is not
+// zero and the output is correct. Otherwise, which only happens with
+// negligible probability, a ZeroMaskError is returned and the affected
+// elements need to be tested again with fresh masks. Opening r·s does not
+// reveal anything about a, since together the two products only reveal a/s,
+// which is uniformly random.
+package zerotest
+
+import (
+	"io"
+
+	"github.com/renproject/mpc/mulopen"
+	"github.com/renproject/mpc/params"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+)
+
+// A ZeroTester is a state machine that implements the zero test protocol. The
+// multiply and open step opens a batch of twice the size of the input batch:
+// the first half contains the products a·r, and the second half contains the
+// products r·s that are used to check that the masks are not zero.
+type ZeroTester struct {
+	mulopener mulopen.MulOpener
+}
+
+// New returns a new ZeroTester state machine along with the initial message
+// that is to be broadcast to the other parties. The state machine will handle
+// this message before being returned. The shares and commitments for the
+// masks r and s should be the outputs of instances of RNG that have not been
+// used for anything else, and similarly the shares and commitments for the
+// RZG should be fresh and have reconstruction threshold 2k-1. The RZG batch
+// needs to be twice the size of the other batches, since the first half is
+// used for the products a·r and the second half is used for the products r·s.
+//
+// Panics: This function will panic in the same cases as mulopen.New, or if the
+// RZG batch is not twice the size of the other batches.
+func New(
+	aShareBatch, rShareBatch, sShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (ZeroTester, []mulopen.Message) {
+	return NewWithRand(
+		random.Reader,
+		aShareBatch, rShareBatch, sShareBatch, rzgShareBatch,
+		aCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch,
+		indices, h,
+	)
+}
+
+// NewWithRand is the same as New, except that the randomness for the initial
+// message is read from the given source.
+//
+// Panics: This function will panic in the same cases as New, or if the source
+// of randomness returns an error.
+func NewWithRand(
+	r io.Reader,
+	aShareBatch, rShareBatch, sShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (ZeroTester, []mulopen.Message) {
+	if !params.ValidPedersenParameter(h) {
+		panic("insecure choice of pedersen parameter")
+	}
+	if len(rzgShareBatch) != 2*len(aShareBatch) || len(rzgCommitmentBatch) != 2*len(aCommitmentBatch) {
+		panic("rzg batch size must be twice the input batch size")
+	}
+
+	xShareBatch := append(append(shamir.VerifiableShares{}, aShareBatch...), rShareBatch...)
+	yShareBatch := append(append(shamir.VerifiableShares{}, rShareBatch...), sShareBatch...)
+	xCommitmentBatch := append(append([]shamir.Commitment{}, aCommitmentBatch...), rCommitmentBatch...)
+	yCommitmentBatch := append(append([]shamir.Commitment{}, rCommitmentBatch...), sCommitmentBatch...)
+
+	mulopener, messages := mulopen.NewWithRand(
+		r,
+		xShareBatch, yShareBatch, rzgShareBatch,
+		xCommitmentBatch, yCommitmentBatch, rzgCommitmentBatch,
+		indices, h,
+	)
+	return ZeroTester{mulopener: mulopener}, messages
+}
+
+// NewEquality returns a new ZeroTester state machine that tests whether the
+// shared values a and b are equal. This is done by testing whether a - b is
+// equal to zero. Otherwise, it is the same as New.
+//
+// Panics: This function will panic in the same cases as New, or if the shares
+// and commitments for a and b have different batch sizes.
+func NewEquality(
+	aShareBatch, bShareBatch, rShareBatch, sShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (ZeroTester, []mulopen.Message) {
+	return NewEqualityWithRand(
+		random.Reader,
+		aShareBatch, bShareBatch, rShareBatch, sShareBatch, rzgShareBatch,
+		aCommitmentBatch, bCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch,
+		indices, h,
+	)
+}
+
+// NewEqualityWithRand is the same as NewEquality, except that the randomness
+// for the initial message is read from the given source.
+//
+// Panics: This function will panic in the same cases as NewEquality, or if the
+// source of randomness returns an error.
+func NewEqualityWithRand(
+	r io.Reader,
+	aShareBatch, bShareBatch, rShareBatch, sShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, bCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	indices []secp256k1.Fn, h secp256k1.Point,
+) (ZeroTester, []mulopen.Message) {
+	if len(aShareBatch) != len(bShareBatch) || len(aCommitmentBatch) != len(bCommitmentBatch) {
+		panic("inconsistent batch size")
+	}
+
+	var negOne secp256k1.Fn
+	negOne.SetU16(1)
+	negOne.Negate(&negOne)
+
+	diffShareBatch := make(shamir.VerifiableShares, len(aShareBatch))
+	for i := range diffShareBatch {
+		diffShareBatch[i].Scale(&bShareBatch[i], &negOne)
+		diffShareBatch[i].Add(&diffShareBatch[i], &aShareBatch[i])
+	}
+	diffCommitmentBatch := make([]shamir.Commitment, len(aCommitmentBatch))
+	for i := range diffCommitmentBatch {
+		diffCommitmentBatch[i] = shamir.NewCommitmentWithCapacity(bCommitmentBatch[i].Len())
+		diffCommitmentBatch[i].Scale(bCommitmentBatch[i], &negOne)
+		diffCommitmentBatch[i].Add(diffCommitmentBatch[i], aCommitmentBatch[i])
+	}
+
+	return NewWithRand(
+		r,
+		diffShareBatch, rShareBatch, sShareBatch, rzgShareBatch,
+		diffCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch,
+		indices, h,
+	)
+}
+
+// HandleMulOpenMessageBatch applies a state transition upon receiveing the
+// given shares from another party during the multiply and open step in the
+// zero test protocol. Once enough valid messages have been received to open
+// the product, the output is returned, which for each element in the batch is
+// true if the corresponding shared value is zero, and false otherwise. If not
+// enough messages have been received, the return value will be nil. If the
+// message batch is invalid in any way, an error will be returned along with a
+// nil value.
+//
+// If the product of the masks is zero for any of the elements in the batch, a
+// ZeroMaskError that identifies these elements is returned along with a nil
+// value, since the result for these elements might be incorrect.
+func (zt *ZeroTester) HandleMulOpenMessageBatch(messageBatch []mulopen.Message) ([]bool, error) {
+	output, err := zt.mulopener.HandleShareBatch(messageBatch)
+	if err != nil {
+		return nil, err
+	}
+	if output == nil {
+		return nil, nil
+	}
+	b := len(output) / 2
+	var zeroMasks []int
+	for i := 0; i < b; i++ {
+		if output[b+i].IsZero() {
+			zeroMasks = append(zeroMasks, i)
+		}
+	}
+	if len(zeroMasks) != 0 {
+		return nil, ZeroMaskError{Indices: zeroMasks}
+	}
+	isZero := make([]bool, b)
+	for i := range isZero {
+		isZero[i] = output[i].IsZero()
+	}
+	return isZero, nil
+}
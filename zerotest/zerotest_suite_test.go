@@ -0,0 +1,13 @@
+package zerotest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestZerotest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Zerotest Suite")
+}
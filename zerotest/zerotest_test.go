@@ -0,0 +1,256 @@
+package zerotest_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mpc/zerotest"
+
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/mulopen"
+	"github.com/renproject/mpc/rkpg/rkpgutil"
+	"github.com/renproject/mpc/zerotest/zerotestutil"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/shamir/shamirutil"
+)
+
+var _ = Describe("Zero test", func() {
+	RandomTestParams := func() (int, int, int, []secp256k1.Fn, secp256k1.Point) {
+		n := shamirutil.RandRange(9, 20)
+		k := shamirutil.RandRange(2, n/3-1)
+		b := shamirutil.RandRange(1, 5)
+		indices := shamirutil.RandomIndices(n)
+		h := secp256k1.RandomPoint()
+		return n, k, b, indices, h
+	}
+
+	// SharingBatch returns a batch of verifiable sharings of the given
+	// secrets, where shares[i] are the shares for player i.
+	SharingBatch := func(
+		indices []secp256k1.Fn, k int, h secp256k1.Point, secrets []secp256k1.Fn,
+	) ([]shamir.VerifiableShares, []shamir.Commitment) {
+		b := len(secrets)
+		shares := make([]shamir.VerifiableShares, len(indices))
+		for i := range shares {
+			shares[i] = make(shamir.VerifiableShares, b)
+		}
+		coms := make([]shamir.Commitment, b)
+		for i, secret := range secrets {
+			var sharing shamir.VerifiableShares
+			sharing, coms[i] = rkpgutil.RXGOutput(indices, k, h, secret)
+			for j := range sharing {
+				shares[j][i] = sharing[j]
+			}
+		}
+		return shares, coms
+	}
+
+	// RandomSecrets returns a batch of secrets, each of which is zero with
+	// probability 1/2. The returned bools indicate which of the secrets are
+	// zero.
+	RandomSecrets := func(b int) ([]secp256k1.Fn, []bool) {
+		secrets := make([]secp256k1.Fn, b)
+		isZero := make([]bool, b)
+		for i := range secrets {
+			if rand.Int()&1 == 0 {
+				secrets[i].SetU16(0)
+				isZero[i] = true
+			} else {
+				secrets[i] = secp256k1.RandomFn()
+			}
+		}
+		return secrets, isZero
+	}
+
+	Context("seeded randomness", func() {
+		Specify("initial messages created from the same seed should be the same", func() {
+			n, k, b, indices, h := RandomTestParams()
+			playerInd := rand.Intn(n)
+			aShares, aCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			sShares, sCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, 2*b, h)
+
+			newWithSeed := func(seed int64) []mulopen.Message {
+				_, messages := NewWithRand(
+					rand.New(rand.NewSource(seed)),
+					aShares[playerInd], rShares[playerInd], sShares[playerInd], rzgShares[playerInd],
+					aCommitments, rCommitments, sCommitments, rzgCommitments,
+					indices, h,
+				)
+				return messages
+			}
+			seed := rand.Int63()
+			messages := newWithSeed(seed)
+			Expect(newWithSeed(seed)).To(Equal(messages))
+			Expect(newWithSeed(seed + 1)).ToNot(Equal(messages))
+		})
+	})
+
+	Context("handling messages", func() {
+		Specify("the output should only be true for the zero inputs", func() {
+			n, k, b, indices, h := RandomTestParams()
+			secrets, isZero := RandomSecrets(b)
+			aShares, aCommitments := SharingBatch(indices, k, h, secrets)
+			rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			sShares, sCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, 2*b, h)
+
+			testers := make([]ZeroTester, n)
+			messages := make([][]mulopen.Message, n)
+			for i := range testers {
+				testers[i], messages[i] = New(
+					aShares[i], rShares[i], sShares[i], rzgShares[i],
+					aCommitments, rCommitments, sCommitments, rzgCommitments,
+					indices, h,
+				)
+			}
+
+			var output []bool
+			for i := 1; i < n && output == nil; i++ {
+				var err error
+				output, err = testers[0].HandleMulOpenMessageBatch(messages[i])
+				Expect(err).ToNot(HaveOccurred())
+				if i < 2*k-2 {
+					Expect(output).To(BeNil())
+				}
+			}
+
+			Expect(output).To(Equal(isZero))
+		})
+
+		Specify("the output should only be true for the pairs of equal inputs", func() {
+			n, k, b, indices, h := RandomTestParams()
+			aSecrets := make([]secp256k1.Fn, b)
+			bSecrets := make([]secp256k1.Fn, b)
+			isEqual := make([]bool, b)
+			for i := range aSecrets {
+				aSecrets[i] = secp256k1.RandomFn()
+				if rand.Int()&1 == 0 {
+					bSecrets[i] = aSecrets[i]
+					isEqual[i] = true
+				} else {
+					bSecrets[i] = secp256k1.RandomFn()
+				}
+			}
+			aShares, aCommitments := SharingBatch(indices, k, h, aSecrets)
+			bShares, bCommitments := SharingBatch(indices, k, h, bSecrets)
+			rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			sShares, sCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, 2*b, h)
+
+			testers := make([]ZeroTester, n)
+			messages := make([][]mulopen.Message, n)
+			for i := range testers {
+				testers[i], messages[i] = NewEquality(
+					aShares[i], bShares[i], rShares[i], sShares[i], rzgShares[i],
+					aCommitments, bCommitments, rCommitments, sCommitments, rzgCommitments,
+					indices, h,
+				)
+			}
+
+			var output []bool
+			for i := 1; i < n && output == nil; i++ {
+				var err error
+				output, err = testers[0].HandleMulOpenMessageBatch(messages[i])
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(output).To(Equal(isEqual))
+		})
+
+		Specify("a zero mask should be detected instead of giving an incorrect output", func() {
+			n, k, b, indices, h := RandomTestParams()
+
+			// None of the secrets are zero, but with a zero mask the product
+			// for the affected element would be zero.
+			aSecrets := make([]secp256k1.Fn, b)
+			rSecrets := make([]secp256k1.Fn, b)
+			for i := range aSecrets {
+				aSecrets[i] = secp256k1.RandomFn()
+				rSecrets[i] = secp256k1.RandomFn()
+			}
+			zeroIndex := rand.Intn(b)
+			rSecrets[zeroIndex].SetU16(0)
+			aShares, aCommitments := SharingBatch(indices, k, h, aSecrets)
+			rShares, rCommitments := SharingBatch(indices, k, h, rSecrets)
+			sShares, sCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, 2*b, h)
+
+			testers := make([]ZeroTester, n)
+			messages := make([][]mulopen.Message, n)
+			for i := range testers {
+				testers[i], messages[i] = New(
+					aShares[i], rShares[i], sShares[i], rzgShares[i],
+					aCommitments, rCommitments, sCommitments, rzgCommitments,
+					indices, h,
+				)
+			}
+
+			var output []bool
+			var err error
+			for i := 1; i < n && err == nil; i++ {
+				output, err = testers[0].HandleMulOpenMessageBatch(messages[i])
+			}
+
+			Expect(output).To(BeNil())
+			Expect(err).To(Equal(ZeroMaskError{Indices: []int{zeroIndex}}))
+		})
+
+		Specify("an rzg batch of the wrong size should panic", func() {
+			_, k, b, indices, h := RandomTestParams()
+			aShares, aCommitments := SharingBatch(indices, k, h, make([]secp256k1.Fn, b))
+			rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, b, h)
+			Expect(func() {
+				New(
+					aShares[0], rShares[0], rShares[0], rzgShares[0],
+					aCommitments, rCommitments, rCommitments, rzgCommitments,
+					indices, h,
+				)
+			}).To(Panic())
+		})
+	})
+
+	Context("network", func() {
+		n := 15
+		k := 4
+		b := 6
+
+		Specify("all honest nodes should learn which of the secrets are zero", func() {
+			indices := shamirutil.RandomIndices(n)
+			h := secp256k1.RandomPoint()
+			secrets, isZero := RandomSecrets(b)
+			aShares, aCommitments := SharingBatch(indices, k, h, secrets)
+			rShares, rCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			sShares, sCommitments, _ := rkpgutil.RNGOutputBatch(indices, k, b, h)
+			rzgShares, rzgCommitments := rkpgutil.RZGOutputBatch(indices, 2*k-1, 2*b, h)
+
+			ids := make([]mpcutil.ID, n)
+			for i := range ids {
+				ids[i] = mpcutil.ID(i + 1)
+			}
+			machines := make([]mpcutil.Machine, n)
+			for i, id := range ids {
+				machine := zerotestutil.NewMachine(
+					aShares[i], rShares[i], sShares[i], rzgShares[i],
+					aCommitments, rCommitments, sCommitments, rzgCommitments,
+					ids, id, indices, h,
+				)
+				machines[i] = &machine
+			}
+
+			shuffleMsgs, _ := mpcutil.MessageShufflerDropper(ids, 0)
+			network := mpcutil.NewNetwork(machines, shuffleMsgs)
+			network.SetCaptureHist(true)
+			err := network.Run()
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, machine := range machines {
+				Expect(machine.(*zerotestutil.Machine).Output).To(Equal(isZero))
+			}
+		})
+	})
+})
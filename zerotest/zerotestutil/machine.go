@@ -0,0 +1,138 @@
+package zerotestutil
+
+import (
+	"io"
+
+	"github.com/renproject/mpc/mpcutil"
+	"github.com/renproject/mpc/random"
+	"github.com/renproject/mpc/zerotest"
+	"github.com/renproject/secp256k1"
+	"github.com/renproject/shamir"
+	"github.com/renproject/surge"
+)
+
+// Machine represents a player that honestly carries out the zero test
+// protocol.
+type Machine struct {
+	OwnID mpcutil.ID
+	zerotest.ZeroTester
+	InitMsgs []Message
+	Output   []bool
+}
+
+// NewMachine constructs a new honest machine for a zero test network test. It
+// will have the given inputs and ID.
+func NewMachine(
+	aShareBatch, rShareBatch, sShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
+) Machine {
+	return NewMachineWithRand(
+		random.Reader,
+		aShareBatch, rShareBatch, sShareBatch, rzgShareBatch,
+		aCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch,
+		ids, ownID, indices, h,
+	)
+}
+
+// NewMachineWithRand is the same as NewMachine, except that the randomness for
+// the ZeroTester is read from the given source.
+func NewMachineWithRand(
+	r io.Reader,
+	aShareBatch, rShareBatch, sShareBatch, rzgShareBatch shamir.VerifiableShares,
+	aCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch []shamir.Commitment,
+	ids []mpcutil.ID, ownID mpcutil.ID, indices []secp256k1.Fn, h secp256k1.Point,
+) Machine {
+	zt, msgs := zerotest.NewWithRand(
+		r,
+		aShareBatch, rShareBatch, sShareBatch, rzgShareBatch,
+		aCommitmentBatch, rCommitmentBatch, sCommitmentBatch, rzgCommitmentBatch,
+		indices, h,
+	)
+	initialMessages := make([]Message, 0, len(ids)-1)
+	for _, id := range ids {
+		if id == ownID {
+			continue
+		}
+		initialMessages = append(initialMessages, Message{
+			FromID:   ownID,
+			ToID:     id,
+			Messages: msgs,
+		})
+	}
+	return Machine{
+		OwnID:      ownID,
+		ZeroTester: zt,
+		InitMsgs:   initialMessages,
+	}
+}
+
+// ID implements the Machine interface.
+func (m Machine) ID() mpcutil.ID { return m.OwnID }
+
+// InitialMessages implements the Machine interface.
+func (m Machine) InitialMessages() []mpcutil.Message {
+	msgs := make([]mpcutil.Message, len(m.InitMsgs))
+	for i := range m.InitMsgs {
+		msgs[i] = &m.InitMsgs[i]
+	}
+	return msgs
+}
+
+// Handle implements the Machine interface.
+func (m *Machine) Handle(msg mpcutil.Message) []mpcutil.Message {
+	output, _ := m.ZeroTester.HandleMulOpenMessageBatch(msg.(*Message).Messages)
+	if output != nil {
+		m.Output = output
+	}
+	return nil
+}
+
+// Done implements the mpcutil.Terminator interface.
+func (m Machine) Done() bool { return len(m.Output) != 0 }
+
+// Result implements the mpcutil.Outputter interface. The output is the batch
+// of zero test results.
+func (m Machine) Result() interface{} { return m.Output }
+
+// SizeHint implements the surge.SizeHinter interface.
+func (m Machine) SizeHint() int {
+	return m.OwnID.SizeHint() +
+		m.ZeroTester.SizeHint() +
+		surge.SizeHint(m.InitMsgs) +
+		surge.SizeHint(m.Output)
+}
+
+// Marshal implements the surge.Marshaler interface.
+func (m Machine) Marshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.OwnID.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.ZeroTester.Marshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Marshal(m.InitMsgs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Marshal(m.Output, buf, rem)
+}
+
+// Unmarshal implements the surge.Unmarshaler interface.
+func (m *Machine) Unmarshal(buf []byte, rem int) ([]byte, int, error) {
+	buf, rem, err := m.OwnID.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = m.ZeroTester.Unmarshal(buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	buf, rem, err = surge.Unmarshal(&m.InitMsgs, buf, rem)
+	if err != nil {
+		return buf, rem, err
+	}
+	return surge.Unmarshal(&m.Output, buf, rem)
+}